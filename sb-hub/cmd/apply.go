@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/spf13/cobra"
+)
+
+// definitionPlan is an sbhub.yaml sandbox that passed every check and has
+// its image in place, ready to be created.
+type definitionPlan struct {
+	name         string
+	sandboxPath  string
+	preset       string
+	ttl          time.Duration
+	config       *container.Config
+	hostConfig   *container.HostConfig
+	initScripts  []string
+	initCommands []string
+	hostPort     string
+}
+
+// prepareDefinition runs the checks for one sbhub.yaml entry on the host
+// context and builds or pulls its image, without touching any existing
+// container. engines holds every context, for the owner quota and the
+// name check. The pre-create hooks run last, so they can veto it.
+func prepareDefinition(ctx context.Context, engines map[string]*pkg.Dockerengine, host string, f *pkg.SandboxFile, defName string, pullOpts pkg.PullOptions) (*definitionPlan, error) {
+	engine := engines[host]
+	def := f.Sandboxes[defName]
+	name := f.SandboxName(defName)
+	spec := pkg.SandboxSpecs[def.Preset]
+	storageRoot := "/home/owen/prac-str"
+	sandboxPath := filepath.Join(storageRoot, name)
+
+	cfg := loadConfig()
+	owner := cfg.CurrentOwner()
+	if err := checkOwnerQuota(ctx, engines, cfg, owner, name, def.Preset); err != nil {
+		return nil, err
+	}
+	for _, other := range pkg.SandboxContexts(ctx, engines, name) {
+		if other != host {
+			return nil, fmt.Errorf("a sandbox named %s already exists on context %s", name, other)
+		}
+	}
+	if err := engine.CheckCapacity(ctx, name, def.Preset, capacityPolicy(cfg)); err != nil {
+		return nil, err
+	}
+	initScripts, err := initScriptsFor(cfg, def.Preset, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	binds := []string{fmt.Sprintf("%s:/data", sandboxPath)}
+	for _, m := range def.Mounts {
+		bind, err := f.MountBind(m)
+		if err != nil {
+			return nil, err
+		}
+		binds = append(binds, bind)
+	}
+
+	ttl := spec.DefaultTTL
+	if def.TTL != "" {
+		if ttl, err = time.ParseDuration(def.TTL); err != nil {
+			return nil, fmt.Errorf("invalid ttl: %v", err)
+		}
+	}
+	ttl = cfg.PolicyFor(owner).ClampTTL(ttl)
+
+	imageToUse := spec.Image
+	if def.Image != "" {
+		imageToUse = def.Image
+	}
+	if def.Build != "" {
+		imageToUse = "sb-local-" + name
+		if err := engine.BuildImage(ctx, f.ResolvePath(def.Build), imageToUse); err != nil {
+			return nil, fmt.Errorf("build failed: %v", err)
+		}
+	} else if err := engine.PullImage(ctx, imageToUse, pullOpts); err != nil {
+		return nil, err
+	}
+
+	ports := def.Ports
+	if len(ports) == 0 {
+		ports = []string{"80"}
+	}
+	usedPorts, _ := engine.GetUsedPorts(ctx)
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
+	firstHostPort := ""
+	for _, p := range ports {
+		hostPort, ctrPort, _ := pkg.ParsePortMapping(p)
+		if hostPort == "" {
+			free := FindFreePort(8000, 9000, usedPorts)
+			if free == 0 {
+				return nil, fmt.Errorf("no free host port for %s", p)
+			}
+			hostPort = fmt.Sprintf("%d", free)
+		}
+		usedPorts[hostPort] = true
+		if !strings.Contains(ctrPort, "/") {
+			ctrPort += "/tcp"
+		}
+		exposed[nat.Port(ctrPort)] = struct{}{}
+		bindings[nat.Port(ctrPort)] = append(bindings[nat.Port(ctrPort)], nat.PortBinding{HostIP: "0.0.0.0", HostPort: hostPort})
+		if firstHostPort == "" {
+			firstHostPort = hostPort
+		}
+	}
+
+	var env []string
+	for k, v := range def.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(env)
+
+	hostConfig := &container.HostConfig{
+		Binds:        binds,
+		NetworkMode:  "sb-hub-net",
+		PortBindings: bindings,
+		Resources: container.Resources{
+			NanoCPUs: int64(spec.CPUCores * 1e9),
+			Memory:   int64(spec.MemoryMB * 1024 * 1024),
+		},
+	}
+	config := &container.Config{
+		Image:        imageToUse,
+		Env:          env,
+		ExposedPorts: exposed,
+		Labels: map[string]string{
+			"com.sbhub.hostport":  firstHostPort,
+			"com.sbhub.project":   f.Project,
+			"com.sbhub.spec-hash": def.Hash(),
+			"com.sbhub.owner":     owner,
+		},
+	}
+
+	preCreate := pkg.NewHookPayload(pkg.HookPreCreate, name, config, hostConfig, sandboxPath)
+	preCreate.Size = def.Preset
+	if err := runHooks(ctx, cfg, preCreate); err != nil {
+		return nil, fmt.Errorf("vetoed by pre-create hook: %v", err)
+	}
+	return &definitionPlan{
+		name:         name,
+		sandboxPath:  sandboxPath,
+		preset:       def.Preset,
+		ttl:          ttl,
+		config:       config,
+		hostConfig:   hostConfig,
+		initScripts:  initScripts,
+		initCommands: def.Init,
+		hostPort:     firstHostPort,
+	}, nil
+}
+
+// createFromPlan starts the container of a prepared definition, then runs
+// the preset's init scripts and the definition's own init commands.
+func createFromPlan(ctx context.Context, cfg *pkg.Config, engine *pkg.Dockerengine, plan *definitionPlan) error {
+	os.MkdirAll(plan.sandboxPath, 0755)
+
+	// A new sandbox starts without the records of an earlier one
+	engine.Meta.Remove(plan.name)
+	id, err := engine.CreateSandbox(ctx, plan.name, plan.ttl, plan.preset, plan.config, plan.hostConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Started %s (ID: %s) at http://localhost:%s\n", plan.name, id[:12], plan.hostPort)
+
+	if err := engine.InitSandbox(ctx, plan.name, plan.initScripts, os.Stdout); err != nil {
+		return err
+	}
+	if err := engine.RunInitCommands(ctx, plan.name, plan.initCommands, os.Stdout); err != nil {
+		return err
+	}
+	runHooks(ctx, cfg, sandboxHookPayload(ctx, engine, pkg.HookPostCreate, "/home/owen/prac-str", plan.name))
+	return nil
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update sandboxes to match sbhub.yaml",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		prune, _ := cmd.Flags().GetBool("prune")
+
+		f, err := pkg.LoadSandboxFile(file)
+		if err != nil {
+			fmt.Printf("❌ Failed to load %s: %v\n", file, err)
+			return
+		}
+
+		cfg := loadConfig()
+		host := contextFor(cfg, "")
+		ep, err := cfg.Endpoint(host)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		// Sandboxes bind folders under the storage root on this machine
+		if !ep.Local() {
+			fmt.Printf("❌ Context %s is remote: apply keeps /data in local folders, so it needs a local endpoint\n", host)
+			return
+		}
+		engines, closeEngines := contextEngines(cfg)
+		defer closeEngines()
+		engine, ok := engines[host]
+		if !ok {
+			fmt.Printf("❌ Cannot connect to context %s\n", host)
+			return
+		}
+		engine.Relabel = ep.Relabels()
+		ctx := context.Background()
+		storageRoot := "/home/owen/prac-str"
+		pullOpts, err := readPullOptions(cmd, cfg)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		if err := engine.EnsureNetwork(ctx); err != nil {
+			fmt.Printf("❌ Failed to set up network: %v\n", err)
+			return
+		}
+		active, err := engine.GetActiveSandboxes(ctx)
+		if err != nil {
+			fmt.Printf("❌ Failed to list sandboxes: %v\n", err)
+			return
+		}
+
+		for _, step := range pkg.PlanSandboxes(f, active) {
+			switch step.Action {
+			case pkg.PlanUnchanged:
+				fmt.Printf("✔️  %s is up to date\n", step.Name)
+				continue
+			case pkg.PlanConflict:
+				fmt.Printf("⚠️  Skipping %s: the name is taken by a container %s\n", step.Name, step.Reason)
+				continue
+			case pkg.PlanRemove:
+				if !prune {
+					fmt.Printf("⚠️  %s is no longer defined (use --prune to remove it)\n", step.Name)
+					continue
+				}
+				fmt.Printf("🗑️  Removing %s (%s)...\n", step.Name, step.Reason)
+				payload, err := removeSandbox(ctx, cfg, engine, storageRoot, step.Name)
+				if errors.Is(err, errRemoveVetoed) {
+					fmt.Printf("⚠️  Keeping %s, %v\n", step.Name, err)
+					continue
+				} else if err != nil {
+					fmt.Printf("❌ Failed to remove %s: %v\n", step.Name, err)
+					continue
+				}
+				runHooks(ctx, cfg, payload.As(pkg.HookPostRemove))
+				continue
+			case pkg.PlanRecreate:
+				fmt.Printf("🔄 Recreating %s (%s)...\n", step.Name, step.Reason)
+			case pkg.PlanCreate:
+				fmt.Printf("📦 Creating %s...\n", step.Name)
+			}
+
+			// The old container stays until the new one is sure to be created
+			plan, err := prepareDefinition(ctx, engines, host, f, step.Definition, pullOpts)
+			if err != nil {
+				fmt.Printf("❌ Failed to apply %s: %v\n", step.Name, err)
+				continue
+			}
+			if step.Action == pkg.PlanRecreate {
+				payload, err := removeSandbox(ctx, cfg, engine, storageRoot, step.Name)
+				if errors.Is(err, errRemoveVetoed) {
+					fmt.Printf("⚠️  Keeping %s, %v\n", step.Name, err)
+					continue
+				} else if err != nil {
+					fmt.Printf("❌ Failed to remove %s: %v\n", step.Name, err)
+					continue
+				}
+				runHooks(ctx, cfg, payload.As(pkg.HookPostRemove))
+			}
+			if err := createFromPlan(ctx, cfg, engine, plan); err != nil {
+				fmt.Printf("❌ Failed to apply %s: %v\n", step.Name, err)
+			}
+		}
+	},
+}
+
+func init() {
+	applyCmd.Flags().StringP("file", "f", "sbhub.yaml", "Path to the sandbox definition file")
+	addPullFlags(applyCmd)
+	applyCmd.Flags().Bool("prune", false, "Remove sandboxes of this project that are no longer defined")
+	rootCmd.AddCommand(applyCmd)
+}
@@ -3,11 +3,11 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 )
 
@@ -18,12 +18,23 @@ var attachCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 
 		name, folder := args[0], args[1]
+		target, _ := cmd.Flags().GetString("target")
+		rerun, _ := cmd.Flags().GetBool("rerun-init")
 		newPath := filepath.Join("/home/owen/prac-str", folder)
+		ep, err := localEndpoint(loadConfig(), name, "attach binds a folder under the storage root")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str"), Relabel: ep.Relabels()}
 
 		inspect, err := engine.InspectSandbox(ctx, name)
 		if err != nil {
@@ -31,19 +42,26 @@ var attachCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Printf("🔄 Attaching sandbox '%s' to folder '%s'\n", name, folder)
+		fmt.Printf("🔄 Attaching sandbox '%s' to folder '%s' at %s\n", name, folder, target)
+		archiveSandboxLogs(ctx, engine, "/home/owen/prac-str", name)
+		engine.KeepCreated(name, inspect)
 		engine.RemoveSandbox(ctx, name, "", false)
 
-		inspect.HostConfig.Binds = []string{fmt.Sprintf("%s:/data", newPath)}
+		inspect.HostConfig.Binds = pkg.ReplaceBind(inspect.HostConfig.Binds, target, fmt.Sprintf("%s:%s", newPath, target))
 
-		id, err := engine.CreateSandbox(ctx, name, 1*time.Hour, inspect.Config.Labels["com.sbhub.size"], inspect.Config, inspect.HostConfig)
+		id, err := engine.CreateSandbox(ctx, name, 1*time.Hour, engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.size"], inspect.Config, inspect.HostConfig)
 		if err == nil {
 			fmt.Printf("✅ Attached. New ID: %s\n", id[:12])
+			if err := AfterRecreate(ctx, engine, name, rerun, os.Stdout); err != nil {
+				fmt.Printf("❌ Init failed: %v\n", err)
+			}
 		}
 
 	},
 }
 
 func init() {
+	attachCmd.Flags().String("target", "/data", "Mount point inside the sandbox to switch")
+	attachCmd.Flags().Bool("rerun-init", false, "Run the sandbox's init scripts again in the new container")
 	rootCmd.AddCommand(attachCmd)
 }
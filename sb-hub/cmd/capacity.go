@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/client"
+	"github.com/spf13/cobra"
+)
+
+// capacityPolicy builds the admission policy from the user config.
+func capacityPolicy(cfg *pkg.Config) pkg.CapacityPolicy {
+	return pkg.CapacityPolicy{Overcommit: cfg.Capacity.Overcommit, DiskPath: "/home/owen/prac-str"}
+}
+
+// admitSandbox checks that a new sandbox of size fits on the host. With a
+// queue timeout it waits for capacity to free up instead of failing.
+func admitSandbox(ctx context.Context, engine *pkg.Dockerengine, name, size string, policy pkg.CapacityPolicy, queue time.Duration) error {
+	deadline := time.Now().Add(queue)
+	announced := false
+	for {
+		err := engine.CheckCapacity(ctx, name, size, policy)
+		if err == nil || queue <= 0 {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("gave up waiting for capacity after %s: %v", queue, err)
+		}
+		if !announced {
+			fmt.Printf("⏳ Queued: %v. Waiting up to %s...\n", err, queue)
+			announced = true
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// checkOwnerQuota rejects a new sandbox of size when it would take owner
+// past their quota. Usage is summed over every endpoint in engines; name
+// is left out so recreating counts once.
+func checkOwnerQuota(ctx context.Context, engines map[string]*pkg.Dockerengine, cfg *pkg.Config, owner, name, size string) error {
+	usage, unreachable := pkg.TotalOwnerUsage(ctx, engines, owner, name)
+	if len(unreachable) == len(engines) {
+		return fmt.Errorf("cannot list sandboxes to check the quota of %s", owner)
+	}
+	for _, host := range unreachable {
+		fmt.Printf("⚠️  Quota of %s ignores context %s: it cannot be reached\n", owner, host)
+	}
+	return cfg.PolicyFor(owner).CheckCreate(usage, size)
+}
+
+// contextEngines connects to every configured context, or only to the
+// command's one when the config has none. Contexts that fail to connect
+// are skipped with a warning. The returned func closes the clients.
+func contextEngines(cfg *pkg.Config) (map[string]*pkg.Dockerengine, func()) {
+	names := []string{contextFor(cfg, "")}
+	if len(cfg.Contexts) > 0 {
+		names = cfg.ContextNames()
+	}
+	engines := map[string]*pkg.Dockerengine{}
+	var clients []*client.Client
+	for _, name := range names {
+		cli, err := newClientFor(cfg, name)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping context %s: %v\n", name, err)
+			continue
+		}
+		clients = append(clients, cli)
+		engines[name] = &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+	}
+	return engines, func() {
+		for _, cli := range clients {
+			cli.Close()
+		}
+	}
+}
+
+// placeSandbox returns the context a new sandbox of size goes to. An
+// explicit or default context is used as is; otherwise, with endpoints in
+// the config, the one in engines with the most free capacity is picked.
+// localOnly keeps sandboxes that need host paths off remote endpoints.
+func placeSandbox(ctx context.Context, cfg *pkg.Config, engines map[string]*pkg.Dockerengine, size string, localOnly bool) (string, error) {
+	if name := explicitContext(); name != "" {
+		return name, nil
+	}
+	if cfg.Context != "" || len(cfg.Contexts) == 0 {
+		return contextFor(cfg, ""), nil
+	}
+
+	candidates := map[string]*pkg.Dockerengine{}
+	for name, engine := range engines {
+		if ep, err := cfg.Endpoint(name); err == nil && (ep.Local() || !localOnly) {
+			candidates[name] = engine
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no local endpoint is reachable, and host folders, mounts and secrets cannot go to a remote one")
+	}
+	// Disk is left out: the storage root is only known on this host
+	chosen, placements, err := pkg.PlaceSandbox(ctx, candidates, size, pkg.CapacityPolicy{Overcommit: cfg.Capacity.Overcommit})
+	if err != nil {
+		return "", err
+	}
+	for _, p := range placements {
+		if p.Context == chosen {
+			cpus, mem, _ := p.Report.Headroom()
+			fmt.Printf("📍 Placing on %s (%.1f CPUs, %d MB free)\n", chosen, cpus, mem)
+		}
+	}
+	return chosen, nil
+}
+
+var capacityCmd = &cobra.Command{
+	Use:   "capacity",
+	Short: "Show committed resources and headroom on the host",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		report, err := engine.Capacity(ctx, "", capacityPolicy(loadConfig()))
+		if err != nil {
+			fmt.Printf("❌ Failed to read capacity: %v\n", err)
+			return
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+			return
+		}
+
+		limit := report.Limit()
+		cpus, mem, disk := report.Headroom()
+		fmt.Printf("📊 %d managed sandbox(es), overcommit ratio %.2f\n\n", report.Sandboxes, report.Overcommit)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "RESOURCE\tHOST\tLIMIT\tCOMMITTED\tHEADROOM")
+		fmt.Fprintf(w, "CPU\t%.1f cores\t%.1f cores\t%.1f cores\t%.1f cores\n", report.Host.CPUs, limit.CPUs, report.Committed.CPUs, cpus)
+		fmt.Fprintf(w, "Memory\t%d MB\t%d MB\t%d MB\t%d MB\n", report.Host.MemoryMB, limit.MemoryMB, report.Committed.MemoryMB, mem)
+		if report.Host.DiskGB > 0 {
+			fmt.Fprintf(w, "Disk\t%.0f GB\t%.0f GB\t%.0f GB\t%.0f GB\n", report.Host.DiskGB, limit.DiskGB, report.Committed.DiskGB, disk)
+		} else {
+			fmt.Fprintf(w, "Disk\tunknown\t-\t%.0f GB\t-\n", report.Committed.DiskGB)
+		}
+		w.Flush()
+
+		fmt.Println()
+		for _, size := range []string{"small", "medium", "large", "xlarge"} {
+			if err := report.Admit(size); err != nil {
+				fmt.Printf("❌ %s: %v\n", size, err)
+			} else {
+				fmt.Printf("✅ %s fits\n", size)
+			}
+		}
+	},
+}
+
+func init() {
+	capacityCmd.Flags().Bool("json", false, "Print the report as JSON")
+	rootCmd.AddCommand(capacityCmd)
+}
@@ -3,53 +3,233 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
+	"github.com/moby/term"
 	"github.com/spf13/cobra"
 )
 
+// watchTermSize sends the current size of the terminal on fd, then a new
+// value on every SIGWINCH until stop is called.
+func watchTermSize(fd uintptr) (<-chan pkg.TermSize, func()) {
+	sizes := make(chan pkg.TermSize, 1)
+	send := func() {
+		if ws, err := term.GetWinsize(fd); err == nil {
+			select {
+			case sizes <- pkg.TermSize{Height: uint(ws.Height), Width: uint(ws.Width)}:
+			default:
+			}
+		}
+	}
+	send()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-winch:
+				send()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return sizes, func() {
+		signal.Stop(winch)
+		close(done)
+	}
+}
+
+// connectOptions controls how console and exec prepare a sandbox before
+// attaching to it.
+type connectOptions struct {
+	Start   bool
+	Timeout time.Duration
+	Renew   time.Duration
+}
+
+// readConnectOptions reads the --start, --start-timeout and --renew flags.
+// --start defaults to interactive when the user did not set it.
+func readConnectOptions(cmd *cobra.Command, interactive bool) connectOptions {
+	opts := connectOptions{Start: interactive}
+	if cmd.Flags().Changed("start") {
+		opts.Start, _ = cmd.Flags().GetBool("start")
+	}
+	opts.Timeout, _ = cmd.Flags().GetDuration("start-timeout")
+	opts.Renew, _ = cmd.Flags().GetDuration("renew")
+	return opts
+}
+
+func addConnectFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("start", false, "Start the sandbox if it is stopped (default: on for interactive sessions)")
+	cmd.Flags().Duration("start-timeout", time.Minute, "How long to wait for the sandbox to become running/healthy")
+	cmd.Flags().Duration("renew", 0, "Renew the sandbox TTL to this duration before connecting")
+}
+
+// prepareSandbox makes sure the sandbox exists and is ready to accept an
+// exec, starting or renewing it as requested. Progress goes to out.
+func prepareSandbox(ctx context.Context, engine *pkg.Dockerengine, name string, opts connectOptions, out io.Writer) error {
+	inspect, err := engine.InspectSandbox(ctx, name)
+	if err != nil {
+		return fmt.Errorf("sandbox '%s' not found", name)
+	}
+
+	if opts.Renew > 0 {
+		ttl := opts.Renew
+		owner := engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.owner"]
+		if clamped := loadConfig().PolicyFor(owner).ClampTTL(ttl); clamped != ttl {
+			fmt.Fprintf(out, "⏱️  TTL capped at %s by the policy for %s\n", clamped, owner)
+			ttl = clamped
+		}
+		fmt.Fprintf(out, "⏱️  Renewing %s for %s...\n", name, ttl)
+		if _, err := renewSandbox(ctx, engine, name, inspect, ttl); err != nil {
+			return fmt.Errorf("renew failed: %v", err)
+		}
+		if err := engine.WaitReady(ctx, name, opts.Timeout); err != nil {
+			return err
+		}
+		return AfterRecreate(ctx, engine, name, false, out)
+	}
+
+	if inspect.State.Running {
+		return nil
+	}
+	if !opts.Start {
+		return fmt.Errorf("sandbox '%s' is %s. Start it with --start", name, inspect.State.Status)
+	}
+	fmt.Fprintf(out, "▶️  Starting %s...\n", name)
+	return engine.StartSandbox(ctx, name, opts.Timeout)
+}
+
+// recordResizes passes sizes through while recording every change after
+// the first, which the recording header already holds.
+func recordResizes(sizes <-chan pkg.TermSize, recorder *pkg.SessionRecorder) <-chan pkg.TermSize {
+	out := make(chan pkg.TermSize, 1)
+	go func() {
+		defer close(out)
+		first := true
+		for size := range sizes {
+			if !first {
+				recorder.Resize(size)
+			}
+			first = false
+			out <- size
+		}
+	}()
+	return out
+}
+
 var consoleCmd = &cobra.Command{
 	Use:     "console [name]",
-	Aliases: []string{"enter", "shell", "exec"},
+	Aliases: []string{"enter", "shell"},
 	Short:   "Open an interactive terminal inside a sandbox",
 	Args:    cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
+		user, _ := cmd.Flags().GetString("user")
+		workdir, _ := cmd.Flags().GetString("workdir")
+		shell, _ := cmd.Flags().GetString("shell")
+		record := loadConfig().Console.Record
+		if cmd.Flags().Changed("record") {
+			record, _ = cmd.Flags().GetBool("record")
+		}
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
 
-		inspect, err := engine.InspectSandbox(ctx, name)
-		if err != nil {
-			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+		_, isTerminal := term.GetFdInfo(os.Stdin)
+		if err := prepareSandbox(ctx, engine, name, readConnectOptions(cmd, isTerminal), os.Stdout); err != nil {
+			fmt.Printf("❌ %v\n", err)
 			return
 		}
 
-		if !inspect.State.Running {
-			fmt.Printf("❌ Sandbox '%s' is %s. Please start it first.\n", name, inspect.State.Status)
-			return
+		if shell == "" {
+			shell = engine.DetectShell(ctx, name)
+		}
+
+		fmt.Printf("🔌 Connecting to %s console (%s)... (type 'exit' to disconnect)\n", name, shell)
+
+		req := pkg.ExecRequest{
+			Cmd:     []string{shell},
+			User:    user,
+			WorkDir: workdir,
+			Stdin:   os.Stdin,
+			Stdout:  os.Stdout,
+			Stderr:  os.Stderr,
 		}
 
-		fmt.Printf("🔌 Connecting to %s console... (type 'exit' to disconnect)\n", name)
+		fd, _ := term.GetFdInfo(os.Stdin)
+		var recorder *pkg.SessionRecorder
+		if record {
+			size := pkg.TermSize{Height: 24, Width: 80}
+			if ws, err := term.GetWinsize(fd); err == nil && isTerminal {
+				size = pkg.TermSize{Height: uint(ws.Height), Width: uint(ws.Width)}
+			}
+			id := pkg.NewSessionID(name, time.Now())
+			path := pkg.SessionPath(sessionsDir(), name, id)
+			os.MkdirAll(filepath.Dir(path), 0700)
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				fmt.Printf("❌ Failed to start recording: %v\n", err)
+				return
+			}
+			defer f.Close()
+			recorder, err = pkg.NewSessionRecorder(f, size, name, map[string]string{"SHELL": shell, "TERM": os.Getenv("TERM")})
+			if err != nil {
+				fmt.Printf("❌ Failed to start recording: %v\n", err)
+				return
+			}
+			defer recorder.Close()
+			req.Stdout = io.MultiWriter(os.Stdout, recorder)
+			req.Stderr = io.MultiWriter(os.Stderr, recorder)
+			fmt.Printf("🎥 Recording session %s\n", id)
+		}
 
-		shellCmd := exec.Command("docker", "exec", "-it", name, "/bin/sh")
+		if isTerminal {
+			state, err := term.SetRawTerminal(fd)
+			if err != nil {
+				fmt.Printf("❌ Failed to set raw terminal mode: %v\n", err)
+				return
+			}
+			defer term.RestoreTerminal(fd, state)
 
-		shellCmd.Stdin = os.Stdin
-		shellCmd.Stdout = os.Stdout
-		shellCmd.Stderr = os.Stderr
+			sizes, stop := watchTermSize(fd)
+			defer stop()
+			req.Tty = true
+			req.Resize = sizes
+			if recorder != nil {
+				req.Resize = recordResizes(sizes, recorder)
+			}
+		}
 
-		err = shellCmd.Run()
-		if err != nil {
+		if _, err := engine.Exec(ctx, name, req); err != nil {
+			if isTerminal {
+				fmt.Print("\r\n")
+			}
 			fmt.Printf("❌ Console session ended with error: %v\n", err)
 		}
 	},
 }
 
 func init() {
+	consoleCmd.Flags().StringP("user", "u", "", "User to run the shell as")
+	consoleCmd.Flags().StringP("workdir", "w", "", "Working directory for the shell")
+	addConnectFlags(consoleCmd)
+	consoleCmd.Flags().String("shell", "", "Shell to start (default: auto-detect bash, zsh, then sh)")
+	consoleCmd.Flags().Bool("record", false, "Record the session in asciicast v2 format (default from console.record in config)")
 	rootCmd.AddCommand(consoleCmd)
 }
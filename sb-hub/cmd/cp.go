@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "cp [src] [dst]",
+	Short: "Copy files between the host and a sandbox",
+	Long: `Copy files between the host and a sandbox.
+
+Use <name>:/path for the sandbox side:
+  sb cp my-box:/var/log/app.log ./
+  sb cp ./config my-box:/etc/app`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		followLink, _ := cmd.Flags().GetBool("follow-link")
+		archive, _ := cmd.Flags().GetBool("archive")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+
+		src, dst := pkg.ParseCopyRef(args[0]), pkg.ParseCopyRef(args[1])
+		if (src.Sandbox == "") == (dst.Sandbox == "") {
+			fmt.Println("❌ Exactly one of src and dst must be a sandbox path (<name>:/path)")
+			return
+		}
+
+		cli, err := newClient(src.Sandbox + dst.Sandbox)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli}
+
+		sandbox := src.Sandbox + dst.Sandbox
+		if _, err := engine.InspectSandbox(ctx, sandbox); err != nil {
+			fmt.Printf("❌ Sandbox '%s' not found.\n", sandbox)
+			return
+		}
+
+		opts := pkg.CopyOptions{FollowLink: followLink, PreserveOwner: archive}
+		if !quiet {
+			opts.Progress = os.Stderr
+		}
+
+		if dst.Sandbox != "" {
+			err = engine.CopyIn(ctx, src.Path, dst, opts)
+		} else {
+			err = engine.CopyOut(ctx, src, dst.Path, opts)
+		}
+		if err != nil {
+			fmt.Printf("❌ Copy failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Copied %s to %s\n", args[0], args[1])
+	},
+}
+
+func init() {
+	cpCmd.Flags().BoolP("follow-link", "L", false, "Follow symlinks in the source path")
+	cpCmd.Flags().BoolP("archive", "a", false, "Keep uid/gid when copying into a sandbox")
+	cpCmd.Flags().BoolP("quiet", "q", false, "Don't show transfer progress")
+	rootCmd.AddCommand(cpCmd)
+}
@@ -7,12 +7,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
 	"github.com/spf13/cobra"
 )
 
@@ -36,6 +34,15 @@ var createCmd = &cobra.Command{
 	Short: "Create a networked sandbox with auto-port mapping",
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		// os.Exit skips deferred calls, so failures that must exit non-zero
+		// set exitCode and return; this runs after every other deferred call
+		exitCode := 0
+		defer func() {
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+		}()
+
 		var name string
 		if len(args) > 0 {
 			name = args[0]
@@ -48,14 +55,97 @@ var createCmd = &cobra.Command{
 
 		size, _ := cmd.Flags().GetString("size")
 		customImg, _ := cmd.Flags().GetString("image")
+		imageArchive, _ := cmd.Flags().GetString("image-archive")
 		restoreTag, _ := cmd.Flags().GetString("restore")
 		ttlOverride, _ := cmd.Flags().GetDuration("ttl")
+		envVars, _ := cmd.Flags().GetStringArray("env")
+		envFiles, _ := cmd.Flags().GetStringArray("env-file")
+		secretFlags, _ := cmd.Flags().GetStringArray("secret")
+		mountFlags, _ := cmd.Flags().GetStringArray("mount")
+		volumeFlags, _ := cmd.Flags().GetStringArray("volume")
+		storage, _ := cmd.Flags().GetString("storage")
+		healthCmd, _ := cmd.Flags().GetString("health-cmd")
+		healthHTTP, _ := cmd.Flags().GetString("health-http")
+		wait, _ := cmd.Flags().GetBool("wait")
+		waitTimeout, _ := cmd.Flags().GetDuration("wait-timeout")
+		initFlags, _ := cmd.Flags().GetStringArray("init")
+		rollback, _ := cmd.Flags().GetBool("init-rollback")
+		cfg := loadConfig()
+		queue := cfg.Capacity.QueueTimeout
+		if cmd.Flags().Changed("queue") {
+			queue, _ = cmd.Flags().GetDuration("queue")
+		}
 
 		spec, ok := pkg.SandboxSpecs[size]
 		if !ok {
 			fmt.Printf("❌ Invalid size: %s\n", size)
 			return
 		}
+		pullOpts, err := readPullOptions(cmd, cfg)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		if storage != "dir" && storage != "volume" {
+			fmt.Printf("❌ Invalid storage backend: %s (expected dir or volume)\n", storage)
+			return
+		}
+		if storage == "volume" && restoreTag != "" {
+			fmt.Println("❌ --restore is only supported with directory storage")
+			return
+		}
+
+		var extraBinds []string
+		for _, m := range mountFlags {
+			bind, err := pkg.ParseMountFlag(m)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			extraBinds = append(extraBinds, bind)
+		}
+		var volumes []string
+		for _, v := range volumeFlags {
+			volName, bind, err := pkg.ParseVolumeFlag(v)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			volumes = append(volumes, volName)
+			extraBinds = append(extraBinds, bind)
+		}
+
+		initScripts, err := initScriptsFor(cfg, size, initFlags)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		env, err := pkg.BuildEnv(envFiles, envVars)
+		if err != nil {
+			fmt.Printf("❌ Invalid environment: %v\n", err)
+			return
+		}
+
+		secrets := make(map[string]string)
+		for _, s := range secretFlags {
+			secretName, secretPath, err := pkg.ParseSecretFlag(s)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			if secretPath != "" {
+				if secretPath, err = filepath.Abs(secretPath); err == nil {
+					_, err = os.Stat(secretPath)
+				}
+				if err != nil {
+					fmt.Printf("❌ Secret '%s': %v\n", secretName, err)
+					return
+				}
+			}
+			secrets[secretName] = secretPath
+		}
 
 		imageToUse := spec.Image
 		if customImg != "" {
@@ -65,77 +155,260 @@ var createCmd = &cobra.Command{
 		storageRoot := "/home/owen/prac-str/"
 		sandboxPath := filepath.Join(storageRoot, name)
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-		defer cli.Close()
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
+		engines, closeEngines := contextEngines(cfg)
+		defer closeEngines()
+		// Host folders, mounts and secrets only exist on this machine
+		needsHost := cmd.Flags().Changed("storage") && storage == "dir" || len(mountFlags) > 0 || len(secrets) > 0 || restoreTag != ""
+		endpoint, err := placeSandbox(ctx, cfg, engines, size, needsHost)
+		if err != nil {
+			fmt.Printf("❌ Cannot place %s: %v\n", name, err)
+			return
+		}
+		ep, err := cfg.Endpoint(endpoint)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		if !ep.Local() {
+			if needsHost {
+				fmt.Printf("❌ Context %s is remote: --storage dir, --mount, --secret and --restore need a local endpoint\n", endpoint)
+				return
+			}
+			if storage == "dir" {
+				fmt.Printf("ℹ️  Context %s is remote, keeping /data in volume %s\n", endpoint, pkg.DataVolumeName(name))
+				storage = "volume"
+			}
+		}
+		if others := pkg.SandboxContexts(ctx, engines, name); len(others) > 0 && (len(others) > 1 || others[0] != endpoint) {
+			fmt.Printf("❌ Sandbox '%s' already exists on context %s\n", name, strings.Join(others, ", "))
+			return
+		}
+		engine, ok := engines[endpoint]
+		if !ok {
+			cli, err := newClientFor(cfg, endpoint)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			defer cli.Close()
+			engine = &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor(storageRoot)}
+			engines[endpoint] = engine
+		}
+
+		// 0. Owner quota, then admission control against host capacity
+		owner := cfg.CurrentOwner()
+		if err := checkOwnerQuota(ctx, engines, cfg, owner, name, size); err != nil {
+			fmt.Printf("❌ Cannot create %s for %s: %v\n", name, owner, err)
+			return
+		}
+		if err := admitSandbox(ctx, engine, name, size, capacityPolicy(cfg), queue); err != nil {
+			fmt.Printf("❌ Cannot create %s: %v\n", name, err)
+			return
+		}
+
+		// An archive replaces the pull, for hosts without registry access
+		if imageArchive != "" {
+			loaded, err := engine.LoadImageArchive(ctx, imageArchive, os.Stdout)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			if imageToUse, err = pkg.PickLoadedImage(loaded, customImg); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			fmt.Printf("📥 Loaded %s from %s\n", imageToUse, filepath.Base(imageArchive))
+			pullOpts.Policy = pkg.PullNever
+		}
 
 		// 1. Networking and Port Logic
 		engine.EnsureNetwork(ctx)
 		usedPorts, _ := engine.GetUsedPorts(ctx)
 		hostPort := FindFreePort(8000, 9000, usedPorts)
 
-		if restoreTag != "" {
-			snapPath := filepath.Join(storageRoot, restoreTag)
-			if _, err := os.Stat(snapPath); os.IsNotExist(err) {
-				snapPath = filepath.Join(storageRoot, fmt.Sprintf("%s_snap_%s", name, restoreTag))
-			}
-			if _, err := os.Stat(snapPath); err == nil {
-				fmt.Printf("🔄 Restoring data from: %s\n", snapPath)
-				exec.Command("sudo", "rm", "-rf", sandboxPath).Run()
-				exec.Command("sudo", "cp", "-r", snapPath, sandboxPath).Run()
-			}
-		}
-
-		if _, err := os.Stat(sandboxPath); err == nil && restoreTag == "" {
-			fmt.Printf("⚠️  Existing data found. [a]ttach, [r]ename, [c]ancel: ")
-			var action string
-			fmt.Scanln(&action)
-			if action == "r" {
-				oldPath := fmt.Sprintf("%s_old_%s", sandboxPath, time.Now().Format("20060102150405"))
-				os.Rename(sandboxPath, oldPath)
-				engine.RemoveSandbox(ctx, name, "", false)
-			} else if action == "a" {
-				engine.RemoveSandbox(ctx, name, "", false)
-			} else {
+		// 2. pre-create hooks can veto before anything is set up
+		storagePath := sandboxPath
+		if storage == "volume" {
+			storagePath = "volume:" + pkg.DataVolumeName(name)
+		}
+		preCreate := pkg.HookPayload{
+			Event:       pkg.HookPreCreate,
+			Name:        name,
+			Size:        size,
+			Image:       imageToUse,
+			Ports:       map[string]string{"80/tcp": fmt.Sprintf("%d", hostPort)},
+			Labels:      map[string]string{"com.sbhub.owner": owner, "com.sbhub.storage": storage},
+			StoragePath: storagePath,
+			Time:        time.Now().UTC(),
+		}
+		if err := runHooks(ctx, cfg, preCreate); err != nil {
+			fmt.Printf("❌ Create of %s vetoed by pre-create hook: %v\n", name, err)
+			return
+		}
+
+		dataBind := fmt.Sprintf("%s:/data", sandboxPath)
+		if storage == "volume" {
+			dataBind = fmt.Sprintf("%s:/data", pkg.DataVolumeName(name))
+			volumes = append(volumes, pkg.DataVolumeName(name))
+		} else {
+			if restoreTag != "" {
+				snapPath := filepath.Join(storageRoot, restoreTag)
+				if _, err := os.Stat(snapPath); os.IsNotExist(err) {
+					snapPath = filepath.Join(storageRoot, fmt.Sprintf("%s_snap_%s", name, restoreTag))
+				}
+				if _, err := os.Stat(snapPath); err == nil {
+					fmt.Printf("🔄 Restoring data from: %s\n", snapPath)
+					exec.Command("sudo", "rm", "-rf", sandboxPath).Run()
+					exec.Command("sudo", "cp", "-r", snapPath, sandboxPath).Run()
+				}
+			}
+
+			if _, err := os.Stat(sandboxPath); err == nil && restoreTag == "" {
+				fmt.Printf("⚠️  Existing data found. [a]ttach, [r]ename, [c]ancel: ")
+				var action string
+				fmt.Scanln(&action)
+				if action == "r" {
+					oldPath := fmt.Sprintf("%s_old_%s", sandboxPath, time.Now().Format("20060102150405"))
+					os.Rename(sandboxPath, oldPath)
+				} else if action != "a" {
+					return
+				}
+				if exists, _ := engine.ContainerExists(ctx, name); exists {
+					archiveSandboxLogs(ctx, engine, storageRoot, name)
+					engine.RemoveSandbox(ctx, name, "", false)
+				}
+			}
+			os.MkdirAll(sandboxPath, 0755)
+		}
+		for _, volName := range volumes {
+			if err := engine.EnsureVolume(ctx, volName); err != nil {
+				fmt.Printf("❌ Failed to create volume '%s': %v\n", volName, err)
 				return
 			}
 		}
-		os.MkdirAll(sandboxPath, 0755)
 
-		engine.EnsureImage(ctx, imageToUse)
+		if err := engine.PullImage(ctx, imageToUse, pullOpts); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 
-		hostConfig := &container.HostConfig{
-			Binds:       []string{fmt.Sprintf("%s:/data", sandboxPath)},
-			NetworkMode: "sb-hub-net",
-			PortBindings: nat.PortMap{
-				"80/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: fmt.Sprintf("%d", hostPort)}},
-			},
-			Resources: container.Resources{
-				NanoCPUs: int64(spec.CPUCores * 1e9),
-				Memory:   int64(spec.MemoryMB * 1024 * 1024),
-			},
+		binds := append([]string{dataBind}, extraBinds...)
+		// Decrypted secrets must not outlive a create that fails
+		var store *pkg.SecretStore
+		keepSecrets := false
+		defer func() {
+			if store != nil && !keepSecrets {
+				pkg.RemoveMountedSecrets(storageRoot, name)
+			}
+		}()
+		if len(secrets) > 0 {
+			for secretName, secretPath := range secrets {
+				if secretPath == "" {
+					if store == nil {
+						if store, err = openSecretStore(); err != nil {
+							fmt.Printf("❌ Failed to open secret store: %v\n", err)
+							return
+						}
+					}
+					secretPath, err = store.Materialize(secretName, pkg.MountedSecretsDir(storageRoot, name))
+					if err != nil {
+						fmt.Printf("❌ Secret '%s': %v\n", secretName, err)
+						return
+					}
+				}
+				binds = append(binds, pkg.SecretBind(secretName, secretPath))
+			}
+			fmt.Printf("🔐 Mounting %d secret(s) under %s\n", len(secrets), pkg.SecretsMountDir)
 		}
 
 		finalTTL := ttlOverride
 		if finalTTL == 0 {
 			finalTTL = spec.DefaultTTL
 		}
+		if clamped := cfg.PolicyFor(owner).ClampTTL(finalTTL); clamped != finalTTL {
+			fmt.Printf("⏱️  TTL capped at %s by the policy for %s\n", clamped, owner)
+			finalTTL = clamped
+		}
 
-		config := &container.Config{
-			Image: imageToUse,
-			Labels: map[string]string{
-				"com.sbhub.hostport": fmt.Sprintf("%d", hostPort),
-			},
+		labels := map[string]string{
+			"com.sbhub.hostport": fmt.Sprintf("%d", hostPort),
+			"com.sbhub.storage":  storage,
+			"com.sbhub.owner":    owner,
+		}
+		if healthHTTP != "" {
+			labels["com.sbhub.health-http"] = healthHTTP
+		}
+		rt, err := runtimeFor(cfg, endpoint, engine)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
 		}
 
-		id, err := engine.CreateSandbox(ctx, name, finalTTL, size, config, hostConfig)
+		// A new sandbox starts without the records of an earlier one
+		engine.Meta.Remove(name)
+		id, err := rt.Create(ctx, pkg.SandboxConfig{
+			Name:      name,
+			Image:     imageToUse,
+			Size:      size,
+			TTL:       finalTTL,
+			Env:       env,
+			Labels:    labels,
+			Binds:     binds,
+			Ports:     map[string]string{"80/tcp": fmt.Sprintf("%d", hostPort)},
+			Network:   "sb-hub-net",
+			HealthCmd: healthCmd,
+		})
 		if err != nil {
 			fmt.Printf("❌ Error: %v\n", err)
+			return
+		}
+		if err := rt.Start(ctx, name); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			rt.Remove(ctx, name)
+			engine.Meta.Remove(name)
+			return
+		}
+		keepSecrets = true
+		// Later commands find the sandbox on its endpoint without --context
+		if len(cfg.Contexts) > 0 {
+			engine.Meta.SetContext(name, endpoint)
+		}
+		if len(initScripts) > 0 {
+			fmt.Printf("🚀 Started %s (ID: %s), running %d init script(s)...\n", name, id[:12], len(initScripts))
+			if err := engine.InitSandbox(ctx, name, initScripts, os.Stdout); err != nil {
+				fmt.Printf("❌ %s failed to initialize: %v\n", name, err)
+				if rollback {
+					archiveSandboxLogs(ctx, engine, storageRoot, name)
+					engine.RemoveSandbox(ctx, name, "", false)
+					engine.Meta.Remove(name)
+					pkg.RemoveMountedSecrets(storageRoot, name)
+					fmt.Printf("🗑️  Rolled back: removed %s (its data folder is kept)\n", name)
+				} else {
+					fmt.Printf("   The sandbox is still running; inspect it with: sb console %s\n", name)
+				}
+				exitCode = 1
+				return
+			}
+		}
+		if !wait {
+			fmt.Printf("✅ Started %s (ID: %s) at http://%s:%d\n", name, id[:12], ep.PublishedHost(), hostPort)
 		} else {
-			// FIXED: Now using 'id' to satisfy the Go compiler
-			fmt.Printf("✅ Started %s (ID: %s) at http://localhost:%d\n", name, id[:12], hostPort)
+			// 3. Readiness: running, passing its healthcheck and answering HTTP
+			fmt.Printf("⏳ Started %s (ID: %s), waiting up to %s for it to become ready...\n", name, id[:12], waitTimeout)
+			url := ""
+			if healthHTTP != "" {
+				url = pkg.ReadinessURL(ep.PublishedHost(), fmt.Sprintf("%d", hostPort), healthHTTP)
+			}
+			if err := engine.WaitHealthy(ctx, name, url, waitTimeout); err != nil {
+				fmt.Printf("❌ %s never became ready: %v\n", name, err)
+				fmt.Printf("   Check its output with: sb logs %s\n", name)
+				exitCode = 1
+				return
+			}
+			fmt.Printf("✅ %s is ready at http://%s:%d\n", name, ep.PublishedHost(), hostPort)
 		}
+		runHooks(ctx, cfg, sandboxHookPayload(ctx, engine, pkg.HookPostCreate, storageRoot, name))
 	},
 }
 
@@ -143,7 +416,22 @@ func init() {
 	createCmd.Flags().StringP("name", "n", "", "Sandbox name")
 	createCmd.Flags().StringP("size", "s", "small", "Size preset")
 	createCmd.Flags().StringP("image", "i", "", "Custom Docker image")
+	createCmd.Flags().String("image-archive", "", "Load the image from a docker save or OCI archive instead of pulling it (--image picks one if it holds several)")
 	createCmd.Flags().StringP("restore", "r", "", "Snapshot folder or tag to restore")
 	createCmd.Flags().DurationP("ttl", "t", 0, "TTL override")
+	createCmd.Flags().StringArrayP("env", "e", nil, "Set an environment variable (KEY=VAL, or KEY to pass through from the host)")
+	createCmd.Flags().StringArray("env-file", nil, "Read environment variables from a file")
+	createCmd.Flags().StringArray("mount", nil, "Bind mount a host path (src:dst[:ro])")
+	createCmd.Flags().StringArray("volume", nil, "Mount a named volume (name:dst)")
+	createCmd.Flags().Duration("queue", 0, "Wait up to this long for host capacity instead of failing (default from capacity.queue_timeout in config)")
+	createCmd.Flags().String("storage", "dir", "Backend for /data: a host directory (dir) or a managed Docker volume (volume)")
+	createCmd.Flags().String("health-cmd", "", "Command run inside the sandbox to check its health (exit 0 = healthy)")
+	createCmd.Flags().String("health-http", "", "Path on the mapped port that must answer with a non-error status (e.g. /healthz)")
+	createCmd.Flags().Bool("wait", false, "Wait until the sandbox is running, healthy and answering --health-http")
+	createCmd.Flags().Duration("wait-timeout", 2*time.Minute, "How long --wait waits before failing")
+	createCmd.Flags().StringArray("init", nil, "Host script copied into the sandbox and run after it starts (repeatable, after presets.<size>.init from config)")
+	createCmd.Flags().Bool("init-rollback", false, "Remove the sandbox if an init script fails")
+	addPullFlags(createCmd)
+	createCmd.Flags().StringArray("secret", nil, "Mount a secret read-only under /run/secrets (NAME=path, or NAME from the secret store)")
 	rootCmd.AddCommand(createCmd)
 }
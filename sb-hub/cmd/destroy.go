@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "Remove every sandbox belonging to an sbhub.yaml project",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		purge, _ := cmd.Flags().GetBool("purge")
+		storageRoot := "/home/owen/prac-str"
+
+		f, err := pkg.LoadSandboxFile(file)
+		if err != nil {
+			fmt.Printf("❌ Failed to load %s: %v\n", file, err)
+			return
+		}
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli}
+		cfg := loadConfig()
+
+		active, err := engine.GetActiveSandboxes(ctx)
+		if err != nil {
+			fmt.Printf("❌ Failed to list sandboxes: %v\n", err)
+			return
+		}
+
+		for name, c := range active {
+			if c.Labels["com.sbhub.project"] != f.Project {
+				continue
+			}
+			payload := sandboxHookPayload(ctx, engine, pkg.HookPreRemove, storageRoot, name)
+			if err := runHooks(ctx, cfg, payload); err != nil {
+				fmt.Printf("⚠️  Keeping %s, vetoed by pre-remove hook: %v\n", name, err)
+				continue
+			}
+			fmt.Printf("🗑️  Removing %s...\n", name)
+			archiveSandboxLogs(ctx, engine, storageRoot, name)
+			if err := engine.RemoveSandbox(ctx, name, "", false); err != nil {
+				fmt.Printf("❌ Failed to remove %s: %v\n", name, err)
+				continue
+			}
+			pkg.MetaStoreFor(storageRoot).Remove(name)
+			if err := pkg.RemoveMountedSecrets(storageRoot, name); err != nil {
+				fmt.Printf("❌ Failed to remove mounted secrets of %s: %v\n", name, err)
+				continue
+			}
+			if purge {
+				if err := exec.Command("sudo", "rm", "-rf", filepath.Join(storageRoot, name)).Run(); err != nil {
+					fmt.Printf("❌ Failed to wipe storage for %s: %v\n", name, err)
+					continue
+				}
+			}
+			runHooks(ctx, cfg, payload.As(pkg.HookPostRemove))
+		}
+		fmt.Println("✅ Done.")
+	},
+}
+
+func init() {
+	destroyCmd.Flags().StringP("file", "f", "sbhub.yaml", "Path to the sandbox definition file")
+	destroyCmd.Flags().Bool("purge", false, "Also wipe each sandbox's data folder")
+	rootCmd.AddCommand(destroyCmd)
+}
@@ -3,33 +3,68 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 )
 
 var detachCmd = &cobra.Command{
 	Use:   "detach [name]",
 	Short: "Remove storage mounts from a sandbox",
+	Long:  "Remove storage mounts from a sandbox. By default every mount is dropped; use --target to remove a single mount point.",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		target, _ := cmd.Flags().GetString("target")
+		rerun, _ := cmd.Flags().GetBool("rerun-init")
+		if _, err := localEndpoint(loadConfig(), name, "detached mounts can only be put back with attach"); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
 
-		inspect, _ := engine.InspectSandbox(ctx, name)
-		fmt.Printf("🔌 Making %s stateless...\n", name)
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+			return
+		}
+		if target != "" {
+			fmt.Printf("🔌 Detaching %s from %s...\n", target, name)
+		} else {
+			fmt.Printf("🔌 Making %s stateless...\n", name)
+		}
 
+		archiveSandboxLogs(ctx, engine, "/home/owen/prac-str", name)
+		engine.KeepCreated(name, inspect)
 		engine.RemoveSandbox(ctx, name, "", false)
-		inspect.HostConfig.Binds = nil
+		if target != "" {
+			inspect.HostConfig.Binds = pkg.RemoveBind(inspect.HostConfig.Binds, target)
+		} else {
+			inspect.HostConfig.Binds = nil
+		}
 
-		engine.CreateSandbox(ctx, name, 1*time.Hour, inspect.Config.Labels["com.sbhub.size"], inspect.Config, inspect.HostConfig)
+		if _, err := engine.CreateSandbox(ctx, name, 1*time.Hour, engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.size"], inspect.Config, inspect.HostConfig); err != nil {
+			fmt.Printf("❌ Detach failed: %v\n", err)
+			return
+		}
 		fmt.Println("✅ Detached.")
+		if err := AfterRecreate(ctx, engine, name, rerun, os.Stdout); err != nil {
+			fmt.Printf("❌ Init failed: %v\n", err)
+		}
 	},
 }
 
-func init() { rootCmd.AddCommand(detachCmd) }
+func init() {
+	detachCmd.Flags().String("target", "", "Only remove the mount at this path inside the sandbox")
+	detachCmd.Flags().Bool("rerun-init", false, "Run the sandbox's init scripts again in the new container")
+	rootCmd.AddCommand(detachCmd)
+}
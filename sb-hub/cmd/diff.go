@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what apply would change",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+
+		f, err := pkg.LoadSandboxFile(file)
+		if err != nil {
+			fmt.Printf("❌ Failed to load %s: %v\n", file, err)
+			return
+		}
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli}
+
+		active, err := engine.GetActiveSandboxes(ctx)
+		if err != nil {
+			fmt.Printf("❌ Failed to list sandboxes: %v\n", err)
+			return
+		}
+
+		symbols := map[pkg.PlanAction]string{
+			pkg.PlanCreate:    "+",
+			pkg.PlanRecreate:  "~",
+			pkg.PlanRemove:    "-",
+			pkg.PlanUnchanged: "=",
+			pkg.PlanConflict:  "!",
+		}
+		for _, step := range pkg.PlanSandboxes(f, active) {
+			if step.Reason != "" {
+				fmt.Printf("%s %s (%s)\n", symbols[step.Action], step.Name, step.Reason)
+			} else {
+				fmt.Printf("%s %s\n", symbols[step.Action], step.Name)
+			}
+		}
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringP("file", "f", "sbhub.yaml", "Path to the sandbox definition file")
+	rootCmd.AddCommand(diffCmd)
+}
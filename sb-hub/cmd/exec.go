@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+// SplitExecArgs returns the sandbox and the command from the arguments of
+// sb exec. Flag parsing stops at the sandbox name, so pflag leaves the "--"
+// before the command in args; it is dropped here.
+func SplitExecArgs(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, errors.New("sandbox name required")
+	}
+	name, command := args[0], args[1:]
+	if len(command) > 0 && command[0] == "--" {
+		command = command[1:]
+	}
+	if len(command) == 0 {
+		return "", nil, errors.New("no command given")
+	}
+	return name, command, nil
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec [name] -- [command] [args...]",
+	Short: "Run a command inside a sandbox",
+	Long: `Run a command inside a sandbox and exit with its exit code.
+
+  sb exec my-box -- ls -la /data
+  echo hello | sb exec -i my-box -- cat`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, command, err := SplitExecArgs(args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		tty, _ := cmd.Flags().GetBool("tty")
+		user, _ := cmd.Flags().GetString("user")
+		workdir, _ := cmd.Flags().GetString("workdir")
+		envVars, _ := cmd.Flags().GetStringArray("env")
+
+		cfg := loadConfig()
+		cli, err := newClientFor(cfg, contextFor(cfg, name))
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+		rt, err := runtimeFor(cfg, contextFor(cfg, name), engine)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		if err := prepareSandbox(ctx, engine, name, readConnectOptions(cmd, interactive || tty), os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+
+		env, err := pkg.BuildEnv(nil, envVars)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Invalid environment: %v\n", err)
+			os.Exit(1)
+		}
+
+		req := pkg.ExecRequest{
+			Cmd:     command,
+			User:    user,
+			WorkDir: workdir,
+			Env:     env,
+			Stdout:  os.Stdout,
+			Stderr:  os.Stderr,
+		}
+		if interactive {
+			req.Stdin = os.Stdin
+		}
+
+		// os.Exit skips deferred calls, so terminal cleanup runs explicitly
+		cleanup := func() {}
+		if fd, isTerminal := term.GetFdInfo(os.Stdin); tty && isTerminal {
+			state, err := term.SetRawTerminal(fd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Failed to set raw terminal mode: %v\n", err)
+				os.Exit(1)
+			}
+			sizes, stop := watchTermSize(fd)
+			cleanup = func() {
+				stop()
+				term.RestoreTerminal(fd, state)
+			}
+			req.Tty = true
+			req.Resize = sizes
+		}
+
+		code, err := rt.Exec(ctx, name, req)
+		cleanup()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Exec failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().BoolP("interactive", "i", false, "Forward stdin to the command")
+	execCmd.Flags().BoolP("tty", "t", false, "Allocate a TTY")
+	execCmd.Flags().StringP("user", "u", "", "User to run the command as")
+	execCmd.Flags().StringP("workdir", "w", "", "Working directory for the command")
+	addConnectFlags(execCmd)
+	execCmd.Flags().StringArrayP("env", "e", nil, "Set an environment variable (KEY=VAL)")
+	rootCmd.AddCommand(execCmd)
+}
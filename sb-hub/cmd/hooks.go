@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+)
+
+// runHooks runs the configured hooks for the payload's event. A failing
+// pre-* hook vetoes the operation and its error is returned; failures of
+// the other hooks are only reported.
+func runHooks(ctx context.Context, cfg *pkg.Config, payload pkg.HookPayload) error {
+	hooks := cfg.HooksFor(payload.Event, payload.Size)
+	if len(hooks) == 0 {
+		return nil
+	}
+	err := pkg.RunHooks(ctx, hooks, payload)
+	if err != nil && !payload.Event.IsPre() {
+		fmt.Printf("⚠️  %s hook failed: %v\n", payload.Event, err)
+		return nil
+	}
+	return err
+}
+
+// sandboxHookPayload describes an existing sandbox for its hooks. When the
+// container is gone only the name and storage path are filled in.
+func sandboxHookPayload(ctx context.Context, engine *pkg.Dockerengine, event pkg.HookEvent, storageRoot, name string) pkg.HookPayload {
+	storagePath := filepath.Join(storageRoot, name)
+	inspect, err := engine.InspectSandbox(ctx, name)
+	if err != nil {
+		return pkg.NewHookPayload(event, name, nil, nil, storagePath)
+	}
+	config := *inspect.Config
+	config.Labels = engine.EffectiveLabels(name, inspect.Config.Labels)
+	if config.Labels["com.sbhub.storage"] == "volume" {
+		storagePath = "volume:" + pkg.DataVolumeName(name)
+	}
+	return pkg.NewHookPayload(event, name, &config, inspect.HostConfig, storagePath)
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+// addPullFlags registers --pull, --pull-timeout and --quiet-pull.
+func addPullFlags(cmd *cobra.Command) {
+	cmd.Flags().String("pull", "", "When to pull the image: always, missing or never (default from images.pull in config, else missing)")
+	cmd.Flags().Duration("pull-timeout", 0, "Give up on a pull after this long (default from images.pull_timeout in config, else 10m)")
+	cmd.Flags().Bool("quiet-pull", false, "Print only a summary line instead of per-layer progress")
+}
+
+// readPullOptions combines the pull flags with the config defaults.
+// Per-layer bars are only drawn on a terminal.
+func readPullOptions(cmd *cobra.Command, cfg *pkg.Config) (pkg.PullOptions, error) {
+	policy := cfg.Images.Pull
+	if cmd.Flags().Changed("pull") {
+		policy, _ = cmd.Flags().GetString("pull")
+	}
+	parsed, err := pkg.ParsePullPolicy(policy)
+	if err != nil {
+		return pkg.PullOptions{}, err
+	}
+	opts := pkg.PullOptions{Policy: parsed, Timeout: cfg.Images.PullTimeout, Auth: cfg.Credentials()}
+	if cmd.Flags().Changed("pull-timeout") {
+		opts.Timeout, _ = cmd.Flags().GetDuration("pull-timeout")
+	}
+	quiet, _ := cmd.Flags().GetBool("quiet-pull")
+	_, isTerminal := term.GetFdInfo(os.Stdout)
+	opts.Progress = isTerminal && !quiet
+	return opts, nil
+}
+
+// printPruneReport lists what an image prune removed and the space freed.
+func printPruneReport(report pkg.ImagePruneReport, dryRun bool) {
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	for _, img := range report.Removed {
+		name := shortID(img.ID)
+		if len(img.Tags) > 0 {
+			name = strings.Join(img.Tags, ", ")
+		}
+		fmt.Printf("🗑️  %s %s (%s)\n", verb, name, pkg.HumanBytes(img.Size))
+	}
+	for _, err := range report.Errors {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	fmt.Printf("✅ %s %d image(s), up to %s", verb, len(report.Removed), pkg.HumanBytes(report.Reclaimed))
+	if report.CacheReclaimed > 0 {
+		fmt.Printf(", plus %s of build cache", pkg.HumanBytes(int64(report.CacheReclaimed)))
+	}
+	fmt.Println()
+}
+
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Manage images built, pulled or loaded by sb-hub",
+}
+
+var imageLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List sb-hub images and the sandboxes using them",
+	Run: func(cmd *cobra.Command, args []string) {
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		images, err := engine.ListImages(context.Background())
+		if err != nil {
+			fmt.Printf("❌ Failed to list images: %v\n", err)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.Debug)
+		fmt.Fprintln(w, "IMAGE\tID\tORIGIN\tSIZE\tCREATED\tUSED BY")
+		for _, img := range images {
+			name := "<none>"
+			if len(img.Tags) > 0 {
+				name = strings.Join(img.Tags, ", ")
+			}
+			usedBy := "-"
+			if len(img.UsedBy) > 0 {
+				usedBy = strings.Join(img.UsedBy, ", ")
+			}
+			age := time.Since(img.Created).Round(time.Minute)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s ago\t%s\n", name, shortID(img.ID), img.Origin, pkg.HumanBytes(img.Size), age, usedBy)
+		}
+		w.Flush()
+	},
+}
+
+var imagePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove sb-hub images no sandbox uses, and dangling build cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan, _ := cmd.Flags().GetDuration("older-than")
+		dangling, _ := cmd.Flags().GetBool("dangling")
+		buildCache, _ := cmd.Flags().GetBool("build-cache")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		report, err := engine.PruneImages(context.Background(), pkg.ImagePruneOptions{
+			OlderThan:    olderThan,
+			DanglingOnly: dangling,
+			BuildCache:   buildCache,
+			DryRun:       dryRun,
+		})
+		if err != nil && len(report.Errors) == 0 {
+			fmt.Printf("❌ Prune failed: %v\n", err)
+			return
+		}
+		printPruneReport(report, dryRun)
+	},
+}
+
+var imageLoadCmd = &cobra.Command{
+	Use:   "load [archive]",
+	Short: "Load images from a docker save or OCI layout archive",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		loaded, err := engine.LoadImageArchive(context.Background(), args[0], os.Stdout)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		for _, l := range loaded {
+			fmt.Printf("📥 Loaded %s\n", l.Ref)
+		}
+	},
+}
+
+var imageSaveCmd = &cobra.Command{
+	Use:   "save [name]",
+	Short: "Write a sandbox's image to an archive that sb image load accepts",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = name + "-image.tar"
+		}
+		// Messages must not mix with an archive written to stdout
+		var msgs io.Writer = os.Stdout
+		if output == "-" {
+			msgs = os.Stderr
+		}
+
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Fprintf(msgs, "❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli}
+
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			fmt.Fprintf(msgs, "❌ Sandbox '%s' not found.\n", name)
+			return
+		}
+		// Save by tag so the archive loads under the same name, unless the
+		// tag has since moved to another image
+		ref := inspect.Config.Image
+		if id, err := engine.ImageID(ctx, ref); err != nil || id != inspect.Image {
+			ref = inspect.Image
+			fmt.Fprintf(msgs, "⚠️  %s no longer points at the image %s runs; saving it untagged\n", inspect.Config.Image, name)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				fmt.Fprintf(msgs, "❌ %v\n", err)
+				return
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := engine.SaveImages(ctx, []string{ref}, w)
+		if err != nil {
+			fmt.Fprintf(msgs, "❌ Save failed: %v\n", err)
+			if output != "-" {
+				os.Remove(output)
+			}
+			return
+		}
+		if output != "-" {
+			fmt.Fprintf(msgs, "💾 Saved %s to %s (%s)\n", ref, output, pkg.HumanBytes(n))
+		}
+	},
+}
+
+func init() {
+	imageSaveCmd.Flags().StringP("output", "o", "", "Archive to write, or - for stdout (default <name>-image.tar)")
+	imagePruneCmd.Flags().Duration("older-than", 0, "Only remove images created longer ago than this")
+	imagePruneCmd.Flags().Bool("dangling", false, "Only remove images that lost their tag, such as the old image of a rebuild")
+	imagePruneCmd.Flags().Bool("build-cache", true, "Also prune dangling build cache")
+	imagePruneCmd.Flags().Bool("dry-run", false, "Show what would be removed without removing it")
+	imageCmd.AddCommand(imageLsCmd, imagePruneCmd, imageLoadCmd, imageSaveCmd)
+	rootCmd.AddCommand(imageCmd)
+}
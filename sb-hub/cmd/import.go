@@ -8,7 +8,6 @@ import (
 	"path/filepath"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -29,17 +28,35 @@ var importCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		path, _ := filepath.Abs(args[0])
 		projectName := filepath.Base(path)
+		dockerfile, _ := cmd.Flags().GetString("dockerfile")
+		buildArgFlags, _ := cmd.Flags().GetStringArray("build-arg")
+		target, _ := cmd.Flags().GetString("target")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		buildArgs, err := pkg.ParseBuildArgs(buildArgFlags)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
 		ctx := context.Background()
 		engine := &pkg.Dockerengine{Client: cli}
 
 		// 1. Dockerfile Logic
 		dockerfilePath := filepath.Join(path, "Dockerfile")
+		if dockerfile != "" {
+			dockerfilePath = dockerfile
+		}
 		if _, err := os.Stat(dockerfilePath); err == nil {
 			tag := "sb-local-" + projectName
-			if err := engine.BuildImage(ctx, path, tag); err != nil {
+			opts := pkg.BuildOptions{Dockerfile: dockerfile, BuildArgs: buildArgs, Target: target, NoCache: noCache}
+			if err := engine.BuildImageWith(ctx, path, tag, opts); err != nil {
 				fmt.Printf("❌ Build failed: %v\n", err)
 				return
 			}
@@ -47,6 +64,9 @@ var importCmd = &cobra.Command{
 			createCmd.Flags().Set("image", tag)
 			createCmd.Run(createCmd, []string{projectName})
 			return
+		} else if dockerfile != "" {
+			fmt.Printf("❌ Dockerfile not found: %v\n", err)
+			return
 		}
 
 		// 2. Docker Compose Logic
@@ -86,5 +106,9 @@ var importCmd = &cobra.Command{
 }
 
 func init() {
+	importCmd.Flags().String("dockerfile", "", "Dockerfile to build, inside the project directory (default <path>/Dockerfile)")
+	importCmd.Flags().StringArray("build-arg", nil, "Set a build-time variable (KEY=VALUE, or KEY to pass through from the host)")
+	importCmd.Flags().String("target", "", "Build this stage of a multi-stage Dockerfile")
+	importCmd.Flags().Bool("no-cache", false, "Do not use the build cache")
 	rootCmd.AddCommand(importCmd)
 }
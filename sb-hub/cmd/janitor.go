@@ -5,48 +5,46 @@ import (
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 )
 
 var janitorCmd = &cobra.Command{
 	Use:   "janitor",
 	Short: "Start the background TTL enforcer",
+	Long: `Start the background TTL enforcer. With contexts in the config it
+watches every endpoint, unless --context picks one.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		once, _ := cmd.Flags().GetBool("once")
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-		defer cli.Close()
-		engine := &pkg.Dockerengine{Client: cli}
 		storageRoot := "/home/owen/prac-str"
+		engines, closeEngines := contextEngines(loadConfig())
+		defer closeEngines()
+		if name := explicitContext(); name != "" {
+			engine, ok := engines[name]
+			if !ok {
+				fmt.Printf("❌ Cannot connect to context %s\n", name)
+				return
+			}
+			engines = map[string]*pkg.Dockerengine{name: engine}
+		}
+		hosts := make([]string, 0, len(engines))
+		for host := range engines {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
 
-		fmt.Println("🧹 Janitor service started. Monitoring TTLs...")
+		fmt.Printf("🧹 Janitor service started. Monitoring TTLs on %s...\n", strings.Join(hosts, ", "))
 
+		lastPrune := map[string]time.Time{}
 		for {
 			ctx := context.Background()
-			expired, err := engine.GetExpiredSandboxes(ctx)
-			if err != nil {
-				fmt.Printf("❌ Janitor Error: %v\n", err)
-			}
-
-			for _, c := range expired {
-				name := filepath.Base(c.Names[0])
-				fmt.Printf("⏰ TTL Expired for: %s. Archiving...\n", name)
-
-				// 1. Stop and Remove Container
-				engine.RemoveSandbox(ctx, name, "", false)
-
-				// 2. Hybrid Move: Using sudo mv to handle root-owned container files
-				oldPath := filepath.Join(storageRoot, name)
-				newPath := filepath.Join(storageRoot, fmt.Sprintf("%s_janitor_%s", name, time.Now().Format("20060102150405")))
-
-				fmt.Printf("📦 Archiving data to: %s\n", filepath.Base(newPath))
-				err := exec.Command("sudo", "mv", oldPath, newPath).Run()
-				if err != nil {
-					fmt.Printf("❌ Failed to archive %s: %v\n", name, err)
-				}
+			cfg := loadConfig()
+			for _, host := range hosts {
+				lastPrune[host] = janitorCycle(ctx, cfg, engines[host], host, storageRoot, lastPrune[host])
 			}
 
 			if once {
@@ -57,6 +55,96 @@ var janitorCmd = &cobra.Command{
 	},
 }
 
+// janitorCycle archives the expired sandboxes of one endpoint and prunes
+// its images when due. It returns when images were last pruned.
+func janitorCycle(ctx context.Context, cfg *pkg.Config, engine *pkg.Dockerengine, host, storageRoot string, lastPrune time.Time) time.Time {
+	expired, err := engine.GetExpiredSandboxes(ctx)
+	if err != nil {
+		fmt.Printf("❌ Janitor Error on %s: %v\n", host, err)
+		return lastPrune
+	}
+
+	// Owner policies can cap lifetimes below the expiry label
+	overdue, err := engine.ExpiredByPolicy(ctx, func(owner string) time.Duration {
+		return cfg.PolicyFor(owner).MaxTTL
+	})
+	if err != nil {
+		fmt.Printf("❌ Janitor Error on %s: %v\n", host, err)
+	}
+	for _, c := range overdue {
+		already := false
+		for _, e := range expired {
+			already = already || e.ID == c.ID
+		}
+		if !already {
+			fmt.Printf("📏 %s outlived the max TTL for owner %s\n", filepath.Base(c.Names[0]), c.Labels["com.sbhub.owner"])
+			expired = append(expired, c)
+		}
+	}
+
+	for _, c := range expired {
+		name := filepath.Base(c.Names[0])
+		owner := engine.EffectiveLabels(name, c.Labels)["com.sbhub.owner"]
+		if err := runHooks(ctx, cfg, sandboxHookPayload(ctx, engine, pkg.HookPreExpire, storageRoot, name)); err != nil {
+			fmt.Printf("⏸️  Expiry of %s vetoed by pre-expire hook, retrying next cycle: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("⏰ TTL Expired for: %s. Archiving...\n", name)
+
+		// 1. Keep the logs, then Stop and Remove Container
+		archiveSandboxLogs(ctx, engine, storageRoot, name)
+		if err := engine.RemoveSandbox(ctx, name, "", false); err != nil {
+			fmt.Printf("❌ Failed to remove %s, retrying next cycle: %v\n", name, err)
+			continue
+		}
+		if err := pkg.RemoveMountedSecrets(storageRoot, name); err != nil {
+			fmt.Printf("❌ Failed to remove mounted secrets of %s: %v\n", name, err)
+		}
+
+		engine.Meta.Remove(name)
+
+		// Volume-backed data stays in its managed volume
+		if c.Labels["com.sbhub.storage"] == "volume" {
+			fmt.Printf("📦 Data kept in volume: %s\n", pkg.DataVolumeName(name))
+			continue
+		}
+
+		// 2. Hybrid Move: Using sudo mv to handle root-owned container files
+		oldPath := filepath.Join(storageRoot, name)
+		newPath := filepath.Join(storageRoot, fmt.Sprintf("%s_janitor_%s", name, time.Now().Format("20060102150405")))
+
+		fmt.Printf("📦 Archiving data to: %s\n", filepath.Base(newPath))
+		err := exec.Command("sudo", "mv", oldPath, newPath).Run()
+		if err != nil {
+			fmt.Printf("❌ Failed to archive %s: %v\n", name, err)
+			continue
+		}
+		// list --mine and snapshot quotas find the archive's owner here
+		if owner != "" {
+			engine.Meta.SetLabels(filepath.Base(newPath), map[string]string{"com.sbhub.owner": owner})
+		}
+	}
+
+	// Optional image garbage collection
+	if prune := cfg.Images.Prune; prune.Auto {
+		interval := prune.Interval
+		if interval <= 0 {
+			interval = pkg.DefaultPruneInterval
+		}
+		if time.Since(lastPrune) >= interval {
+			lastPrune = time.Now()
+			report, err := engine.PruneImages(ctx, pkg.ImagePruneOptions{OlderThan: prune.OlderThan, DanglingOnly: prune.DanglingOnly, BuildCache: true})
+			if err != nil && len(report.Errors) == 0 {
+				fmt.Printf("❌ Image prune failed: %v\n", err)
+			} else if len(report.Removed) > 0 || len(report.Errors) > 0 {
+				printPruneReport(report, false)
+			}
+		}
+	}
+
+	return lastPrune
+}
+
 func init() {
 	janitorCmd.Flags().Bool("once", false, "Run one cleanup cycle and exit (useful for testing)")
 	rootCmd.AddCommand(janitorCmd)
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/api/types/container"
 	"github.com/spf13/cobra"
 )
 
@@ -19,62 +22,177 @@ var listCmd = &cobra.Command{
 	Short:   "List all sandboxes and archived data",
 	Run: func(cmd *cobra.Command, args []string) {
 		storageRoot := "/home/owen/prac-str"
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-		defer cli.Close()
+		mine, _ := cmd.Flags().GetBool("mine")
+		allContexts, _ := cmd.Flags().GetBool("all-contexts")
+		cfg := loadConfig()
+		me := cfg.CurrentOwner()
 
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
-		activeMap, _ := engine.GetActiveSandboxes(ctx)
+		engine := &pkg.Dockerengine{Meta: pkg.MetaStoreFor(storageRoot)}
+
+		// Sandboxes by name, with the context each copy runs on
+		contexts := []string{contextFor(cfg, "")}
+		if allContexts {
+			contexts = cfg.ContextNames()
+		}
+		type hostedSandbox struct {
+			host string
+			c    container.Summary
+		}
+		activeMap := map[string][]hostedSandbox{}
+		// Where each endpoint's published ports answer, for health probes
+		publishedHost := map[string]string{}
+		for _, host := range contexts {
+			if ep, err := cfg.Endpoint(host); err == nil {
+				publishedHost[host] = ep.PublishedHost()
+			}
+			cli, err := newClientFor(cfg, host)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			defer cli.Close()
+			found, err := (&pkg.Dockerengine{Client: cli}).GetActiveSandboxes(ctx)
+			if err != nil && allContexts {
+				fmt.Fprintf(os.Stderr, "⚠️  Skipping context %s: %v\n", host, err)
+				continue
+			}
+			for name, c := range found {
+				activeMap[name] = append(activeMap[name], hostedSandbox{host, c})
+			}
+		}
 
 		entries, _ := os.ReadDir(storageRoot)
 		// We add PORT to the header
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.Debug)
-		fmt.Fprintln(w, "NAME\tTYPE\tSIZE\tSTATUS\tIMAGE\tPORT\tTTL REMAINING\tSTORAGE PATH")
+		header := "NAME\tTYPE\tOWNER\tSIZE\tSTATUS\tHEALTH\tIMAGE\tPORT\tTTL REMAINING\tSTORAGE PATH"
+		if allContexts {
+			header = "NAME\tHOST\t" + strings.TrimPrefix(header, "NAME\t")
+		}
+		fmt.Fprintln(w, header)
 
+		var names []string
+		local := map[string]bool{}
 		for _, entry := range entries {
-			if !entry.IsDir() {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 				continue
 			}
-			name := entry.Name()
-			fullPath := filepath.Join(storageRoot, name)
-
-			sandboxType := "Archived 💾"
-			size := "-"
-			status := "Data Only"
-			imageName := "-"
-			port := "-" // Default for archived data
-			ttlRemaining := "-"
+			names = append(names, entry.Name())
+			local[entry.Name()] = true
+		}
+		// Volume-backed and remote sandboxes have no folder under the
+		// storage root
+		var folderless []string
+		for name, hosted := range activeMap {
+			if !local[name] && hosted[0].c.Labels["com.sbhub.managed"] == "true" {
+				folderless = append(folderless, name)
+			}
+		}
+		sort.Strings(folderless)
+		names = append(names, folderless...)
 
-			if c, exists := activeMap[name]; exists {
-				sandboxType = "Active 🟢"
-				status = c.State
-				imageName = c.Image
-				size = c.Labels["com.sbhub.size"]
+		type listRow struct {
+			cells    []string
+			probeURL string
+		}
+		var rows []listRow
+		for _, name := range names {
+			hosted := activeMap[name]
+			if len(hosted) == 0 {
+				hosted = []hostedSandbox{{host: "-"}}
+			}
+			for _, h := range hosted {
+				fullPath := filepath.Join(storageRoot, name)
+				if h.c.Labels["com.sbhub.storage"] == "volume" {
+					fullPath = "volume:" + pkg.DataVolumeName(name)
+				}
 
-				// Pull the host port from labels
-				if p, ok := c.Labels["com.sbhub.hostport"]; ok {
-					port = p
+				sandboxType := "Archived 💾"
+				size := "-"
+				status := "Data Only"
+				health := "-"
+				probeURL := ""
+				imageName := "-"
+				port := "-" // Default for archived data
+				ttlRemaining := "-"
+				owner := "-"
+				if m, err := engine.Meta.Load(name); err == nil && m.Labels["com.sbhub.owner"] != "" {
+					owner = m.Labels["com.sbhub.owner"]
 				}
 
-				if exp, ok := c.Labels["com.sbhub.expires"]; ok {
-					t, err := time.Parse(time.RFC3339, exp)
-					if err == nil {
-						rem := time.Until(t).Round(time.Second)
-						if rem > 0 {
-							ttlRemaining = rem.String()
-						} else {
-							ttlRemaining = "EXPIRED"
+				if c := h.c; c.ID != "" {
+					sandboxType = "Active 🟢"
+					status = c.State
+					if dh := pkg.DockerHealth(c); dh != "" {
+						health = dh
+					} else if ph, ok := publishedHost[h.host]; ok {
+						probeURL = pkg.HealthProbeURL(c, ph)
+					}
+					imageName = c.Image
+					labels := engine.EffectiveLabels(name, c.Labels)
+					size = labels["com.sbhub.size"]
+					if o := labels["com.sbhub.owner"]; o != "" {
+						owner = o
+					}
+
+					// Pull the host port from labels
+					if p, ok := c.Labels["com.sbhub.hostport"]; ok {
+						port = p
+					}
+
+					if exp, ok := labels["com.sbhub.expires"]; ok {
+						t, err := time.Parse(time.RFC3339, exp)
+						if err == nil {
+							rem := time.Until(t).Round(time.Second)
+							if rem > 0 {
+								ttlRemaining = rem.String()
+							} else {
+								ttlRemaining = "EXPIRED"
+							}
 						}
 					}
 				}
+				if mine && owner != me {
+					continue
+				}
+				row := []string{name, sandboxType, owner, size, status, health, imageName, port, ttlRemaining, fullPath}
+				if allContexts {
+					row = append([]string{name, h.host}, row[1:]...)
+				}
+				rows = append(rows, listRow{row, probeURL})
 			}
-			// Added port variable to the output string
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, sandboxType, size, status, imageName, port, ttlRemaining, fullPath)
+		}
+
+		// Probe health-http sandboxes in parallel so slow ones cost one
+		// timeout in total
+		healthCol := 5
+		if allContexts {
+			healthCol = 6
+		}
+		var wg sync.WaitGroup
+		for i := range rows {
+			if rows[i].probeURL == "" {
+				continue
+			}
+			wg.Add(1)
+			go func(r *listRow) {
+				defer wg.Done()
+				r.cells[healthCol] = "healthy"
+				if err := pkg.ProbeHTTP(ctx, r.probeURL, time.Second); err != nil {
+					r.cells[healthCol] = "unhealthy"
+				}
+			}(&rows[i])
+		}
+		wg.Wait()
+		for _, r := range rows {
+			fmt.Fprintln(w, strings.Join(r.cells, "\t"))
 		}
 		w.Flush()
 	},
 }
 
 func init() {
+	listCmd.Flags().Bool("mine", false, "Only show sandboxes and snapshots owned by you")
+	listCmd.Flags().Bool("all-contexts", false, "List sandboxes from every configured endpoint, with a HOST column")
 	rootCmd.AddCommand(listCmd)
 }
@@ -5,52 +5,205 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
+	"github.com/moby/term"
 	"github.com/spf13/cobra"
 )
 
+// prefixColors cycle through the sandboxes of a merged log.
+var prefixColors = []string{"36", "33", "32", "35", "34", "91", "96", "93"}
+
+// logPrinter formats log lines for the terminal, optionally prefixed with
+// the sandbox name.
+type logPrinter struct {
+	prefix     bool
+	timestamps bool
+	color      bool
+	width      int
+	colors     map[string]string
+}
+
+func newLogPrinter(names []string, prefix, timestamps bool) *logPrinter {
+	_, isTerminal := term.GetFdInfo(os.Stdout)
+	p := &logPrinter{prefix: prefix, timestamps: timestamps, color: isTerminal, colors: map[string]string{}}
+	for i, name := range names {
+		if len(name) > p.width {
+			p.width = len(name)
+		}
+		p.colors[name] = prefixColors[i%len(prefixColors)]
+	}
+	return p
+}
+
+func (p *logPrinter) print(line pkg.LogLine) error {
+	var out io.Writer = os.Stdout
+	if line.Stream == "stderr" {
+		out = os.Stderr
+	}
+	head := ""
+	if p.prefix {
+		head = fmt.Sprintf("%-*s | ", p.width, line.Sandbox)
+		if p.color {
+			head = fmt.Sprintf("\x1b[%sm%s\x1b[0m", p.colors[line.Sandbox], head)
+		}
+	}
+	if p.timestamps && !line.Time.IsZero() {
+		head += line.Time.Local().Format(time.RFC3339) + " "
+	}
+	_, err := fmt.Fprintf(out, "%s%s\n", head, line.Text)
+	return err
+}
+
+// archiveSandboxLogs saves the sandbox's logs before it is removed. Failure
+// is reported but never blocks the removal.
+func archiveSandboxLogs(ctx context.Context, engine *pkg.Dockerengine, storageRoot, name string) {
+	path, err := engine.ArchiveLogs(ctx, name, pkg.LogArchiveDir(storageRoot, name))
+	if err != nil {
+		fmt.Printf("⚠️  Could not archive logs of %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("📜 Logs archived to %s\n", path)
+}
+
+// printArchivedLogs prints every archived log of name, oldest first, within
+// opts.Since and opts.Until, keeping only the last opts.Tail lines unless it
+// is "all".
+func printArchivedLogs(storageRoot, name string, opts pkg.LogOptions, printer *logPrinter) error {
+	archives, err := pkg.ListLogArchives(pkg.LogArchiveDir(storageRoot, name))
+	if err != nil {
+		return err
+	}
+	if len(archives) == 0 {
+		return fmt.Errorf("no archived logs for '%s'", name)
+	}
+	limit := -1
+	if opts.Tail != "all" {
+		if limit, err = strconv.Atoi(opts.Tail); err != nil {
+			return fmt.Errorf("invalid --tail value '%s'", opts.Tail)
+		}
+	}
+	now := time.Now()
+	var since, until time.Time
+	if opts.Since != "" {
+		if since, err = pkg.ParseLogTime(opts.Since, now); err != nil {
+			return fmt.Errorf("--since: %v", err)
+		}
+	}
+	if opts.Until != "" {
+		if until, err = pkg.ParseLogTime(opts.Until, now); err != nil {
+			return fmt.Errorf("--until: %v", err)
+		}
+	}
+
+	var lines []pkg.LogLine
+	for _, path := range archives {
+		err := pkg.ReadLogArchive(path, name, opts.Grep, func(line pkg.LogLine) error {
+			if (!since.IsZero() && line.Time.Before(since)) || (!until.IsZero() && !line.Time.Before(until)) {
+				return nil
+			}
+			lines = append(lines, line)
+			if limit >= 0 && len(lines) > limit {
+				lines = lines[1:]
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, line := range lines {
+		if err := printer.print(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var logsCmd = &cobra.Command{
-	Use:   "logs [name]",
-	Short: "View the output logs of a sandbox",
-	Args:  cobra.ExactArgs(1),
+	Use:   "logs [name...]",
+	Short: "View the output logs of one or more sandboxes",
+	Long: `View the output logs of one or more sandboxes.
+
+With several names the streams are interleaved and each line is prefixed
+with its sandbox name.
+
+  sb logs my-box --since 10m --grep error
+  sb logs api worker -f`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		name := args[0]
 		follow, _ := cmd.Flags().GetBool("follow")
+		tail, _ := cmd.Flags().GetString("tail")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		grep, _ := cmd.Flags().GetString("grep")
+		prefix, _ := cmd.Flags().GetBool("prefix")
+		timestamps, _ := cmd.Flags().GetBool("timestamps")
+		archived, _ := cmd.Flags().GetBool("archived")
+
+		opts := pkg.LogOptions{Follow: follow, Tail: tail, Since: since, Until: until}
+		if grep != "" {
+			re, err := regexp.Compile(grep)
+			if err != nil {
+				fmt.Printf("❌ Invalid --grep pattern: %v\n", err)
+				return
+			}
+			opts.Grep = re
+		}
+
+		printer := newLogPrinter(args, prefix || len(args) > 1, timestamps)
+		if archived {
+			if follow {
+				fmt.Println("❌ --follow cannot be used with --archived")
+				return
+			}
+			for _, name := range args {
+				if err := printArchivedLogs("/home/owen/prac-str", name, opts, printer); err != nil {
+					fmt.Printf("❌ %v\n", err)
+				}
+			}
+			return
+		}
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		cfg := loadConfig()
+		host := contextFor(cfg, sandboxArg(args))
+		cli, err := newClientFor(cfg, host)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
-
-		_, err := engine.InspectSandbox(ctx, name)
+		rt, err := runtimeFor(cfg, host, &pkg.Dockerengine{Client: cli})
 		if err != nil {
-			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+			fmt.Printf("❌ %v\n", err)
 			return
 		}
-		options := container.LogsOptions{
-			ShowStdout: true,
-			ShowStderr: true,
-			Follow:     follow,
-			Timestamps: true,
-			Tail:       "50",
+
+		for _, name := range args {
+			if _, err := rt.Inspect(ctx, name); err != nil {
+				fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+				return
+			}
 		}
 
-		out, err := cli.ContainerLogs(ctx, name, options)
-		if err != nil {
+		if err := pkg.MergeRuntimeLogs(ctx, rt, args, opts, printer.print); err != nil {
 			fmt.Printf("❌ Failed to fetch logs: %v\n", err)
-			return
 		}
-		defer out.Close()
-
-		fmt.Printf("📋 Showing logs for %s...\n", name)
-		io.Copy(os.Stdout, out)
 	},
 }
 
 func init() {
 	logsCmd.Flags().BoolP("follow", "f", false, "Stream live logs")
+	logsCmd.Flags().String("tail", "50", "Number of lines to show from the end of the log, or 'all'")
+	logsCmd.Flags().String("since", "", "Show logs since a timestamp or relative duration (e.g. 10m)")
+	logsCmd.Flags().String("until", "", "Show logs before a timestamp or relative duration")
+	logsCmd.Flags().String("grep", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().Bool("prefix", false, "Prefix each line with the sandbox name (implied with several names)")
+	logsCmd.Flags().BoolP("timestamps", "t", false, "Show timestamps")
+	logsCmd.Flags().Bool("archived", false, "Read logs saved when the sandbox was removed or expired")
 	rootCmd.AddCommand(logsCmd)
 }
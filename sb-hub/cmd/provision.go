@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+)
+
+// initScriptsFor returns the init scripts configured for the preset
+// followed by the ones given on the command line.
+func initScriptsFor(cfg *pkg.Config, size string, extra []string) ([]string, error) {
+	paths := append(append([]string(nil), cfg.Presets[size].Init...), extra...)
+	return pkg.ResolveInitScripts(paths)
+}
+
+// AfterRecreate runs the recorded init scripts and commands of a recreated
+// sandbox again when rerun is set, and otherwise says they were skipped.
+// Output goes to out. Without a metadata store there is nothing recorded.
+func AfterRecreate(ctx context.Context, engine *pkg.Dockerengine, name string, rerun bool, out io.Writer) error {
+	if engine.Meta == nil {
+		return nil
+	}
+	meta, err := engine.Meta.Load(name)
+	if err != nil || meta.Init == nil {
+		return err
+	}
+	if !rerun {
+		fmt.Fprintf(out, "ℹ️  Init scripts already ran on %s; pass --rerun-init to run them again\n", meta.Init.Completed.Format("2006-01-02 15:04"))
+		return nil
+	}
+	if err := engine.InitSandbox(ctx, name, meta.Init.Scripts, out); err != nil {
+		return err
+	}
+	return engine.RunInitCommands(ctx, name, meta.Init.Commands, out)
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+// readPassword reads a password from stdin, without echo on a terminal.
+func readPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		data, err := io.ReadAll(os.Stdin)
+		return strings.TrimRight(string(data), "\r\n"), err
+	}
+	fd, isTerminal := term.GetFdInfo(os.Stdin)
+	if !isTerminal {
+		return "", fmt.Errorf("no terminal to prompt on; use --password-stdin")
+	}
+	fmt.Print("Password: ")
+	state, err := term.SaveState(fd)
+	if err != nil {
+		return "", err
+	}
+	term.DisableEcho(fd, state)
+	defer term.RestoreTerminal(fd, state)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Println()
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage logins for private image registries",
+}
+
+var registryLoginCmd = &cobra.Command{
+	Use:   "login [server]",
+	Short: "Log in to a registry (Docker Hub when server is omitted)",
+	Long: `Log in to a registry (Docker Hub when server is omitted).
+
+The login is checked by the Docker daemon, then saved like docker login
+does: in the registry's credential helper when ~/.docker/config.json
+configures one, otherwise in the file itself.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		server := "docker.io"
+		if len(args) > 0 {
+			server = args[0]
+		}
+		host := pkg.NormalizeRegistry(server)
+		username, _ := cmd.Flags().GetString("username")
+		passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+
+		if username == "" {
+			fmt.Println("❌ --username is required")
+			return
+		}
+		password, err := readPassword(passwordStdin)
+		if err != nil {
+			fmt.Printf("❌ Failed to read password: %v\n", err)
+			return
+		}
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		engine := &pkg.Dockerengine{Client: cli}
+
+		address := host
+		if host == "docker.io" {
+			address = pkg.DockerHubServer
+		}
+		auth, err := engine.Login(context.Background(), registry.AuthConfig{Username: username, Password: password, ServerAddress: address})
+		if err != nil {
+			fmt.Printf("❌ Login to %s failed: %v\n", host, err)
+			return
+		}
+		if err := pkg.StoreDockerLogin(pkg.DockerConfigPath(), auth); err != nil {
+			fmt.Printf("❌ Failed to save the login: %v\n", err)
+			return
+		}
+		fmt.Printf("🔑 Logged in to %s as %s\n", host, username)
+	},
+}
+
+var registryLogoutCmd = &cobra.Command{
+	Use:   "logout [server]",
+	Short: "Remove the saved login of a registry (Docker Hub when server is omitted)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		server := "docker.io"
+		if len(args) > 0 {
+			server = args[0]
+		}
+		host := pkg.NormalizeRegistry(server)
+
+		removed, err := pkg.RemoveDockerLogin(pkg.DockerConfigPath(), host)
+		if err != nil {
+			fmt.Printf("❌ Logout failed: %v\n", err)
+			return
+		}
+		if !removed {
+			fmt.Printf("ℹ️  Not logged in to %s\n", host)
+		} else {
+			fmt.Printf("🔒 Logged out of %s\n", host)
+		}
+		for configured := range loadConfig().Registries {
+			if pkg.NormalizeRegistry(configured) == host {
+				fmt.Printf("⚠️  %s is still configured under registries in the sb-hub config\n", host)
+			}
+		}
+	},
+}
+
+var registryListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List registries with saved logins",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logins, err := loadConfig().Credentials().Logins()
+		if err != nil {
+			fmt.Printf("❌ Failed to read logins: %v\n", err)
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.Debug)
+		fmt.Fprintln(w, "REGISTRY\tUSERNAME\tSOURCE")
+		for _, l := range logins {
+			user := l.Username
+			if user == "" {
+				user = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", l.Host, user, l.Source)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	registryLoginCmd.Flags().StringP("username", "u", "", "Registry username")
+	registryLoginCmd.Flags().Bool("password-stdin", false, "Read the password or token from stdin")
+	registryCmd.AddCommand(registryLoginCmd, registryLogoutCmd, registryListCmd)
+	rootCmd.AddCommand(registryCmd)
+}
@@ -2,16 +2,39 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 )
 
+// errRemoveVetoed marks a removal stopped by a pre-remove hook.
+var errRemoveVetoed = errors.New("vetoed by pre-remove hook")
+
+// removeSandbox runs the pre-remove hooks, keeps the sandbox's logs and
+// removes its container and decrypted secrets. The returned payload is for
+// the post-remove hooks, which callers run once the rest of their cleanup
+// has succeeded.
+func removeSandbox(ctx context.Context, cfg *pkg.Config, engine *pkg.Dockerengine, storageRoot, name string) (pkg.HookPayload, error) {
+	payload := sandboxHookPayload(ctx, engine, pkg.HookPreRemove, storageRoot, name)
+	if err := runHooks(ctx, cfg, payload); err != nil {
+		return payload, fmt.Errorf("%w: %v", errRemoveVetoed, err)
+	}
+	archiveSandboxLogs(ctx, engine, storageRoot, name)
+	if err := engine.RemoveSandbox(ctx, name, "", false); err != nil {
+		return payload, err
+	}
+	if err := pkg.RemoveMountedSecrets(storageRoot, name); err != nil {
+		return payload, fmt.Errorf("failed to remove mounted secrets: %v", err)
+	}
+	return payload, pkg.MetaStoreFor(storageRoot).Remove(name)
+}
+
 var removeCmd = &cobra.Command{
 	Use:     "remove [name]",
 	Aliases: []string{"rm"},
@@ -31,31 +54,81 @@ var removeCmd = &cobra.Command{
 
 		volOnly, _ := cmd.Flags().GetBool("vol-only")
 		storagePath := filepath.Join("/home/owen/prac-str", name)
+		cfg := loadConfig()
+		ctx := context.Background()
 
 		if strings.Contains(name, "_snap_") || volOnly {
+			payload := pkg.NewHookPayload(pkg.HookPreRemove, name, nil, nil, storagePath)
+			if err := runHooks(ctx, cfg, payload); err != nil {
+				fmt.Printf("❌ Removal of %s vetoed by pre-remove hook: %v\n", name, err)
+				return
+			}
 			fmt.Printf("🧹 Wiping volume data at %s...\n", storagePath)
 			if err := exec.Command("sudo", "rm", "-rf", storagePath).Run(); err != nil {
 				fmt.Printf("❌ Failed to wipe folder: %v\n", err)
 				return
 			}
+			pkg.MetaStoreFor("/home/owen/prac-str").Remove(name)
 			fmt.Println("✅ Volume data removed.")
+			runHooks(ctx, cfg, payload.As(pkg.HookPostRemove))
 			return
 		}
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		host := contextFor(cfg, name)
+		cli, err := newClientFor(cfg, host)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
-		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+		rt, err := runtimeFor(cfg, host, engine)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		payload := sandboxHookPayload(ctx, engine, pkg.HookPreRemove, "/home/owen/prac-str", name)
+		if err := runHooks(ctx, cfg, payload); err != nil {
+			fmt.Printf("❌ Removal of %s vetoed by pre-remove hook: %v\n", name, err)
+			return
+		}
 
 		fmt.Printf("🗑️  Removing sandbox %s and all associated data...\n", name)
 
-		engine.RemoveSandbox(ctx, name, "", false)
+		info, inspectErr := rt.Inspect(ctx, name)
+		if inspectErr == nil {
+			archiveSandboxLogs(ctx, engine, "/home/owen/prac-str", name)
+			rt.Stop(ctx, name, 30*time.Second)
+			if err := rt.Remove(ctx, name); err != nil {
+				fmt.Printf("❌ Failed to remove container: %v\n", err)
+				return
+			}
+		}
+		// Post-remove hooks only run once everything is gone
+		failed := false
+		if err := pkg.RemoveMountedSecrets("/home/owen/prac-str", name); err != nil {
+			fmt.Printf("❌ Failed to remove mounted secrets: %v\n", err)
+			failed = true
+		}
+		engine.Meta.Remove(name)
+
+		if inspectErr == nil && info.Labels["com.sbhub.storage"] == "volume" {
+			if err := engine.RemoveVolume(ctx, pkg.DataVolumeName(name)); err != nil {
+				fmt.Printf("❌ Failed to remove data volume: %v\n", err)
+				failed = true
+			}
+		}
 
 		if err := exec.Command("sudo", "rm", "-rf", storagePath).Run(); err != nil {
 			fmt.Printf("❌ Failed to wipe storage path: %v\n", err)
-		} else {
-			fmt.Println("✅ Done.")
+			failed = true
+		}
+		if failed {
+			return
 		}
+		fmt.Println("✅ Done.")
+		runHooks(ctx, cfg, payload.As(pkg.HookPostRemove))
 	},
 }
 
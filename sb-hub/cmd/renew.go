@@ -3,13 +3,25 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/NjariaOwen/sb-hub/pkg"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/api/types/container"
 	"github.com/spf13/cobra"
 )
 
+// renewSandbox recreates the sandbox from its own config with a fresh
+// expiry label, since Docker labels cannot be changed in place. The old
+// container's logs are archived first, and its creation time is kept so
+// owner MaxTTLs still count from the original create.
+func renewSandbox(ctx context.Context, engine *pkg.Dockerengine, name string, inspect container.InspectResponse, ttl time.Duration) (string, error) {
+	archiveSandboxLogs(ctx, engine, "/home/owen/prac-str", name)
+	engine.KeepCreated(name, inspect)
+	engine.RemoveSandbox(ctx, name, "", false)
+	return engine.CreateSandbox(ctx, name, ttl, engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.size"], inspect.Config, inspect.HostConfig)
+}
+
 var renewCmd = &cobra.Command{
 	Use:   "renew [name] [duration]",
 	Short: "Extend the TTL of an active sandbox",
@@ -17,6 +29,7 @@ var renewCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
 		durationStr := args[1]
+		rerun, _ := cmd.Flags().GetBool("rerun-init")
 
 		extension, err := time.ParseDuration(durationStr)
 		if err != nil {
@@ -24,10 +37,14 @@ var renewCmd = &cobra.Command{
 			return
 		}
 
-		cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
 		defer cli.Close()
 		ctx := context.Background()
-		engine := &pkg.Dockerengine{Client: cli}
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
 
 		inspect, err := engine.InspectSandbox(ctx, name)
 		if err != nil {
@@ -35,19 +52,27 @@ var renewCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Printf("⏱️  Renewing %s for %s...\n", name, durationStr)
+		owner := engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.owner"]
+		if clamped := loadConfig().PolicyFor(owner).ClampTTL(extension); clamped != extension {
+			fmt.Printf("⏱️  TTL capped at %s by the policy for %s\n", clamped, owner)
+			extension, durationStr = clamped, clamped.String()
+		}
 
-		engine.RemoveSandbox(ctx, name, "", false)
+		fmt.Printf("⏱️  Renewing %s for %s...\n", name, durationStr)
 
-		id, err := engine.CreateSandbox(ctx, name, extension, inspect.Config.Labels["com.sbhub.size"], inspect.Config, inspect.HostConfig)
+		id, err := renewSandbox(ctx, engine, name, inspect, extension)
 		if err != nil {
 			fmt.Printf("❌ Renew failed: %v\n", err)
 		} else {
 			fmt.Printf("✅ Renewed. New ID: %s\n", id[:12])
+			if err := AfterRecreate(ctx, engine, name, rerun, os.Stdout); err != nil {
+				fmt.Printf("❌ Init failed: %v\n", err)
+			}
 		}
 	},
 }
 
 func init() {
+	renewCmd.Flags().Bool("rerun-init", false, "Run the sandbox's init scripts again in the new container")
 	rootCmd.AddCommand(renewCmd)
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+var resizeCmd = &cobra.Command{
+	Use:   "resize [name] [size]",
+	Short: "Change the size preset of a running sandbox in place",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, size := args[0], strings.ToLower(args[1])
+		recomputeTTL, _ := cmd.Flags().GetBool("reset-ttl")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if _, ok := pkg.SandboxSpecs[size]; !ok {
+			fmt.Printf("❌ Unknown size '%s' (use small, medium, large or xlarge)\n", size)
+			return
+		}
+
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+			return
+		}
+		current := engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.size"]
+		if current == size && !recomputeTTL {
+			fmt.Printf("✔️  %s is already %s.\n", name, size)
+			return
+		}
+
+		cfg := loadConfig()
+		if err := engine.CheckCapacity(ctx, name, size, capacityPolicy(cfg)); err != nil {
+			if !force {
+				fmt.Printf("❌ Host lacks capacity: %v (use --force to resize anyway)\n", err)
+				return
+			}
+			fmt.Printf("⚠️  Host lacks capacity: %v\n", err)
+		}
+
+		spec := pkg.SandboxSpecs[size]
+		var ttl time.Duration
+		if recomputeTTL {
+			owner := engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.owner"]
+			ttl = cfg.PolicyFor(owner).ClampTTL(spec.DefaultTTL)
+			if ttl != spec.DefaultTTL {
+				fmt.Printf("⏱️  TTL capped at %s by the policy for %s\n", ttl, owner)
+			}
+		}
+
+		fmt.Printf("📐 Resizing %s from %s to %s...\n", name, current, size)
+		if err := engine.ResizeSandbox(ctx, name, size, ttl); err != nil {
+			fmt.Printf("❌ Resize failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ %s now has %.1f CPUs and %d MB memory.\n", name, spec.CPUCores, spec.MemoryMB)
+		if recomputeTTL {
+			fmt.Printf("⏱️  TTL reset to %s.\n", ttl)
+		}
+	},
+}
+
+func init() {
+	resizeCmd.Flags().Bool("reset-ttl", false, "Reset the TTL to the new preset's default")
+	resizeCmd.Flags().Bool("force", false, "Resize even if the host lacks capacity")
+	rootCmd.AddCommand(resizeCmd)
+}
@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 )
 
@@ -9,6 +14,95 @@ var rootCmd = &cobra.Command{
 	Short: "sb-hub is a CLI for managing development sandboxes",
 }
 
+// loadConfig reads the user config, falling back to defaults with a warning
+// when it cannot be parsed.
+func loadConfig() *pkg.Config {
+	path := pkg.DefaultConfigPath()
+	cfg, err := pkg.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Ignoring %s: %v\n", path, err)
+		return &pkg.Config{}
+	}
+	return cfg
+}
+
+// explicitContext returns the context chosen with --context or
+// $SBHUB_CONTEXT, if any.
+func explicitContext() string {
+	if name, _ := rootCmd.PersistentFlags().GetString("context"); name != "" {
+		return name
+	}
+	return os.Getenv("SBHUB_CONTEXT")
+}
+
+// contextFor picks the endpoint a command talks to: an explicit context,
+// then the one sandbox was placed on, then the config default.
+func contextFor(cfg *pkg.Config, sandbox string) string {
+	if name := explicitContext(); name != "" {
+		return name
+	}
+	if sandbox != "" {
+		if meta, err := pkg.MetaStoreFor("/home/owen/prac-str").Load(sandbox); err == nil && meta.Context != "" {
+			return meta.Context
+		}
+	}
+	if cfg.Context != "" {
+		return cfg.Context
+	}
+	return pkg.DefaultContext
+}
+
+// newClient connects to the Docker endpoint of sandbox, or of the command
+// when sandbox is empty.
+func newClient(sandbox string) (*client.Client, error) {
+	cfg := loadConfig()
+	return newClientFor(cfg, contextFor(cfg, sandbox))
+}
+
+// newClientFor connects to a named context.
+func newClientFor(cfg *pkg.Config, name string) (*client.Client, error) {
+	ep, err := cfg.Endpoint(name)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.NewDockerClient(ep)
+}
+
+// localEndpoint returns the sandbox's endpoint, failing when it is remote,
+// for commands that bind folders on this machine. why says what needs them.
+func localEndpoint(cfg *pkg.Config, sandbox, why string) (pkg.Endpoint, error) {
+	host := contextFor(cfg, sandbox)
+	ep, err := cfg.Endpoint(host)
+	if err != nil {
+		return ep, err
+	}
+	if !ep.Local() {
+		return ep, fmt.Errorf("context %s is remote: %s, so it needs a local endpoint", host, why)
+	}
+	return ep, nil
+}
+
+// runtimeFor returns the Runtime of a named context on engine's client.
+func runtimeFor(cfg *pkg.Config, name string, engine *pkg.Dockerengine) (pkg.Runtime, error) {
+	ep, err := cfg.Endpoint(name)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.RuntimeFor(ep, engine)
+}
+
+// sandboxArg returns the first sandbox named on the command line, if any.
+func sandboxArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("context", "", "Docker endpoint from the config to use (default $SBHUB_CONTEXT, the sandbox's own, or context in the config)")
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 
+	"github.com/NjariaOwen/sb-hub/pkg"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +27,19 @@ var saveCmd = &cobra.Command{
 			return
 		}
 
+		cfg := loadConfig()
+		owner := cfg.CurrentOwner()
+		meta := pkg.MetaStoreFor(storageRoot)
+		usage := pkg.OwnerUsage{SnapshotBytes: pkg.SnapshotBytes(storageRoot, meta, owner)}
+		if prev, err := meta.Load(filepath.Base(dst)); err == nil && prev.Labels["com.sbhub.owner"] == owner {
+			// Overwriting frees the old copy
+			usage.SnapshotBytes -= pkg.TreeSize(dst)
+		}
+		if err := cfg.PolicyFor(owner).CheckSnapshot(usage, pkg.TreeSize(src)); err != nil {
+			fmt.Printf("❌ Cannot save for %s: %v\n", owner, err)
+			return
+		}
+
 		if _, err := os.Stat(dst); err == nil {
 			fmt.Printf("🔄 Snapshot '%s' exists. Overwriting...\n", tag)
 			exec.Command("sudo", "rm", "-rf", dst).Run()
@@ -36,7 +51,20 @@ var saveCmd = &cobra.Command{
 			fmt.Printf("❌ Failed to save: %v\n", err)
 			return
 		}
+		meta.SetLabels(filepath.Base(dst), map[string]string{"com.sbhub.owner": owner})
 		fmt.Println("✅ Saved successfully.")
+
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: meta}
+		payload := sandboxHookPayload(ctx, engine, pkg.HookPostSave, storageRoot, name)
+		payload.Snapshot = dst
+		runHooks(ctx, cfg, payload)
 	},
 }
 
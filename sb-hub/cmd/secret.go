@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+func openSecretStore() (*pkg.SecretStore, error) {
+	storageRoot := "/home/owen/prac-str"
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return pkg.OpenSecretStore(filepath.Join(storageRoot, ".secrets"), filepath.Join(home, ".sbhub", "secrets.key"))
+}
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage the local encrypted secret store",
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set [name] [file]",
+	Short: "Store a secret from a file (or stdin when file is omitted or '-')",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		var value []byte
+		var err error
+		if len(args) == 2 && args[1] != "-" {
+			value, err = os.ReadFile(args[1])
+		} else {
+			value, err = io.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to read secret: %v\n", err)
+			return
+		}
+
+		store, err := openSecretStore()
+		if err != nil {
+			fmt.Printf("❌ Failed to open secret store: %v\n", err)
+			return
+		}
+		if err := store.Set(name, value); err != nil {
+			fmt.Printf("❌ Failed to store secret: %v\n", err)
+			return
+		}
+		fmt.Printf("🔐 Secret '%s' stored.\n", name)
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List stored secret names",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSecretStore()
+		if err != nil {
+			fmt.Printf("❌ Failed to open secret store: %v\n", err)
+			return
+		}
+		names, err := store.List()
+		if err != nil {
+			fmt.Printf("❌ Failed to list secrets: %v\n", err)
+			return
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	},
+}
+
+var secretRemoveCmd = &cobra.Command{
+	Use:     "remove [name]",
+	Aliases: []string{"rm"},
+	Short:   "Delete a stored secret",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSecretStore()
+		if err != nil {
+			fmt.Printf("❌ Failed to open secret store: %v\n", err)
+			return
+		}
+		if err := store.Remove(args[0]); err != nil {
+			fmt.Printf("❌ Failed to remove secret: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Secret '%s' removed.\n", args[0])
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd, secretListCmd, secretRemoveCmd)
+	rootCmd.AddCommand(secretCmd)
+}
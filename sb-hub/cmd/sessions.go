@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+func sessionsDir() string {
+	return filepath.Join("/home/owen/prac-str", ".sessions")
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Browse and replay recorded console sessions",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:     "list [name]",
+	Aliases: []string{"ls"},
+	Short:   "List recorded console sessions of a sandbox",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sessions, err := pkg.ListSessions(sessionsDir(), args[0])
+		if err != nil {
+			fmt.Printf("❌ Failed to list sessions: %v\n", err)
+			return
+		}
+		if len(sessions) == 0 {
+			fmt.Printf("No recorded sessions for %s.\n", args[0])
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tSIZE")
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.Started.Local().Format("2006-01-02 15:04:05"), s.Duration.Round(time.Second), pkg.HumanBytes(s.Size))
+		}
+		w.Flush()
+	},
+}
+
+var sessionsPlayCmd = &cobra.Command{
+	Use:   "play [id]",
+	Short: "Replay a recorded console session with its original timing",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		speed, _ := cmd.Flags().GetFloat64("speed")
+		idle, _ := cmd.Flags().GetDuration("idle-limit")
+
+		path, err := pkg.FindSession(sessionsDir(), args[0])
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("❌ Failed to open session: %v\n", err)
+			return
+		}
+		defer f.Close()
+
+		if err := pkg.PlayCast(f, os.Stdout, speed, idle); err != nil {
+			fmt.Printf("\n❌ Playback failed: %v\n", err)
+			return
+		}
+		fmt.Printf("\n⏹️  End of session %s\n", args[0])
+	},
+}
+
+func init() {
+	sessionsPlayCmd.Flags().Float64("speed", 1, "Playback speed multiplier")
+	sessionsPlayCmd.Flags().Duration("idle-limit", 2*time.Second, "Cap pauses between events (0 keeps the original timing)")
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsPlayCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
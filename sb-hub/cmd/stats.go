@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+// statsTarget is a sandbox to sample and the preset it was created with.
+type statsTarget struct {
+	Name string
+	Size string
+}
+
+// resolveStatsTargets returns the named sandboxes, or every running
+// sb-hub sandbox when no names are given.
+func resolveStatsTargets(ctx context.Context, engine *pkg.Dockerengine, names []string) ([]statsTarget, error) {
+	var targets []statsTarget
+	if len(names) == 0 {
+		active, err := engine.GetActiveSandboxes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for name, c := range active {
+			if c.State == "running" && c.Labels["com.sbhub.managed"] == "true" {
+				targets = append(targets, statsTarget{Name: name, Size: engine.EffectiveLabels(name, c.Labels)["com.sbhub.size"]})
+			}
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+		return targets, nil
+	}
+	for _, name := range names {
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox '%s' not found", name)
+		}
+		targets = append(targets, statsTarget{Name: name, Size: engine.EffectiveLabels(name, inspect.Config.Labels)["com.sbhub.size"]})
+	}
+	return targets, nil
+}
+
+// sampleAll takes one sample of every target in parallel.
+func sampleAll(ctx context.Context, engine *pkg.Dockerengine, targets []statsTarget) []pkg.SandboxStats {
+	samples := make([]pkg.SandboxStats, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t statsTarget) {
+			defer wg.Done()
+			s, err := engine.SampleStats(ctx, t.Name, t.Size)
+			if err != nil {
+				s = pkg.SandboxStats{Name: t.Name, Size: t.Size}
+			}
+			samples[i] = s
+		}(i, t)
+	}
+	wg.Wait()
+	return samples
+}
+
+// sortStats orders samples by the given column, busiest first.
+func sortStats(samples []pkg.SandboxStats, by string) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		switch by {
+		case "mem":
+			return samples[i].MemPercent > samples[j].MemPercent
+		case "name":
+			return samples[i].Name < samples[j].Name
+		default:
+			return samples[i].CPULimitPercent > samples[j].CPULimitPercent
+		}
+	})
+}
+
+func printStatsTable(samples []pkg.SandboxStats, warnings []string) {
+	if _, isTerminal := term.GetFdInfo(os.Stdout); isTerminal {
+		fmt.Print("\033[H\033[2J")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tCPU %\tOF LIMIT\tMEM USAGE / LIMIT\tMEM %\tNET I/O\tBLOCK I/O\tPIDS")
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s\t%s\t%.1f%%\t%.0f%%\t%s / %s\t%.1f%%\t%s / %s\t%s / %s\t%d\n",
+			s.Name, s.Size, s.CPUPercent, s.CPULimitPercent,
+			pkg.HumanBytes(int64(s.MemUsage)), pkg.HumanBytes(int64(s.MemLimit)), s.MemPercent,
+			pkg.HumanBytes(int64(s.NetRx)), pkg.HumanBytes(int64(s.NetTx)),
+			pkg.HumanBytes(int64(s.BlockRead)), pkg.HumanBytes(int64(s.BlockWrite)), s.Pids)
+	}
+	w.Flush()
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+}
+
+// watchStatsBoard streams stats for every target and redraws the table
+// every interval. Samples are ordered by sortBy and cut to limit (0 = all).
+func watchStatsBoard(ctx context.Context, engine *pkg.Dockerengine, targets []statsTarget, interval time.Duration, sortBy string, limit int) {
+	var mu sync.Mutex
+	latest := map[string]pkg.SandboxStats{}
+	watch := pkg.NewLimitWatch(90, 5)
+	var warnings []string
+
+	for _, t := range targets {
+		go func(t statsTarget) {
+			err := engine.WatchStats(ctx, t.Name, t.Size, func(s pkg.SandboxStats) {
+				mu.Lock()
+				defer mu.Unlock()
+				latest[s.Name] = s
+				for _, w := range watch.Observe(s) {
+					warnings = append(warnings, fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), w))
+				}
+			})
+			if err != nil {
+				mu.Lock()
+				warnings = append(warnings, fmt.Sprintf("%s stats stopped: %v", t.Name, err))
+				mu.Unlock()
+			}
+		}(t)
+	}
+
+	for {
+		time.Sleep(interval)
+		mu.Lock()
+		var samples []pkg.SandboxStats
+		for _, s := range latest {
+			samples = append(samples, s)
+		}
+		if len(warnings) > 5 {
+			warnings = warnings[len(warnings)-5:]
+		}
+		shown := append([]string(nil), warnings...)
+		mu.Unlock()
+
+		sortStats(samples, sortBy)
+		if limit > 0 && len(samples) > limit {
+			samples = samples[:limit]
+		}
+		printStatsTable(samples, shown)
+	}
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [name...]",
+	Short: "Stream CPU, memory, network and disk usage of sandboxes",
+	Run: func(cmd *cobra.Command, args []string) {
+		noStream, _ := cmd.Flags().GetBool("no-stream")
+
+		cli, err := newClient(sandboxArg(args))
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		targets, err := resolveStatsTargets(ctx, engine, args)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		if len(targets) == 0 {
+			fmt.Println("No running sandboxes.")
+			return
+		}
+
+		if noStream {
+			samples := sampleAll(ctx, engine, targets)
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(samples)
+			return
+		}
+		watchStatsBoard(ctx, engine, targets, time.Second, "name", 0)
+	},
+}
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show the busiest sandboxes",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+		sortBy, _ := cmd.Flags().GetString("sort")
+		once, _ := cmd.Flags().GetBool("once")
+		if sortBy != "cpu" && sortBy != "mem" {
+			fmt.Printf("❌ --sort must be cpu or mem, got '%s'\n", sortBy)
+			return
+		}
+
+		cli, err := newClient("")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		targets, err := resolveStatsTargets(ctx, engine, nil)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		if len(targets) == 0 {
+			fmt.Println("No running sandboxes.")
+			return
+		}
+
+		if once {
+			samples := sampleAll(ctx, engine, targets)
+			sortStats(samples, sortBy)
+			if limit > 0 && len(samples) > limit {
+				samples = samples[:limit]
+			}
+			printStatsTable(samples, nil)
+			return
+		}
+		watchStatsBoard(ctx, engine, targets, 2*time.Second, sortBy, limit)
+	},
+}
+
+func init() {
+	statsCmd.Flags().Bool("no-stream", false, "Print a single JSON snapshot and exit")
+	topCmd.Flags().IntP("limit", "n", 10, "Number of sandboxes to show (0 = all)")
+	topCmd.Flags().String("sort", "cpu", "Sort by cpu or mem (relative to the preset limit)")
+	topCmd.Flags().Bool("once", false, "Print one snapshot and exit")
+	rootCmd.AddCommand(statsCmd, topCmd)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [name] [localdir]:[path]",
+	Short: "Keep a host directory in sync with a path inside a sandbox",
+	Long: `Keep a host directory in sync with a path inside a sandbox.
+
+Modes:
+  bind     recreate the sandbox with the directory bind mounted at path
+  push     copy the directory in, then watch it and push every change (default)
+  two-way  like push, and also poll the sandbox for files changed inside it
+
+push and two-way copy through the Docker archive API, so they work when
+bind mounts aren't possible (remote daemons, rootless setups). Paths
+matched by .gitignore or .sbignore in the directory are skipped.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		mode, _ := cmd.Flags().GetString("mode")
+		once, _ := cmd.Flags().GetBool("once")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		rerun, _ := cmd.Flags().GetBool("rerun-init")
+
+		localDir, containerPath, err := pkg.ParseSyncSpec(args[1])
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		var ep pkg.Endpoint
+		if mode == "bind" {
+			if ep, err = localEndpoint(loadConfig(), name, "--mode bind mounts a host directory (use push or two-way instead)"); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+		}
+
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str"), Relabel: ep.Relabels()}
+
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+			return
+		}
+
+		switch mode {
+		case "bind":
+			fmt.Printf("🔗 Binding %s to %s:%s...\n", localDir, name, containerPath)
+			labels := engine.EffectiveLabels(name, inspect.Config.Labels)
+			ttl := pkg.RemainingTTL(labels, 1*time.Hour)
+			archiveSandboxLogs(ctx, engine, "/home/owen/prac-str", name)
+			engine.KeepCreated(name, inspect)
+			engine.RemoveSandbox(ctx, name, "", false)
+
+			inspect.HostConfig.Binds = pkg.ReplaceBind(inspect.HostConfig.Binds, containerPath, fmt.Sprintf("%s:%s", localDir, containerPath))
+			id, err := engine.CreateSandbox(ctx, name, ttl, labels["com.sbhub.size"], inspect.Config, inspect.HostConfig)
+			if err != nil {
+				fmt.Printf("❌ Bind failed: %v\n", err)
+				return
+			}
+			fmt.Printf("✅ Bound. New ID: %s\n", id[:12])
+			if err := AfterRecreate(ctx, engine, name, rerun, os.Stdout); err != nil {
+				fmt.Printf("❌ Init failed: %v\n", err)
+			}
+			return
+		case "push", "two-way":
+		default:
+			fmt.Printf("❌ Invalid mode: %s (expected bind, push or two-way)\n", mode)
+			return
+		}
+
+		if !inspect.State.Running {
+			fmt.Printf("❌ Sandbox '%s' is %s. Please start it first.\n", name, inspect.State.Status)
+			return
+		}
+
+		ignore, err := pkg.LoadIgnoreMatcher(localDir)
+		if err != nil {
+			fmt.Printf("❌ Failed to read ignore files: %v\n", err)
+			return
+		}
+		opts := pkg.SyncOptions{
+			Sandbox:       name,
+			LocalDir:      localDir,
+			ContainerPath: containerPath,
+			TwoWay:        mode == "two-way",
+			Interval:      interval,
+			Ignore:        ignore,
+		}
+
+		if once {
+			fmt.Printf("📤 Copying %s to %s:%s...\n", localDir, name, containerPath)
+			if err := engine.PushTree(ctx, opts, nil); err != nil {
+				fmt.Printf("❌ Sync failed: %v\n", err)
+				return
+			}
+			fmt.Println("✅ Synced.")
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+		fmt.Printf("👀 Syncing %s to %s:%s (%s). Press Ctrl+C to stop.\n", localDir, name, containerPath, mode)
+		if err := engine.WatchSync(ctx, opts); err != nil {
+			fmt.Printf("❌ Sync failed: %v\n", err)
+			return
+		}
+		fmt.Println("\n✅ Sync stopped.")
+	},
+}
+
+func init() {
+	syncCmd.Flags().String("mode", "push", "Sync mode: bind, push or two-way")
+	syncCmd.Flags().Bool("once", false, "Copy the directory once and exit instead of watching")
+	syncCmd.Flags().Duration("interval", 2*time.Second, "How often two-way mode polls the sandbox for changes")
+	syncCmd.Flags().Bool("rerun-init", false, "With --mode bind, run the sandbox's init scripts again in the new container")
+	rootCmd.AddCommand(syncCmd)
+}
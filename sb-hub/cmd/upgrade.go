@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+// shortID trims the algorithm prefix and truncates a digest or image ID
+// for display.
+func shortID(id string) string {
+	if i := len("sha256:"); len(id) > i && id[:i] == "sha256:" {
+		id = id[i:]
+	}
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+// replaceImage recreates the sandbox on image, keeping its binds, ports,
+// env and remaining TTL, then handles its init scripts.
+func replaceImage(ctx context.Context, engine *pkg.Dockerengine, name string, inspect container.InspectResponse, image string, rerun bool) {
+	fmt.Printf("♻️  Recreating %s...\n", name)
+	archiveSandboxLogs(ctx, engine, "/home/owen/prac-str", name)
+	id, err := engine.RecreateSandbox(ctx, name, inspect, image)
+	if err != nil {
+		fmt.Printf("❌ Recreate failed: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ %s recreated. New ID: %s\n", name, id[:12])
+	if err := AfterRecreate(ctx, engine, name, rerun, os.Stdout); err != nil {
+		fmt.Printf("❌ Init failed: %v\n", err)
+	}
+}
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild [name]",
+	Short: "Rebuild an imported sandbox's image from its source and recreate it",
+	Long: `Rebuild an imported sandbox's image from its source and recreate it.
+
+The build re-runs in the source directory, with the Dockerfile and target
+recorded on the image by sb import. Build args are not recorded; pass them
+again with --build-arg. If the build produces the same image, the sandbox
+is left alone unless --force is given. Otherwise it is recreated with the
+same binds, port mappings, env and remaining TTL.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		buildArgFlags, _ := cmd.Flags().GetStringArray("build-arg")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		force, _ := cmd.Flags().GetBool("force")
+		rerun, _ := cmd.Flags().GetBool("rerun-init")
+
+		buildArgs, err := pkg.ParseBuildArgs(buildArgFlags)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+			return
+		}
+		img, err := cli.ImageInspect(ctx, inspect.Image)
+		if err != nil {
+			fmt.Printf("❌ Cannot inspect the image of %s: %v\n", name, err)
+			return
+		}
+		var labels map[string]string
+		if img.Config != nil {
+			labels = img.Config.Labels
+		}
+		source, opts, err := pkg.RebuildOptions(labels)
+		if err != nil {
+			fmt.Printf("❌ Cannot rebuild %s: %v\n", name, err)
+			return
+		}
+		opts.BuildArgs, opts.NoCache = buildArgs, noCache
+
+		tag := inspect.Config.Image
+		if err := engine.BuildImageWith(ctx, source, tag, opts); err != nil {
+			fmt.Printf("❌ Build failed: %v\n", err)
+			return
+		}
+		newID, err := engine.ImageID(ctx, tag)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		if newID == inspect.Image && !force {
+			fmt.Printf("✅ %s is up to date (%s)\n", name, shortID(newID))
+			return
+		}
+		fmt.Printf("🔄 %s: %s → %s\n", tag, shortID(inspect.Image), shortID(newID))
+		replaceImage(ctx, engine, name, inspect, tag, rerun)
+	},
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [name]",
+	Short: "Pull a sandbox's image again and recreate it if the image changed",
+	Long: `Pull a sandbox's image again and recreate it if the image changed.
+
+The digest of the freshly pulled image is compared with the one the
+sandbox runs. When they differ the sandbox is recreated with the same
+binds, port mappings, env and remaining TTL. Images built by sb import
+are upgraded with sb rebuild instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		check, _ := cmd.Flags().GetBool("check")
+		force, _ := cmd.Flags().GetBool("force")
+		rerun, _ := cmd.Flags().GetBool("rerun-init")
+
+		cfg := loadConfig()
+		opts := pkg.PullOptions{Policy: pkg.PullAlways, Timeout: cfg.Images.PullTimeout, Strict: true, Auth: cfg.Credentials()}
+		if cmd.Flags().Changed("pull-timeout") {
+			opts.Timeout, _ = cmd.Flags().GetDuration("pull-timeout")
+		}
+		quiet, _ := cmd.Flags().GetBool("quiet-pull")
+		_, isTerminal := term.GetFdInfo(os.Stdout)
+		opts.Progress = isTerminal && !quiet
+
+		cli, err := newClient(name)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		engine := &pkg.Dockerengine{Client: cli, Meta: pkg.MetaStoreFor("/home/owen/prac-str")}
+
+		inspect, err := engine.InspectSandbox(ctx, name)
+		if err != nil {
+			fmt.Printf("❌ Sandbox '%s' not found.\n", name)
+			return
+		}
+		ref := inspect.Config.Image
+		old, oldErr := cli.ImageInspect(ctx, inspect.Image)
+		if oldErr == nil && old.Config != nil && old.Config.Labels["com.sbhub.source"] != "" {
+			fmt.Printf("❌ %s runs an image built from %s; use sb rebuild %s\n", name, old.Config.Labels["com.sbhub.source"], name)
+			return
+		}
+
+		if err := engine.PullImage(ctx, ref, opts); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		pulled, err := cli.ImageInspect(ctx, ref)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		oldDigest := inspect.Image
+		if oldErr == nil {
+			oldDigest = pkg.ImageDigest(old)
+		}
+		if pulled.ID == inspect.Image && !force {
+			fmt.Printf("✅ %s is up to date (%s %s)\n", name, ref, shortID(pkg.ImageDigest(pulled)))
+			return
+		}
+		fmt.Printf("🔄 %s: %s → %s\n", ref, shortID(oldDigest), shortID(pkg.ImageDigest(pulled)))
+		if check {
+			fmt.Printf("ℹ️  Run sb upgrade %s to recreate it on the new image\n", name)
+			return
+		}
+		replaceImage(ctx, engine, name, inspect, ref, rerun)
+	},
+}
+
+func init() {
+	rebuildCmd.Flags().StringArray("build-arg", nil, "Set a build-time variable (KEY=VALUE, or KEY to pass through from the host)")
+	rebuildCmd.Flags().Bool("no-cache", false, "Do not use the build cache")
+	rebuildCmd.Flags().Bool("force", false, "Recreate the sandbox even if the image did not change")
+	rebuildCmd.Flags().Bool("rerun-init", false, "Run the sandbox's init scripts again in the new container")
+	rootCmd.AddCommand(rebuildCmd)
+
+	upgradeCmd.Flags().Bool("check", false, "Only pull and report whether a newer image is available")
+	upgradeCmd.Flags().Bool("force", false, "Recreate the sandbox even if the image did not change")
+	upgradeCmd.Flags().Duration("pull-timeout", 0, "Give up on the pull after this long (default from images.pull_timeout in config, else 10m)")
+	upgradeCmd.Flags().Bool("quiet-pull", false, "Print only a summary line instead of per-layer progress")
+	upgradeCmd.Flags().Bool("rerun-init", false, "Run the sandbox's init scripts again in the new container")
+	rootCmd.AddCommand(upgradeCmd)
+}
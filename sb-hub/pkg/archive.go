@@ -0,0 +1,204 @@
+package pkg
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TarTree streams root as a tar archive whose entry names are prefixed with
+// dstPrefix, so extracting it at "/" in a container recreates the tree at
+// dstPrefix. When rels is non-empty only those paths (relative to root) are
+// included. Paths matched by ignore are skipped. Symlinks are stored as
+// links and file modes and mtimes are preserved.
+func TarTree(root, dstPrefix string, rels []string, ignore *IgnoreMatcher) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := writeTree(tw, root, dstPrefix, rels, ignore)
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+func writeTree(tw *tar.Writer, root, dstPrefix string, rels []string, ignore *IgnoreMatcher) error {
+	if len(rels) == 0 {
+		rels = []string{"."}
+	}
+	for _, rel := range rels {
+		start := filepath.Join(root, rel)
+		err := filepath.Walk(start, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			r, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			if r != "." && ignore.Ignored(r) {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return writeEntry(tw, p, path.Join(strings.TrimPrefix(dstPrefix, "/"), filepath.ToSlash(r)), fi)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, src, name string, fi os.FileInfo) error {
+	if name == "" || name == "." {
+		return nil
+	}
+	link := ""
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		link = target
+	}
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if fi.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ExtractFilter decides whether a tar entry (named relative to the extract
+// root) should be written. Returning false skips it.
+type ExtractFilter func(rel string, hdr *tar.Header) bool
+
+// ExtractTar unpacks a tar stream into dst. The first path component of
+// every entry is replaced by dst, matching the layout produced by the Docker
+// archive endpoint for a directory. Entries escaping dst are rejected, either
+// by name, through a symlink unpacked earlier, or as a link target.
+func ExtractTar(r io.Reader, dst string, filter ExtractFilter) error {
+	dst = filepath.Clean(dst)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rel := "."
+		if _, rest, ok := strings.Cut(strings.TrimSuffix(hdr.Name, "/"), "/"); ok {
+			rel = filepath.FromSlash(rest)
+		}
+		if filter != nil && !filter(rel, hdr) {
+			continue
+		}
+		target := filepath.Join(dst, rel)
+		if !withinDir(dst, target) {
+			return fmt.Errorf("archive entry '%s' escapes destination", hdr.Name)
+		}
+		if err := checkNoSymlinks(dst, target, hdr.Typeflag == tar.TypeDir); err != nil {
+			return fmt.Errorf("archive entry '%s': %v", hdr.Name, err)
+		}
+
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+			os.Chmod(target, mode|0700)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		case tar.TypeSymlink:
+			if filepath.IsAbs(hdr.Linkname) || !withinDir(dst, filepath.Join(filepath.Dir(target), hdr.Linkname)) {
+				return fmt.Errorf("archive entry '%s' links outside destination: %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// withinDir reports whether the cleaned path p is dir or below it.
+func withinDir(dir, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(os.PathSeparator))
+}
+
+// checkNoSymlinks refuses to write target when a folder between dst and it
+// is a symlink, so an earlier entry cannot redirect later ones. With
+// self, target itself must not be a symlink either, as for directories
+// that are created in place rather than replaced.
+func checkNoSymlinks(dst, target string, self bool) error {
+	rel, err := filepath.Rel(dst, target)
+	if err != nil || rel == "." {
+		return err
+	}
+	parts := strings.Split(rel, string(os.PathSeparator))
+	if !self {
+		parts = parts[:len(parts)-1]
+	}
+	p := dst
+	for _, part := range parts {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	archive "github.com/moby/go-archive"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// BuildOptions are the optional settings of an image build.
+type BuildOptions struct {
+	// Dockerfile is a path relative to the context or absolute, and must
+	// be inside the context. Defaults to "Dockerfile".
+	Dockerfile string
+	// BuildArgs maps names to values; a nil value takes the variable from
+	// the daemon's environment, like docker build --build-arg NAME.
+	BuildArgs map[string]*string
+	Target    string
+	NoCache   bool
+	// Out receives the build log; nil means stdout.
+	Out io.Writer
+}
+
+// ParseBuildArgs turns K=V flags into build args. A bare K takes its value
+// from the host environment and is left out when unset.
+func ParseBuildArgs(flags []string) (map[string]*string, error) {
+	args := map[string]*string{}
+	for _, f := range flags {
+		k, v, ok := strings.Cut(f, "=")
+		if k == "" {
+			return nil, fmt.Errorf("invalid build arg '%s' (expected KEY=VALUE)", f)
+		}
+		if !ok {
+			env, set := os.LookupEnv(k)
+			if !set {
+				continue
+			}
+			v = env
+		}
+		args[k] = &v
+	}
+	return args, nil
+}
+
+// ReadDockerignore returns the exclude patterns of dir/.dockerignore, or
+// none when the file does not exist. The Dockerfile and .dockerignore are
+// always kept so the daemon can read them.
+func ReadDockerignore(dir, dockerfile string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	patterns, err := ignorefile.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf(".dockerignore: %v", err)
+	}
+	if len(patterns) > 0 {
+		patterns = append(patterns, "!"+filepath.ToSlash(dockerfile), "!.dockerignore")
+	}
+	return patterns, nil
+}
+
+// ContextHash digests the path, mode and content of every file a build
+// would send. Timestamps are left out, so an unchanged tree hashes the same.
+func ContextHash(dir string, excludes []string) (string, error) {
+	pm, err := patternmatcher.New(excludes)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		skip, err := pm.MatchesOrParentMatches(rel)
+		if err != nil {
+			return err
+		}
+		if skip {
+			if d.IsDir() && !pm.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %o\n", rel, info.Mode())
+		switch {
+		case info.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(h, f)
+			return err
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			fmt.Fprintln(h, target)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BuildImage builds the Dockerfile at the root of path as tag.
+func (e *Dockerengine) BuildImage(ctx context.Context, path, tag string) error {
+	return e.BuildImageWith(ctx, path, tag, BuildOptions{})
+}
+
+// BuildImageWith builds an image from the context directory path,
+// honouring .dockerignore. The image is labelled with the absolute source
+// path, the context hash, the Dockerfile and the target, so it can be
+// rebuilt later. An error in the build stream fails the build.
+func (e *Dockerengine) BuildImageWith(ctx context.Context, path, tag string, opts BuildOptions) error {
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	contextDir, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	dockerfile, err := relDockerfile(contextDir, opts.Dockerfile)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, dockerfile)); err != nil {
+		return fmt.Errorf("dockerfile: %v", err)
+	}
+
+	excludes, err := ReadDockerignore(contextDir, dockerfile)
+	if err != nil {
+		return err
+	}
+	hash, err := ContextHash(contextDir, excludes)
+	if err != nil {
+		return fmt.Errorf("hashing build context: %v", err)
+	}
+
+	fmt.Fprintf(out, "🛠️  Building custom image: %s\n", tag)
+	tar, err := archive.TarWithOptions(contextDir, &archive.TarOptions{ExcludePatterns: excludes})
+	if err != nil {
+		return err
+	}
+
+	res, err := e.Client.ImageBuild(ctx, tar, types.ImageBuildOptions{
+		Dockerfile: filepath.ToSlash(dockerfile),
+		Tags:       []string{tag},
+		Remove:     true,
+		BuildArgs:  opts.BuildArgs,
+		Target:     opts.Target,
+		NoCache:    opts.NoCache,
+		Labels: map[string]string{
+			"com.sbhub.managed":      "true",
+			"com.sbhub.source":       contextDir,
+			"com.sbhub.context-hash": hash,
+			"com.sbhub.dockerfile":   filepath.ToSlash(dockerfile),
+			"com.sbhub.target":       opts.Target,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return ReadBuildStream(res.Body, out)
+}
+
+// relDockerfile returns the Dockerfile path relative to the context.
+func relDockerfile(contextDir, dockerfile string) (string, error) {
+	if dockerfile == "" {
+		return "Dockerfile", nil
+	}
+	abs, err := filepath.Abs(dockerfile)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(contextDir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("dockerfile %s is outside the build context %s", dockerfile, contextDir)
+	}
+	return rel, nil
+}
+
+// ReadBuildStream copies the build log in the JSON stream to out and
+// returns the first error the daemon reported.
+func ReadBuildStream(r io.Reader, out io.Writer) error {
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading build output: %v", err)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if msg.ErrorMessage != "" {
+			return fmt.Errorf("%s", msg.ErrorMessage)
+		}
+		switch {
+		case msg.Stream != "":
+			fmt.Fprint(out, msg.Stream)
+		case msg.Status != "" && msg.ID == "":
+			fmt.Fprintln(out, msg.Status)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Resources is an amount of CPU, memory and disk.
+type Resources struct {
+	CPUs     float64 `json:"cpus"`
+	MemoryMB uint64  `json:"memory_mb"`
+	DiskGB   float64 `json:"disk_gb"`
+}
+
+// CapacityReport compares what managed sandboxes have committed with what
+// the host offers. Limit is the host capacity scaled by the overcommit
+// ratio; a zero DiskGB means the disk size is unknown and not enforced.
+type CapacityReport struct {
+	Host       Resources `json:"host"`
+	Committed  Resources `json:"committed"`
+	Overcommit float64   `json:"overcommit"`
+	Sandboxes  int       `json:"sandboxes"`
+}
+
+// Limit is the most that may be committed under the overcommit ratio.
+func (r CapacityReport) Limit() Resources {
+	ratio := r.Overcommit
+	if ratio <= 0 {
+		ratio = 1
+	}
+	return Resources{
+		CPUs:     r.Host.CPUs * ratio,
+		MemoryMB: uint64(float64(r.Host.MemoryMB) * ratio),
+		DiskGB:   r.Host.DiskGB * ratio,
+	}
+}
+
+// Headroom is what is left to commit before hitting the limit. Negative
+// values mean the host is already over its limit.
+func (r CapacityReport) Headroom() (cpus float64, memoryMB int64, diskGB float64) {
+	limit := r.Limit()
+	return limit.CPUs - r.Committed.CPUs, int64(limit.MemoryMB) - int64(r.Committed.MemoryMB), limit.DiskGB - r.Committed.DiskGB
+}
+
+// Admit returns an error naming the first resource that a sandbox of size
+// would push over the limit.
+func (r CapacityReport) Admit(size string) error {
+	spec, ok := SandboxSpecs[size]
+	if !ok {
+		return fmt.Errorf("unknown size preset '%s'", size)
+	}
+	limit := r.Limit()
+	if r.Committed.CPUs+spec.CPUCores > limit.CPUs {
+		return fmt.Errorf("not enough CPU: %.1f of %.1f cores committed, %s needs %.1f", r.Committed.CPUs, limit.CPUs, size, spec.CPUCores)
+	}
+	if r.Committed.MemoryMB+spec.MemoryMB > limit.MemoryMB {
+		return fmt.Errorf("not enough memory: %d of %d MB committed, %s needs %d MB", r.Committed.MemoryMB, limit.MemoryMB, size, spec.MemoryMB)
+	}
+	if limit.DiskGB > 0 && r.Committed.DiskGB+float64(spec.DiskGB) > limit.DiskGB {
+		return fmt.Errorf("not enough disk: %.0f of %.0f GB committed, %s needs %d GB", r.Committed.DiskGB, limit.DiskGB, size, spec.DiskGB)
+	}
+	return nil
+}
+
+// CapacityPolicy controls admission. DiskPath is the filesystem sandbox
+// data lives on; leave it empty to skip disk accounting.
+type CapacityPolicy struct {
+	Overcommit float64
+	DiskPath   string
+}
+
+// HostCapacity reports the CPUs and memory of the Docker host, and the size
+// of the filesystem at diskPath when given.
+func (e *Dockerengine) HostCapacity(ctx context.Context, diskPath string) (Resources, error) {
+	info, err := e.Client.Info(ctx)
+	if err != nil {
+		return Resources{}, err
+	}
+	host := Resources{CPUs: float64(info.NCPU), MemoryMB: uint64(info.MemTotal) / (1024 * 1024)}
+	if diskPath != "" {
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(diskPath, &fs); err == nil {
+			host.DiskGB = float64(fs.Blocks) * float64(fs.Bsize) / (1 << 30)
+		}
+	}
+	return host, nil
+}
+
+// CommittedResources sums the preset limits of managed sandboxes except
+// exclude. CPU and memory count running sandboxes only; disk counts every
+// sandbox since stopped ones keep their data.
+func (e *Dockerengine) CommittedResources(ctx context.Context, exclude string) (Resources, int, error) {
+	active, err := e.GetActiveSandboxes(ctx)
+	if err != nil {
+		return Resources{}, 0, err
+	}
+	var total Resources
+	count := 0
+	for name, c := range active {
+		if name == exclude || c.Labels["com.sbhub.managed"] != "true" {
+			continue
+		}
+		spec, ok := SandboxSpecs[e.EffectiveLabels(name, c.Labels)["com.sbhub.size"]]
+		if !ok {
+			continue
+		}
+		count++
+		total.DiskGB += float64(spec.DiskGB)
+		if c.State == "running" {
+			total.CPUs += spec.CPUCores
+			total.MemoryMB += spec.MemoryMB
+		}
+	}
+	return total, count, nil
+}
+
+// Capacity builds a report of the host under policy, leaving sandbox
+// exclude out of the committed totals (for resizing it).
+func (e *Dockerengine) Capacity(ctx context.Context, exclude string, policy CapacityPolicy) (CapacityReport, error) {
+	host, err := e.HostCapacity(ctx, policy.DiskPath)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	committed, count, err := e.CommittedResources(ctx, exclude)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	overcommit := policy.Overcommit
+	if overcommit <= 0 {
+		overcommit = 1
+	}
+	return CapacityReport{Host: host, Committed: committed, Overcommit: overcommit, Sandboxes: count}, nil
+}
+
+// CheckCapacity returns an error when giving sandbox name the resources of
+// size would commit more than the host allows under policy.
+func (e *Dockerengine) CheckCapacity(ctx context.Context, name, size string, policy CapacityPolicy) error {
+	report, err := e.Capacity(ctx, name, policy)
+	if err != nil {
+		return err
+	}
+	return report.Admit(size)
+}
+
+// ResizeSandbox changes the CPU and memory limits of a sandbox in place and
+// records the new size in the metadata store. A non-zero ttl resets the
+// expiry to that long from now; callers clamp it to the owner's policy.
+func (e *Dockerengine) ResizeSandbox(ctx context.Context, name, size string, ttl time.Duration) error {
+	spec, ok := SandboxSpecs[size]
+	if !ok {
+		return fmt.Errorf("unknown size preset '%s'", size)
+	}
+	if e.Meta == nil {
+		return fmt.Errorf("no metadata store to record the new size")
+	}
+
+	memory := int64(spec.MemoryMB * 1024 * 1024)
+	_, err := e.Client.ContainerUpdate(ctx, name, container.UpdateConfig{
+		Resources: container.Resources{
+			NanoCPUs: int64(spec.CPUCores * 1e9),
+			Memory:   memory,
+			// Keep Docker's default of swap equal to memory
+			MemorySwap: 2 * memory,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{"com.sbhub.size": size}
+	if ttl > 0 {
+		labels["com.sbhub.expires"] = time.Now().Add(ttl).Format(time.RFC3339)
+	}
+	return e.Meta.SetLabels(name, labels)
+}
@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user defaults read from ~/.sbhub/config.yaml. Every field is
+// optional; a missing file yields the zero Config.
+type Config struct {
+	// Owner is stamped on new sandboxes; defaults to the OS user.
+	Owner    string         `yaml:"owner"`
+	Console  ConsoleConfig  `yaml:"console"`
+	Capacity CapacityConfig `yaml:"capacity"`
+	// Owners maps an owner to its quotas and TTL policy. The "default"
+	// entry applies to everyone and is overridden field by field.
+	Owners map[string]OwnerPolicy `yaml:"owners"`
+	// Presets holds extra settings per size preset, keyed by preset name.
+	Presets map[string]PresetConfig `yaml:"presets"`
+	// Hooks run for every sandbox; preset hooks run after these.
+	Hooks  HookConfig   `yaml:"hooks"`
+	Images ImagesConfig `yaml:"images"`
+	// Registries maps a registry host to its login. These take precedence
+	// over ~/.docker/config.json.
+	Registries map[string]RegistryConfig `yaml:"registries"`
+	// Contexts names the Docker endpoints sandboxes can run on. Context
+	// is the one commands use without --context.
+	Contexts map[string]Endpoint `yaml:"contexts"`
+	Context  string              `yaml:"context"`
+}
+
+// Credentials resolves registry logins from the config and the Docker
+// config.
+func (c *Config) Credentials() *Credentials {
+	return &Credentials{Registries: c.Registries}
+}
+
+type ImagesConfig struct {
+	// Pull is the default pull policy: always, missing (default) or never.
+	Pull string `yaml:"pull"`
+	// PullTimeout bounds each pull. Defaults to 10m.
+	PullTimeout time.Duration    `yaml:"pull_timeout"`
+	Prune       ImagePruneConfig `yaml:"prune"`
+}
+
+type ImagePruneConfig struct {
+	// Auto makes the janitor prune unused sb-hub images.
+	Auto bool `yaml:"auto"`
+	// Interval is the time between janitor prunes. Defaults to 24h.
+	Interval time.Duration `yaml:"interval"`
+	// OlderThan keeps images younger than this.
+	OlderThan time.Duration `yaml:"older_than"`
+	// DanglingOnly keeps images that still have a tag.
+	DanglingOnly bool `yaml:"dangling_only"`
+}
+
+// DefaultPruneInterval applies when images.prune.interval is unset.
+const DefaultPruneInterval = 24 * time.Hour
+
+type PresetConfig struct {
+	// Init lists host scripts run inside every new sandbox of the preset,
+	// before any --init scripts.
+	Init  []string   `yaml:"init"`
+	Hooks HookConfig `yaml:"hooks"`
+}
+
+type ConsoleConfig struct {
+	// Record turns on session recording for every console without --record.
+	Record bool `yaml:"record"`
+}
+
+type CapacityConfig struct {
+	// Overcommit scales host capacity before admission; 1.5 lets sandboxes
+	// reserve 150% of the host. Defaults to 1.
+	Overcommit float64 `yaml:"overcommit"`
+	// QueueTimeout makes create wait this long for capacity instead of
+	// failing straight away.
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+}
+
+// DefaultConfigPath returns $SBHUB_CONFIG or ~/.sbhub/config.yaml.
+func DefaultConfigPath() string {
+	if p := os.Getenv("SBHUB_CONFIG"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".sbhub", "config.yaml")
+}
+
+// expandHome replaces a leading ~/ in p with the user's home directory.
+func expandHome(p string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return p
+}
+
+// LoadConfig reads the config file at path.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	if _, err := ParsePullPolicy(cfg.Images.Pull); err != nil {
+		return nil, fmt.Errorf("images.pull: %v", err)
+	}
+	for name, ep := range cfg.Contexts {
+		if err := ep.Validate(); err != nil {
+			return nil, fmt.Errorf("context %s: %v", name, err)
+		}
+	}
+	if cfg.Context != "" {
+		if _, err := cfg.Endpoint(cfg.Context); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Hooks.Validate(); err != nil {
+		return nil, err
+	}
+	for name, preset := range cfg.Presets {
+		if err := preset.Hooks.Validate(); err != nil {
+			return nil, fmt.Errorf("preset %s: %v", name, err)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,276 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// DefaultContext is the endpoint taken from the DOCKER_* environment, as
+// docker itself does without a context.
+const DefaultContext = "default"
+
+// Endpoint is a Docker daemon sb-hub can manage sandboxes on. Host is a
+// unix://, tcp:// or ssh:// address; an empty Host means the environment,
+// or the local Podman socket when Runtime is podman.
+type Endpoint struct {
+	Host    string     `yaml:"host"`
+	TLS     *TLSConfig `yaml:"tls"`
+	Runtime string     `yaml:"runtime"`
+}
+
+// TLSConfig holds the client certificate files of a tcp:// endpoint.
+type TLSConfig struct {
+	CA   string `yaml:"ca"`
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// Validate checks the runtime, the host scheme and that TLS is only set
+// for tcp.
+func (ep Endpoint) Validate() error {
+	switch ep.Runtime {
+	case "", RuntimeDocker, RuntimePodman, RuntimeFake:
+	default:
+		return fmt.Errorf("unknown runtime '%s' (expected docker, podman or fake)", ep.Runtime)
+	}
+	if ep.Host == "" {
+		return nil
+	}
+	u, err := url.Parse(ep.Host)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "unix", "tcp", "ssh":
+	default:
+		return fmt.Errorf("unsupported host '%s' (expected unix://, tcp:// or ssh://)", ep.Host)
+	}
+	if ep.TLS != nil && u.Scheme != "tcp" {
+		return fmt.Errorf("tls is only supported for tcp:// hosts")
+	}
+	return nil
+}
+
+// Local reports whether the endpoint's daemon runs on this machine, so
+// its published ports answer on localhost.
+func (ep Endpoint) Local() bool {
+	host := ep.Host
+	if host == "" && ep.Runtime != RuntimePodman {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	return host == "" || strings.HasPrefix(host, "unix://")
+}
+
+// PublishedHost is the host name a sandbox's published ports answer on:
+// localhost for local endpoints, otherwise the endpoint's host.
+func (ep Endpoint) PublishedHost() string {
+	host := ep.Host
+	if host == "" && ep.Runtime != RuntimePodman {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if ep.Local() {
+		return "localhost"
+	}
+	if u, err := url.Parse(host); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "localhost"
+}
+
+// ContextNames returns the configured endpoints plus DefaultContext,
+// sorted.
+func (c *Config) ContextNames() []string {
+	names := []string{}
+	if _, ok := c.Contexts[DefaultContext]; !ok {
+		names = append(names, DefaultContext)
+	}
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Endpoint returns the endpoint of a context. DefaultContext resolves to
+// the environment unless the config redefines it.
+func (c *Config) Endpoint(name string) (Endpoint, error) {
+	if ep, ok := c.Contexts[name]; ok {
+		return ep, nil
+	}
+	if name == DefaultContext {
+		return Endpoint{}, nil
+	}
+	return Endpoint{}, fmt.Errorf("unknown context '%s' (configured: %s)", name, strings.Join(c.ContextNames(), ", "))
+}
+
+// NewDockerClient connects to an endpoint. ssh:// endpoints run
+// "docker system dial-stdio" on the remote host, like docker does. Podman
+// endpoints are reached through its Docker-compatible API.
+func NewDockerClient(ep Endpoint) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if ep.Host == "" && ep.Runtime == RuntimePodman {
+		ep.Host = PodmanSocket()
+	}
+	if ep.Host != "" {
+		u, err := url.Parse(ep.Host)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "ssh" {
+			opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(sshDialer(u)))
+		} else {
+			opts = append(opts, client.WithHost(ep.Host))
+		}
+		if ep.TLS != nil {
+			opts = append(opts, client.WithTLSClientConfig(expandHome(ep.TLS.CA), expandHome(ep.TLS.Cert), expandHome(ep.TLS.Key)))
+		}
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// SSHCommand returns the ssh arguments that reach the Docker API of the
+// host in u, an ssh://[user@]host[:port] URL.
+func SSHCommand(u *url.URL) []string {
+	args := []string{}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	return append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+}
+
+func sshDialer(u *url.URL) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c := exec.Command("ssh", SSHCommand(u)...)
+		stdin, err := c.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := c.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Start(); err != nil {
+			return nil, fmt.Errorf("ssh %s: %v", u.Host, err)
+		}
+		return &commandConn{cmd: c, stdin: stdin, stdout: stdout, host: u.Host}, nil
+	}
+}
+
+// commandConn is a net.Conn over the stdin and stdout of a process.
+// Deadlines are not supported; the HTTP client relies on contexts.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	host   string
+}
+
+func (c *commandConn) Read(b []byte) (int, error)  { return c.stdout.Read(b) }
+func (c *commandConn) Write(b []byte) (int, error) { return c.stdin.Write(b) }
+
+func (c *commandConn) Close() error {
+	c.stdin.Close()
+	c.cmd.Process.Kill()
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return commandAddr("sb-hub") }
+func (c *commandConn) RemoteAddr() net.Addr               { return commandAddr(c.host) }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
+
+// Placement is the capacity of one endpoint considered for a new sandbox.
+type Placement struct {
+	Context string
+	Report  CapacityReport
+	// Err is why the endpoint cannot take the sandbox, if it cannot.
+	Err error
+}
+
+// PlaceSandbox picks the endpoint with the most free memory, then CPU,
+// among those that admit a sandbox of size. Unreachable endpoints are
+// skipped. Every candidate is returned so the choice can be explained.
+func PlaceSandbox(ctx context.Context, engines map[string]*Dockerengine, size string, policy CapacityPolicy) (string, []Placement, error) {
+	var placements []Placement
+	for name, engine := range engines {
+		p := Placement{Context: name}
+		p.Report, p.Err = engine.Capacity(ctx, "", policy)
+		if p.Err == nil {
+			p.Err = p.Report.Admit(size)
+		}
+		placements = append(placements, p)
+	}
+	sort.Slice(placements, func(i, j int) bool {
+		ci, mi, _ := placements[i].Report.Headroom()
+		cj, mj, _ := placements[j].Report.Headroom()
+		if mi != mj {
+			return mi > mj
+		}
+		if ci != cj {
+			return ci > cj
+		}
+		return placements[i].Context < placements[j].Context
+	})
+
+	var reasons []string
+	for _, p := range placements {
+		if p.Err == nil {
+			return p.Context, placements, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %v", p.Context, p.Err))
+	}
+	return "", placements, fmt.Errorf("no endpoint can take a %s sandbox (%s)", size, strings.Join(reasons, "; "))
+}
+
+// TotalOwnerUsage sums OwnerUsage across endpoints, so quotas hold
+// wherever sandboxes were placed. Endpoints that cannot be listed are
+// returned instead of failing the whole check.
+func TotalOwnerUsage(ctx context.Context, engines map[string]*Dockerengine, owner, exclude string) (OwnerUsage, []string) {
+	var total OwnerUsage
+	var unreachable []string
+	for name, engine := range engines {
+		usage, err := engine.OwnerUsage(ctx, owner, exclude)
+		if err != nil {
+			unreachable = append(unreachable, name)
+			continue
+		}
+		total.Sandboxes += usage.Sandboxes
+		total.CPUs += usage.CPUs
+		total.MemoryMB += usage.MemoryMB
+	}
+	sort.Strings(unreachable)
+	return total, unreachable
+}
+
+// SandboxContexts returns the endpoints that have a container called name,
+// sorted. Unreachable endpoints are skipped.
+func SandboxContexts(ctx context.Context, engines map[string]*Dockerengine, name string) []string {
+	var found []string
+	for ctxName, engine := range engines {
+		if exists, err := engine.ContainerExists(ctx, name); err == nil && exists {
+			found = append(found, ctxName)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
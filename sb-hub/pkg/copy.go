@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CopyRef is one side of an sb cp: a path inside a sandbox when Sandbox is
+// set, otherwise a host path.
+type CopyRef struct {
+	Sandbox string
+	Path    string
+}
+
+// ParseCopyRef parses "<name>:/path" as a sandbox path. Anything else,
+// including paths that merely contain a colon after a slash, is a host path.
+func ParseCopyRef(arg string) CopyRef {
+	name, p, ok := strings.Cut(arg, ":")
+	if !ok || name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return CopyRef{Path: arg}
+	}
+	return CopyRef{Sandbox: name, Path: p}
+}
+
+type CopyOptions struct {
+	// FollowLink copies what a symlink source points to instead of the link.
+	FollowLink bool
+	// PreserveOwner keeps uid/gid when copying into a sandbox.
+	PreserveOwner bool
+	// Progress receives a progress line for slow transfers; nil disables it.
+	Progress io.Writer
+}
+
+// CopyIn copies a host file or directory into a sandbox. Like docker cp, if
+// the destination is an existing directory the source is copied into it,
+// otherwise it is copied to the destination path itself.
+func (e *Dockerengine) CopyIn(ctx context.Context, src string, dst CopyRef, opts CopyOptions) error {
+	if opts.FollowLink {
+		resolved, err := filepath.EvalSymlinks(src)
+		if err != nil {
+			return err
+		}
+		src = resolved
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return err
+	}
+
+	dstPath := dst.Path
+	if !path.IsAbs(dstPath) {
+		return fmt.Errorf("sandbox path '%s' must be absolute", dstPath)
+	}
+	if st, err := e.StatSandboxPath(ctx, dst.Sandbox, dstPath); (err == nil && st.Mode.IsDir()) || strings.HasSuffix(dstPath, "/") {
+		dstPath = path.Join(dstPath, filepath.Base(src))
+	}
+
+	progress := &ProgressReader{
+		R:     TarTree(src, dstPath, nil, nil),
+		Out:   opts.Progress,
+		Label: "Copying",
+		Total: TreeSize(src),
+	}
+	defer progress.Done()
+	return e.CopyToSandbox(ctx, dst.Sandbox, "/", progress, opts.PreserveOwner)
+}
+
+// CopyOut copies a file or directory from a sandbox to the host, with the
+// same destination rules as CopyIn.
+func (e *Dockerengine) CopyOut(ctx context.Context, src CopyRef, dst string, opts CopyOptions) error {
+	content, stat, err := e.CopyFromSandbox(ctx, src.Sandbox, src.Path)
+	if err != nil {
+		return err
+	}
+	if opts.FollowLink && stat.Mode&os.ModeSymlink != 0 && stat.LinkTarget != "" {
+		content.Close()
+		linkName := stat.Name
+		content, stat, err = e.CopyFromSandbox(ctx, src.Sandbox, stat.LinkTarget)
+		if err != nil {
+			return err
+		}
+		stat.Name = linkName
+	}
+	defer content.Close()
+
+	target := dst
+	if fi, err := os.Stat(dst); (err == nil && fi.IsDir()) || strings.HasSuffix(dst, string(os.PathSeparator)) {
+		target = filepath.Join(dst, stat.Name)
+	}
+
+	progress := &ProgressReader{R: content, Out: opts.Progress, Label: "Copying"}
+	if stat.Mode.IsRegular() {
+		progress.Total = stat.Size
+	}
+	defer progress.Done()
+	return ExtractTar(progress, target, nil)
+}
+
+// TreeSize sums the size of regular files under root. It is used as the
+// progress total, so errors are ignored.
+func TreeSize(root string) int64 {
+	var total int64
+	filepath.Walk(root, func(_ string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			total += fi.Size()
+		}
+		return nil
+	})
+	return total
+}
@@ -10,11 +10,15 @@ import (
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
-	archive "github.com/moby/go-archive"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
@@ -29,13 +33,38 @@ type DockerClient interface {
 	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
 	ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
 	ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+	ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error)
 	ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
+	ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error)
+	ImageLoad(ctx context.Context, input io.Reader, loadOpts ...client.ImageLoadOption) (image.LoadResponse, error)
+	ImageSave(ctx context.Context, imageIDs []string, saveOpts ...client.ImageSaveOption) (io.ReadCloser, error)
+	ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error)
+	BuildCachePrune(ctx context.Context, opts build.CachePruneOptions) (*build.CachePruneReport, error)
 	NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
 	NetworkCreate(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error)
+	VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+	ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error)
+	ContainerExecStart(ctx context.Context, execID string, config container.ExecStartOptions) error
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error)
+	ContainerStatPath(ctx context.Context, containerID, path string) (container.PathStat, error)
+	ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error
+	ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error)
+	ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.UpdateResponse, error)
+	Info(ctx context.Context) (system.Info, error)
+	RegistryLogin(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error)
 }
 
 type Dockerengine struct {
 	Client DockerClient
+	// Meta, when set, holds label updates made after creation
+	Meta *MetaStore
+	// Relabel adds the SELinux "z" option to host binds of the sandboxes
+	// it creates, as PodmanRuntime does (see Endpoint.Relabels)
+	Relabel bool
 }
 
 func (e *Dockerengine) Ping(ctx context.Context) error {
@@ -57,22 +86,6 @@ func (e *Dockerengine) ContainerExists(ctx context.Context, name string) (bool,
 	return len(containers) > 0, nil
 }
 
-func (e *Dockerengine) EnsureImage(ctx context.Context, imageName string) error {
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-	defer cancel()
-
-	fmt.Printf("🔍 Checking for image '%s'...\n", imageName)
-
-	out, err := e.Client.ImagePull(ctx, imageName, image.PullOptions{})
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	io.Copy(os.Stdout, out)
-	return nil
-}
-
 func (e *Dockerengine) RemoveSandbox(ctx context.Context, name string, storagePath string, forceAll bool) error {
 	stopTimeout := 30
 	e.Client.ContainerStop(ctx, name, container.StopOptions{Timeout: &stopTimeout})
@@ -88,25 +101,81 @@ func (e *Dockerengine) RemoveSandbox(ctx context.Context, name string, storagePa
 }
 
 func (e *Dockerengine) CreateSandbox(ctx context.Context, name string, ttl time.Duration, size string, config *container.Config, hostConfig *container.HostConfig) (string, error) {
-	expiry := time.Now().Add(ttl).Format(time.RFC3339)
-	if config.Labels == nil {
-		config.Labels = make(map[string]string)
-	}
-	config.Labels["com.sbhub.managed"] = "true"
-	config.Labels["com.sbhub.expires"] = expiry
-	config.Labels["com.sbhub.size"] = size
+	config.Labels = SandboxLabels(config.Labels, ttl, size)
 	config.Tty = true
 	config.OpenStdin = true
+	if e.Relabel {
+		hostConfig.Binds = RelabelBinds(hostConfig.Binds)
+	}
 
 	resp, err := e.Client.ContainerCreate(ctx, config, hostConfig, nil, nil, name)
 	if err != nil {
 		return "", err
 	}
+	// The new labels are authoritative again
+	if e.Meta != nil {
+		e.Meta.ClearLabels(name)
+	}
 
 	err = e.Client.ContainerStart(ctx, resp.ID, container.StartOptions{})
 	return resp.ID, err
 }
 
+// SandboxLabels stamps labels with the marks every sandbox carries: managed,
+// its expiry and its size preset.
+func SandboxLabels(labels map[string]string, ttl time.Duration, size string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels["com.sbhub.managed"] = "true"
+	labels["com.sbhub.expires"] = time.Now().Add(ttl).Format(time.RFC3339)
+	labels["com.sbhub.size"] = size
+	return labels
+}
+
+// StartSandbox starts a stopped sandbox and waits until it is running and,
+// if the image defines a healthcheck, healthy.
+func (e *Dockerengine) StartSandbox(ctx context.Context, name string, timeout time.Duration) error {
+	if err := e.Client.ContainerStart(ctx, name, container.StartOptions{}); err != nil {
+		return err
+	}
+	return e.WaitReady(ctx, name, timeout)
+}
+
+// WaitReady polls the sandbox until it is running and not failing its
+// healthcheck, or until timeout.
+func (e *Dockerengine) WaitReady(ctx context.Context, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		inspect, err := e.Client.ContainerInspect(ctx, name)
+		if err != nil {
+			return err
+		}
+		state := inspect.State
+		if state == nil {
+			return fmt.Errorf("sandbox '%s' has no state", name)
+		}
+		if !state.Running && state.Status != "created" && state.Status != "restarting" {
+			return fmt.Errorf("sandbox '%s' is %s (exit code %d)", name, state.Status, state.ExitCode)
+		}
+		if state.Running {
+			if state.Health == nil || state.Health.Status == container.Healthy || state.Health.Status == container.NoHealthcheck {
+				return nil
+			}
+			if state.Health.Status == container.Unhealthy {
+				return fmt.Errorf("sandbox '%s' is unhealthy", name)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for sandbox '%s' to become ready", name)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
 func (e *Dockerengine) EnsureNetwork(ctx context.Context) error {
 	netName := "sb-hub-net"
 	_, err := e.Client.NetworkInspect(ctx, netName, network.InspectOptions{})
@@ -119,24 +188,18 @@ func (e *Dockerengine) EnsureNetwork(ctx context.Context) error {
 	return err
 }
 
-func (e *Dockerengine) BuildImage(ctx context.Context, path, tag string) error {
-	fmt.Printf("🛠️  Building custom image: %s\n", tag)
-	tar, err := archive.TarWithOptions(path, &archive.TarOptions{})
-	if err != nil {
-		return err
-	}
-	opts := types.ImageBuildOptions{
-		Dockerfile: "Dockerfile",
-		Tags:       []string{tag},
-		Remove:     true,
-	}
-	res, err := e.Client.ImageBuild(ctx, tar, opts)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	io.Copy(os.Stdout, res.Body)
-	return nil
+// EnsureVolume creates a named volume labelled as sb-hub managed. Creating
+// a volume that already exists is a no-op on the daemon side.
+func (e *Dockerengine) EnsureVolume(ctx context.Context, name string) error {
+	_, err := e.Client.VolumeCreate(ctx, volume.CreateOptions{
+		Name:   name,
+		Labels: map[string]string{"com.sbhub.managed": "true"},
+	})
+	return err
+}
+
+func (e *Dockerengine) RemoveVolume(ctx context.Context, name string) error {
+	return e.Client.VolumeRemove(ctx, name, false)
 }
 
 func (e *Dockerengine) GetUsedPorts(ctx context.Context) (map[string]bool, error) {
@@ -181,7 +244,11 @@ func (e *Dockerengine) GetExpiredSandboxes(ctx context.Context) ([]container.Sum
 	var expired []container.Summary
 	now := time.Now()
 	for _, c := range containers {
-		if expStr, ok := c.Labels["com.sbhub.expires"]; ok {
+		labels := c.Labels
+		if len(c.Names) > 0 {
+			labels = e.EffectiveLabels(filepath.Base(c.Names[0]), c.Labels)
+		}
+		if expStr, ok := labels["com.sbhub.expires"]; ok {
 			expiry, err := time.Parse(time.RFC3339, expStr)
 			if err == nil && now.After(expiry) {
 				expired = append(expired, c)
@@ -191,10 +258,42 @@ func (e *Dockerengine) GetExpiredSandboxes(ctx context.Context) ([]container.Sum
 	return expired, nil
 }
 
+// RemainingTTL returns the time left before the expiry label, or fallback
+// when the label is missing or already passed.
+func RemainingTTL(labels map[string]string, fallback time.Duration) time.Duration {
+	expiry, err := time.Parse(time.RFC3339, labels["com.sbhub.expires"])
+	if err != nil {
+		return fallback
+	}
+	if rem := time.Until(expiry); rem > 0 {
+		return rem
+	}
+	return fallback
+}
+
 func (e *Dockerengine) InspectSandbox(ctx context.Context, name string) (container.InspectResponse, error) {
 	return e.Client.ContainerInspect(ctx, name)
 }
 
+// CopyToSandbox extracts a tar stream into the sandbox at dstPath. With
+// preserveOwner the tar's uid/gid are kept instead of mapping to root.
+func (e *Dockerengine) CopyToSandbox(ctx context.Context, name, dstPath string, content io.Reader, preserveOwner bool) error {
+	return e.Client.CopyToContainer(ctx, name, dstPath, content, container.CopyToContainerOptions{
+		AllowOverwriteDirWithFile: true,
+		CopyUIDGID:                preserveOwner,
+	})
+}
+
+// StatSandboxPath describes a path inside the sandbox.
+func (e *Dockerengine) StatSandboxPath(ctx context.Context, name, p string) (container.PathStat, error) {
+	return e.Client.ContainerStatPath(ctx, name, p)
+}
+
+// CopyFromSandbox returns srcPath from the sandbox as a tar stream.
+func (e *Dockerengine) CopyFromSandbox(ctx context.Context, name, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	return e.Client.CopyFromContainer(ctx, name, srcPath)
+}
+
 func GenerateRandomName() string {
 	adjectives := []string{"swift", "brave", "cool", "mighty", "keen"}
 	nouns := []string{"whale", "ship", "anchor", "pilot", "wave"}
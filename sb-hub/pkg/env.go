@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseEnvFile reads KEY=VAL lines from a dotenv-style file. Blank lines and
+// lines starting with '#' are skipped, and surrounding quotes are stripped.
+func ParseEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars []string
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, val, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VAL", path, lineNo)
+		}
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		vars = append(vars, fmt.Sprintf("%s=%s", strings.TrimSpace(key), val))
+	}
+	return vars, scanner.Err()
+}
+
+// BuildEnv merges variables from env files and KEY=VAL flags into a
+// container env list. Later entries override earlier ones with the same key,
+// so flags take precedence over files.
+func BuildEnv(envFiles []string, vars []string) ([]string, error) {
+	var all []string
+	for _, path := range envFiles {
+		fileVars, err := ParseEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, fileVars...)
+	}
+	for _, v := range vars {
+		if !strings.Contains(v, "=") {
+			// Bare KEY passes through the value from the host, like docker run.
+			val, ok := os.LookupEnv(v)
+			if !ok {
+				continue
+			}
+			v = fmt.Sprintf("%s=%s", v, val)
+		}
+		all = append(all, v)
+	}
+
+	index := make(map[string]int)
+	var env []string
+	for _, v := range all {
+		key, _, _ := strings.Cut(v, "=")
+		if i, ok := index[key]; ok {
+			env[i] = v
+			continue
+		}
+		index[key] = len(env)
+		env = append(env, v)
+	}
+	return env, nil
+}
@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// TermSize is a terminal size in character cells.
+type TermSize struct {
+	Height uint
+	Width  uint
+}
+
+// ExecRequest describes a process to run inside a sandbox and the streams
+// wired to it.
+type ExecRequest struct {
+	Cmd     []string
+	User    string
+	WorkDir string
+	Env     []string
+	Tty     bool
+
+	// Stdin is forwarded to the process when non-nil.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Resize delivers terminal size changes for TTY sessions. The first
+	// value should be the current size.
+	Resize <-chan TermSize
+}
+
+// Exec runs a process inside the sandbox through the Docker exec API,
+// streams its output, and returns its exit code once it finishes.
+func (e *Dockerengine) Exec(ctx context.Context, name string, req ExecRequest) (int, error) {
+	created, err := e.Client.ContainerExecCreate(ctx, name, container.ExecOptions{
+		Cmd:          req.Cmd,
+		User:         req.User,
+		WorkingDir:   req.WorkDir,
+		Env:          req.Env,
+		Tty:          req.Tty,
+		AttachStdin:  req.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := e.Client.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{Tty: req.Tty})
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Close()
+
+	if req.Resize != nil {
+		go func() {
+			for size := range req.Resize {
+				e.Client.ContainerExecResize(ctx, created.ID, container.ResizeOptions{Height: size.Height, Width: size.Width})
+			}
+		}()
+	}
+
+	if req.Stdin != nil {
+		go func() {
+			io.Copy(resp.Conn, req.Stdin)
+			resp.CloseWrite()
+		}()
+	}
+
+	stdout, stderr := req.Stdout, req.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	outputDone := make(chan error, 1)
+	go func() {
+		var err error
+		if req.Tty {
+			_, err = io.Copy(stdout, resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(stdout, stderr, resp.Reader)
+		}
+		outputDone <- err
+	}()
+
+	select {
+	case err := <-outputDone:
+		if err != nil {
+			return -1, err
+		}
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+	return e.waitExec(ctx, created.ID)
+}
+
+// RunInSandbox runs cmd inside the sandbox without a TTY and waits for it
+// to exit. A non-zero exit code is returned as an error.
+func (e *Dockerengine) RunInSandbox(ctx context.Context, name string, cmd []string) error {
+	exec, err := e.Client.ContainerExecCreate(ctx, name, container.ExecOptions{Cmd: cmd})
+	if err != nil {
+		return err
+	}
+	if err := e.Client.ContainerExecStart(ctx, exec.ID, container.ExecStartOptions{Detach: true}); err != nil {
+		return err
+	}
+	code, err := e.waitExec(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("'%s' exited with code %d", cmd[0], code)
+	}
+	return nil
+}
+
+// waitExec polls an exec until it has exited and returns its exit code.
+func (e *Dockerengine) waitExec(ctx context.Context, execID string) (int, error) {
+	for {
+		inspect, err := e.Client.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return -1, err
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// DetectShell picks the nicest interactive shell available in the sandbox,
+// falling back to /bin/sh.
+func (e *Dockerengine) DetectShell(ctx context.Context, name string) string {
+	for _, shell := range []string{"/bin/bash", "/usr/bin/bash", "/bin/zsh", "/usr/bin/zsh"} {
+		if _, err := e.Client.ContainerStatPath(ctx, name, shell); err == nil {
+			return shell
+		}
+	}
+	return "/bin/sh"
+}
@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeRuntime keeps sandboxes in memory. It needs no daemon, so it serves
+// tests of code written against Runtime and contexts with runtime: fake;
+// Calls records every change it was asked to make.
+type FakeRuntime struct {
+	// ExecFn runs exec requests; without it every command exits 0.
+	ExecFn func(name string, req ExecRequest) (int, error)
+	// Calls lists the changes made, such as "create box" or "stop box".
+	Calls []string
+
+	mu        sync.Mutex
+	sandboxes map[string]*fakeSandbox
+}
+
+type fakeSandbox struct {
+	info SandboxInfo
+	logs []LogLine
+}
+
+// NewFakeRuntime returns an empty in-memory runtime.
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{sandboxes: map[string]*fakeSandbox{}}
+}
+
+func (f *FakeRuntime) Name() string { return RuntimeFake }
+
+func (f *FakeRuntime) Create(ctx context.Context, cfg SandboxConfig) (string, error) {
+	if _, ok := SandboxSpecs[cfg.Size]; !ok {
+		return "", fmt.Errorf("unknown size preset '%s'", cfg.Size)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.sandboxes[cfg.Name]; ok {
+		return "", fmt.Errorf("sandbox '%s' already exists", cfg.Name)
+	}
+
+	labels := make(map[string]string, len(cfg.Labels))
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	ports := make(map[string]string, len(cfg.Ports))
+	for k, v := range cfg.Ports {
+		ports[k] = v
+	}
+	id := make([]byte, 32)
+	rand.Read(id)
+	info := SandboxInfo{
+		ID:      hex.EncodeToString(id),
+		Name:    cfg.Name,
+		Image:   cfg.Image,
+		State:   "created",
+		Labels:  SandboxLabels(labels, cfg.TTL, cfg.Size),
+		Ports:   ports,
+		Created: time.Now(),
+	}
+	if cfg.HealthCmd != "" {
+		info.Health = "starting"
+	}
+	f.sandboxes[cfg.Name] = &fakeSandbox{info: info}
+	f.Calls = append(f.Calls, "create "+cfg.Name)
+	return info.ID, nil
+}
+
+func (f *FakeRuntime) Start(ctx context.Context, name string) error {
+	return f.update(name, "start", func(s *fakeSandbox) {
+		s.info.State = "running"
+		s.info.ExitCode = 0
+		s.info.StartedAt = time.Now()
+		if s.info.Health != "" {
+			s.info.Health = "healthy"
+		}
+	})
+}
+
+func (f *FakeRuntime) Stop(ctx context.Context, name string, timeout time.Duration) error {
+	return f.update(name, "stop", func(s *fakeSandbox) {
+		if s.info.Running() {
+			s.info.State = "exited"
+			s.info.ExitCode = 137
+		}
+	})
+}
+
+func (f *FakeRuntime) Remove(ctx context.Context, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.sandboxes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(f.sandboxes, name)
+	f.Calls = append(f.Calls, "remove "+name)
+	return nil
+}
+
+// List returns the sandboxes sorted by name.
+func (f *FakeRuntime) List(ctx context.Context) ([]SandboxInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	infos := make([]SandboxInfo, 0, len(f.sandboxes))
+	for _, s := range f.sandboxes {
+		infos = append(infos, s.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func (f *FakeRuntime) Inspect(ctx context.Context, name string) (SandboxInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sandboxes[name]
+	if !ok {
+		return SandboxInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return s.info, nil
+}
+
+// Exec refuses sandboxes that are not running, like the Docker API does.
+func (f *FakeRuntime) Exec(ctx context.Context, name string, req ExecRequest) (int, error) {
+	info, err := f.Inspect(ctx, name)
+	if err != nil {
+		return -1, err
+	}
+	if !info.Running() {
+		return -1, fmt.Errorf("sandbox '%s' is not running", name)
+	}
+	f.mu.Lock()
+	f.Calls = append(f.Calls, "exec "+name+" "+strings.Join(req.Cmd, " "))
+	f.mu.Unlock()
+	if f.ExecFn == nil {
+		return 0, nil
+	}
+	return f.ExecFn(name, req)
+}
+
+// Logs replays the lines added with WriteLog. Follow is ignored.
+func (f *FakeRuntime) Logs(ctx context.Context, name string, opts LogOptions, fn func(LogLine) error) error {
+	f.mu.Lock()
+	s, ok := f.sandboxes[name]
+	if !ok {
+		f.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	var lines []LogLine
+	for _, line := range s.logs {
+		if opts.Grep == nil || opts.Grep.MatchString(line.Text) {
+			lines = append(lines, line)
+		}
+	}
+	f.mu.Unlock()
+
+	if n, err := strconv.Atoi(opts.Tail); err == nil && n >= 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLog appends a line to the sandbox's log.
+func (f *FakeRuntime) WriteLog(name, stream, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sandboxes[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	s.logs = append(s.logs, LogLine{Sandbox: name, Stream: stream, Time: time.Now(), Text: text})
+	return nil
+}
+
+func (f *FakeRuntime) update(name, call string, fn func(*fakeSandbox)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sandboxes[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	fn(s)
+	f.Calls = append(f.Calls, call+" "+name)
+	return nil
+}
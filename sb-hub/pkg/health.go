@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// HealthCheck builds a Docker healthcheck that runs cmd with the image's
+// shell. It is probed every second while the sandbox starts, then every 5s.
+func HealthCheck(cmd string) *container.HealthConfig {
+	return &container.HealthConfig{
+		Test:          []string{"CMD-SHELL", cmd},
+		Interval:      5 * time.Second,
+		Timeout:       3 * time.Second,
+		StartPeriod:   30 * time.Second,
+		StartInterval: time.Second,
+		Retries:       3,
+	}
+}
+
+// ReadinessURL is the URL of path on a sandbox's mapped port, published on
+// host (see Endpoint.PublishedHost).
+func ReadinessURL(host, hostPort, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("http://%s:%s%s", host, hostPort, path)
+}
+
+// ProbeHTTP makes one GET request and treats any status below 400 as ready.
+func ProbeHTTP(ctx context.Context, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// WaitHealthy waits until the sandbox is running and healthy, like
+// WaitReady, and then until url (if set) answers. The sandbox exiting while
+// the URL is polled is reported straight away.
+func (e *Dockerengine) WaitHealthy(ctx context.Context, name, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := e.WaitReady(ctx, name, timeout); err != nil || url == "" {
+		return err
+	}
+	for {
+		probeErr := ProbeHTTP(ctx, url, 2*time.Second)
+		if probeErr == nil {
+			return nil
+		}
+		inspect, err := e.Client.ContainerInspect(ctx, name)
+		if err == nil && inspect.State != nil && !inspect.State.Running {
+			return fmt.Errorf("sandbox '%s' is %s (exit code %d)", name, inspect.State.Status, inspect.State.ExitCode)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s: %v", url, probeErr)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// DockerHealth returns the Docker healthcheck state in a sandbox's status
+// line, or "" when it has no healthcheck.
+func DockerHealth(c container.Summary) string {
+	switch {
+	case strings.Contains(c.Status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(c.Status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(c.Status, "(health: starting)"):
+		return "starting"
+	}
+	return ""
+}
+
+// HealthProbeURL returns the com.sbhub.health-http URL of a running
+// sandbox on the host its endpoint publishes ports on, or "" when it has
+// none.
+func HealthProbeURL(c container.Summary, host string) string {
+	path, ok := c.Labels["com.sbhub.health-http"]
+	if !ok || c.State != "running" {
+		return ""
+	}
+	return ReadinessURL(host, c.Labels["com.sbhub.hostport"], path)
+}
+
+// HealthStatus reports the health of a listed sandbox: the Docker
+// healthcheck state from its status line, or else a probe of its
+// com.sbhub.health-http path on host. Sandboxes with neither report "-".
+func HealthStatus(ctx context.Context, c container.Summary, host string) string {
+	if h := DockerHealth(c); h != "" {
+		return h
+	}
+	url := HealthProbeURL(c, host)
+	if url == "" {
+		return "-"
+	}
+	if err := ProbeHTTP(ctx, url, time.Second); err != nil {
+		return "unhealthy"
+	}
+	return "healthy"
+}
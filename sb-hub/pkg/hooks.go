@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// HookEvent names a point in a sandbox's lifecycle where hooks run.
+type HookEvent string
+
+const (
+	HookPreCreate  HookEvent = "pre-create"
+	HookPostCreate HookEvent = "post-create"
+	HookPreRemove  HookEvent = "pre-remove"
+	HookPostRemove HookEvent = "post-remove"
+	HookPreExpire  HookEvent = "pre-expire"
+	HookPostSave   HookEvent = "post-save"
+)
+
+// HookEvents lists every event hooks can be registered for.
+var HookEvents = []HookEvent{HookPreCreate, HookPostCreate, HookPreRemove, HookPostRemove, HookPreExpire, HookPostSave}
+
+// IsPre reports whether hooks of the event run before the operation and
+// can veto it.
+func (ev HookEvent) IsPre() bool {
+	return strings.HasPrefix(string(ev), "pre-")
+}
+
+// DefaultHookTimeout applies to hooks without a timeout of their own.
+const DefaultHookTimeout = 30 * time.Second
+
+// Hook is a host executable run on a lifecycle event. It receives the
+// HookPayload as JSON on stdin.
+type Hook struct {
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// HookConfig maps events to the hooks run for them, in order.
+type HookConfig map[HookEvent][]Hook
+
+// Validate rejects unknown events and hooks without a command.
+func (hc HookConfig) Validate() error {
+	for ev, hooks := range hc {
+		known := false
+		for _, k := range HookEvents {
+			known = known || ev == k
+		}
+		if !known {
+			return fmt.Errorf("unknown hook event '%s'", ev)
+		}
+		for _, h := range hooks {
+			if h.Command == "" {
+				return fmt.Errorf("%s hook has no command", ev)
+			}
+		}
+	}
+	return nil
+}
+
+// HooksFor returns the global hooks of event followed by those of the
+// size preset.
+func (c *Config) HooksFor(event HookEvent, size string) []Hook {
+	hooks := append([]Hook(nil), c.Hooks[event]...)
+	return append(hooks, c.Presets[size].Hooks[event]...)
+}
+
+// HookPayload describes the sandbox a hook runs for.
+type HookPayload struct {
+	Event       HookEvent         `json:"event"`
+	Name        string            `json:"name"`
+	Size        string            `json:"size,omitempty"`
+	Image       string            `json:"image,omitempty"`
+	Ports       map[string]string `json:"ports,omitempty"` // container port -> host port
+	Labels      map[string]string `json:"labels,omitempty"`
+	StoragePath string            `json:"storage_path,omitempty"`
+	Snapshot    string            `json:"snapshot,omitempty"`
+	Time        time.Time         `json:"time"`
+}
+
+// NewHookPayload builds the payload for a sandbox from its container
+// settings. Either config may be nil.
+func NewHookPayload(event HookEvent, name string, config *container.Config, hostConfig *container.HostConfig, storagePath string) HookPayload {
+	p := HookPayload{Event: event, Name: name, StoragePath: storagePath, Time: time.Now().UTC()}
+	if config != nil {
+		p.Image = config.Image
+		p.Labels = config.Labels
+		p.Size = config.Labels["com.sbhub.size"]
+	}
+	if hostConfig != nil && len(hostConfig.PortBindings) > 0 {
+		p.Ports = map[string]string{}
+		for port, bindings := range hostConfig.PortBindings {
+			if len(bindings) > 0 {
+				p.Ports[string(port)] = bindings[0].HostPort
+			}
+		}
+	}
+	return p
+}
+
+// As returns a copy of the payload for another event, such as the
+// post-remove that follows a pre-remove.
+func (p HookPayload) As(event HookEvent) HookPayload {
+	p.Event = event
+	p.Time = time.Now().UTC()
+	return p
+}
+
+// RunHook runs one hook with the payload on stdin and SBHUB_EVENT and
+// SBHUB_SANDBOX in its environment. A non-zero exit or timeout is an error
+// that carries the hook's output.
+func RunHook(ctx context.Context, h Hook, payload HookPayload) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	command := expandHome(h.Command)
+
+	var out bytes.Buffer
+	c := exec.CommandContext(ctx, command, h.Args...)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = &out
+	c.Stderr = &out
+	c.Env = append(os.Environ(), "SBHUB_EVENT="+string(payload.Event), "SBHUB_SANDBOX="+payload.Name)
+	c.WaitDelay = time.Second
+
+	err = c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s timed out after %s", filepath.Base(command), timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%s: %v: %s", filepath.Base(command), err, msg)
+		}
+		return fmt.Errorf("%s: %v", filepath.Base(command), err)
+	}
+	return nil
+}
+
+// RunHooks runs hooks in order. For pre-* events the first failure stops
+// the run and vetoes the operation; for the others every hook runs and
+// the failures are joined.
+func RunHooks(ctx context.Context, hooks []Hook, payload HookPayload) error {
+	var errs []error
+	for _, h := range hooks {
+		if err := RunHook(ctx, h, payload); err != nil {
+			if payload.Event.IsPre() {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// LoadedImage is an image the daemon reported loading from an archive.
+// Ref is a tag, or the image ID for an untagged image.
+type LoadedImage struct {
+	Ref    string
+	Tagged bool
+}
+
+// ReadLoadStream returns the images an image-load response reports. The
+// daemon answers in JSON messages or, on old API versions, plain text.
+func ReadLoadStream(r io.Reader, isJSON bool) ([]LoadedImage, error) {
+	var lines []string
+	if isJSON {
+		dec := json.NewDecoder(r)
+		for {
+			var msg jsonmessage.JSONMessage
+			if err := dec.Decode(&msg); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("reading load output: %v", err)
+			}
+			if msg.Error != nil {
+				return nil, msg.Error
+			}
+			if msg.ErrorMessage != "" {
+				return nil, fmt.Errorf("%s", msg.ErrorMessage)
+			}
+			lines = append(lines, strings.Split(msg.Stream, "\n")...)
+		}
+	} else {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var loaded []LoadedImage
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if ref, ok := strings.CutPrefix(line, "Loaded image ID: "); ok {
+			loaded = append(loaded, LoadedImage{Ref: ref})
+		} else if ref, ok := strings.CutPrefix(line, "Loaded image: "); ok {
+			loaded = append(loaded, LoadedImage{Ref: ref, Tagged: true})
+		}
+	}
+	if len(loaded) == 0 {
+		return nil, fmt.Errorf("the archive contained no images")
+	}
+	return loaded, nil
+}
+
+// LoadImageArchive loads a docker save or OCI layout archive, optionally
+// gzip, bzip2 or xz compressed, and records the images it contained.
+// progress, when set, shows how much of the file was sent.
+func (e *Dockerengine) LoadImageArchive(ctx context.Context, path string, progress io.Writer) ([]LoadedImage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var input io.Reader = f
+	var bar *ProgressReader
+	if progress != nil {
+		fi, _ := f.Stat()
+		bar = &ProgressReader{R: f, Out: progress, Label: "Loading", Total: fi.Size()}
+		input = bar
+	}
+
+	res, err := e.Client.ImageLoad(ctx, input)
+	if bar != nil {
+		bar.Done()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %v", path, err)
+	}
+	defer res.Body.Close()
+	loaded, err := ReadLoadStream(res.Body, res.JSON)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %v", path, err)
+	}
+
+	if e.Meta != nil {
+		for _, l := range loaded {
+			if img, err := e.Client.ImageInspect(ctx, l.Ref); err == nil {
+				e.Meta.RecordLoad(l.Ref, img.ID)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// PickLoadedImage chooses the image to run from an archive. want selects
+// one by reference when the archive holds several.
+func PickLoadedImage(loaded []LoadedImage, want string) (string, error) {
+	if want != "" {
+		for _, l := range loaded {
+			if l.Ref == want || l.Ref == want+":latest" {
+				return l.Ref, nil
+			}
+		}
+		return "", fmt.Errorf("the archive does not contain %s", want)
+	}
+	if len(loaded) > 1 {
+		refs := make([]string, len(loaded))
+		for i, l := range loaded {
+			refs[i] = l.Ref
+		}
+		return "", fmt.Errorf("the archive contains %d images (%s); choose one with --image", len(loaded), strings.Join(refs, ", "))
+	}
+	return loaded[0].Ref, nil
+}
+
+// SaveImages writes refs as a docker save archive to w.
+func (e *Dockerengine) SaveImages(ctx context.Context, refs []string, w io.Writer) (int64, error) {
+	rc, err := e.Client.ImageSave(ctx, refs)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(w, rc)
+}
@@ -0,0 +1,264 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+)
+
+// SandboxImage is an image built or pulled by sb-hub.
+type SandboxImage struct {
+	ID      string
+	Tags    []string
+	Size    int64
+	Created time.Time
+	// Origin is "built" for images labelled by sb import, "loaded" for
+	// images from an archive, else "pulled".
+	Origin string
+	// Source is the project directory of a built image.
+	Source string
+	// UsedBy lists the sandboxes whose container runs the image.
+	UsedBy []string
+}
+
+// Dangling reports whether the image has lost all its tags, as the old
+// image of a rebuild does.
+func (i SandboxImage) Dangling() bool {
+	return len(i.Tags) == 0
+}
+
+// PulledImage records an image pulled or loaded by sb-hub. The ID is kept
+// so the old image of a ref that was pulled again is still recognised.
+type PulledImage struct {
+	Ref string `json:"ref"`
+	ID  string `json:"id"`
+	// Origin is "loaded" for images from an archive; empty means pulled.
+	Origin string `json:"origin,omitempty"`
+}
+
+// pulledPath holds the pulled images. Container names cannot start with
+// "_", so it never clashes with a sandbox record.
+func (s *MetaStore) pulledPath() string {
+	return filepath.Join(s.Dir, "_images.json")
+}
+
+// PulledImages returns the images recorded by RecordPull.
+func (s *MetaStore) PulledImages() ([]PulledImage, error) {
+	var pulled []PulledImage
+	data, err := os.ReadFile(s.pulledPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pulled, json.Unmarshal(data, &pulled)
+}
+
+// RecordPull remembers that sb-hub pulled ref as the image id.
+func (s *MetaStore) RecordPull(ref, id string) error {
+	return s.record(PulledImage{Ref: ref, ID: id})
+}
+
+// RecordLoad remembers that sb-hub loaded ref from an archive as the
+// image id.
+func (s *MetaStore) RecordLoad(ref, id string) error {
+	return s.record(PulledImage{Ref: ref, ID: id, Origin: "loaded"})
+}
+
+func (s *MetaStore) record(img PulledImage) error {
+	pulled, err := s.PulledImages()
+	if err != nil {
+		return err
+	}
+	for _, p := range pulled {
+		if p.Ref == img.Ref && p.ID == img.ID {
+			return nil
+		}
+	}
+	return s.savePulled(append(pulled, img))
+}
+
+// ForgetPulls drops the records of the image ids.
+func (s *MetaStore) ForgetPulls(ids ...string) error {
+	pulled, err := s.PulledImages()
+	if err != nil {
+		return err
+	}
+	drop := map[string]bool{}
+	for _, id := range ids {
+		drop[id] = true
+	}
+	var kept []PulledImage
+	for _, p := range pulled {
+		if !drop[p.ID] {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(pulled) {
+		return nil
+	}
+	return s.savePulled(kept)
+}
+
+func (s *MetaStore) savePulled(pulled []PulledImage) error {
+	data, err := json.MarshalIndent(pulled, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.pulledPath(), data, 0644)
+}
+
+// ListImages returns the images labelled com.sbhub.managed and the ones
+// recorded as pulled or loaded by sb-hub, with the sandboxes that use them. Records
+// of images that no longer exist are dropped.
+func (e *Dockerengine) ListImages(ctx context.Context) ([]SandboxImage, error) {
+	summaries, err := e.Client.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pulled := map[string]string{} // image ID -> origin
+	if e.Meta != nil {
+		records, err := e.Meta.PulledImages()
+		if err != nil {
+			return nil, err
+		}
+		present := map[string]bool{}
+		for _, s := range summaries {
+			present[s.ID] = true
+		}
+		var gone []string
+		for _, r := range records {
+			if !present[r.ID] {
+				gone = append(gone, r.ID)
+			} else if r.Origin != "" {
+				pulled[r.ID] = r.Origin
+			} else if pulled[r.ID] == "" {
+				pulled[r.ID] = "pulled"
+			}
+		}
+		if len(gone) > 0 {
+			e.Meta.ForgetPulls(gone...)
+		}
+	}
+
+	f := filters.NewArgs()
+	f.Add("label", "com.sbhub.managed=true")
+	containers, err := e.Client.ContainerList(ctx, container.ListOptions{All: true, Filters: f})
+	if err != nil {
+		return nil, err
+	}
+	usedBy := map[string][]string{}
+	for _, c := range containers {
+		if len(c.Names) > 0 {
+			usedBy[c.ImageID] = append(usedBy[c.ImageID], filepath.Base(c.Names[0]))
+		}
+	}
+
+	var images []SandboxImage
+	for _, s := range summaries {
+		built := s.Labels["com.sbhub.managed"] == "true"
+		if !built && pulled[s.ID] == "" {
+			continue
+		}
+		img := SandboxImage{ID: s.ID, Size: s.Size, Created: time.Unix(s.Created, 0), Origin: pulled[s.ID], UsedBy: usedBy[s.ID]}
+		if built {
+			img.Origin, img.Source = "built", s.Labels["com.sbhub.source"]
+		}
+		for _, tag := range s.RepoTags {
+			if tag != "<none>:<none>" {
+				img.Tags = append(img.Tags, tag)
+			}
+		}
+		sort.Strings(img.UsedBy)
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Created.After(images[j].Created) })
+	return images, nil
+}
+
+// ImagePruneOptions selects what PruneImages removes.
+type ImagePruneOptions struct {
+	// OlderThan keeps images created more recently than this.
+	OlderThan time.Duration
+	// DanglingOnly keeps images that still have a tag.
+	DanglingOnly bool
+	// BuildCache also prunes dangling build cache.
+	BuildCache bool
+	DryRun     bool
+}
+
+// ImagePruneReport is what PruneImages removed, or would remove.
+type ImagePruneReport struct {
+	Removed []SandboxImage
+	// Reclaimed is an upper bound, since images can share layers.
+	Reclaimed      int64
+	CacheReclaimed uint64
+	Errors         []error
+}
+
+// PruneImages removes sb-hub images that no sandbox uses. Tagged images
+// are removed tag by tag, so an image still used by a container outside
+// sb-hub is refused by the daemon and reported instead of forced.
+func (e *Dockerengine) PruneImages(ctx context.Context, opts ImagePruneOptions) (ImagePruneReport, error) {
+	var report ImagePruneReport
+	images, err := e.ListImages(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, img := range images {
+		if len(img.UsedBy) > 0 || (opts.DanglingOnly && !img.Dangling()) || time.Since(img.Created) < opts.OlderThan {
+			continue
+		}
+		if opts.DryRun {
+			report.Removed = append(report.Removed, img)
+			report.Reclaimed += img.Size
+			continue
+		}
+		refs := img.Tags
+		if img.Dangling() {
+			refs = []string{img.ID}
+		}
+		var failed error
+		for _, ref := range refs {
+			if _, err := e.Client.ImageRemove(ctx, ref, image.RemoveOptions{PruneChildren: true}); err != nil {
+				failed = fmt.Errorf("%s: %v", ref, err)
+				break
+			}
+		}
+		if failed != nil {
+			report.Errors = append(report.Errors, failed)
+			continue
+		}
+		if e.Meta != nil {
+			e.Meta.ForgetPulls(img.ID)
+		}
+		report.Removed = append(report.Removed, img)
+		report.Reclaimed += img.Size
+	}
+
+	if opts.BuildCache && !opts.DryRun {
+		cache, err := e.Client.BuildCachePrune(ctx, build.CachePruneOptions{})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("build cache: %v", err))
+		} else if cache != nil {
+			report.CacheReclaimed = cache.SpaceReclaimed
+		}
+	}
+	return report, errors.Join(report.Errors...)
+}
@@ -0,0 +1,272 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogOptions selects which part of a sandbox's log to read. Since and Until
+// accept anything the Docker API does: RFC3339 times, Unix timestamps or
+// relative durations such as "10m".
+type LogOptions struct {
+	Follow bool
+	Tail   string
+	Since  string
+	Until  string
+	Grep   *regexp.Regexp
+}
+
+// LogLine is one line of sandbox output.
+type LogLine struct {
+	Sandbox string
+	Stream  string // "stdout" or "stderr"
+	Time    time.Time
+	Text    string
+}
+
+// StreamLogs reads the log of one sandbox and calls fn for every line that
+// matches opts.Grep. Non-TTY streams are demultiplexed so stderr lines are
+// marked as such.
+func (e *Dockerengine) StreamLogs(ctx context.Context, name string, opts LogOptions, fn func(LogLine) error) error {
+	inspect, err := e.Client.ContainerInspect(ctx, name)
+	if err != nil {
+		return err
+	}
+	tty := inspect.Config != nil && inspect.Config.Tty
+
+	tail := opts.Tail
+	if tail == "" {
+		tail = "all"
+	}
+	out, err := e.Client.ContainerLogs(ctx, name, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Since:      opts.Since,
+		Until:      opts.Until,
+		Tail:       tail,
+		Timestamps: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var mu sync.Mutex
+	emit := func(stream string) *lineWriter {
+		return &lineWriter{fn: func(raw string) error {
+			line := parseLogLine(name, stream, raw)
+			if opts.Grep != nil && !opts.Grep.MatchString(line.Text) {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(line)
+		}}
+	}
+	stdout, stderr := emit("stdout"), emit("stderr")
+
+	if tty {
+		_, err = io.Copy(stdout, out)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, out)
+	}
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	if err := stdout.Flush(); err != nil {
+		return err
+	}
+	return stderr.Flush()
+}
+
+// MergeLogs reads the logs of several sandboxes and passes every line to fn,
+// one call at a time. When following, lines arrive as they are written;
+// otherwise they are sorted by timestamp before fn sees them.
+func (e *Dockerengine) MergeLogs(ctx context.Context, names []string, opts LogOptions, fn func(LogLine) error) error {
+	return MergeRuntimeLogs(ctx, &DockerRuntime{Engine: e}, names, opts, fn)
+}
+
+// MergeRuntimeLogs is MergeLogs for the sandboxes of any Runtime.
+func MergeRuntimeLogs(ctx context.Context, rt Runtime, names []string, opts LogOptions, fn func(LogLine) error) error {
+	var mu sync.Mutex
+	var collected []LogLine
+	collect := func(line LogLine) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if opts.Follow {
+			return fn(line)
+		}
+		collected = append(collected, line)
+		return nil
+	}
+
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			errs[i] = rt.Logs(ctx, name, opts, collect)
+		}(i, name)
+	}
+	wg.Wait()
+
+	sort.SliceStable(collected, func(i, j int) bool { return collected[i].Time.Before(collected[j].Time) })
+	for _, line := range collected {
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLogLine splits the timestamp Docker prepends to each line.
+func parseLogLine(name, stream, raw string) LogLine {
+	line := LogLine{Sandbox: name, Stream: stream, Text: strings.TrimSuffix(raw, "\r")}
+	if ts, rest, ok := strings.Cut(line.Text, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			line.Time = t
+			line.Text = rest
+		}
+	}
+	return line
+}
+
+// lineWriter buffers writes and calls fn once per complete line.
+type lineWriter struct {
+	buf bytes.Buffer
+	fn  func(string) error
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf.Next(i + 1))
+		if err := w.fn(strings.TrimSuffix(line, "\n")); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush emits a trailing line that had no newline.
+func (w *lineWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	rest := w.buf.String()
+	w.buf.Reset()
+	return w.fn(rest)
+}
+
+// LogArchiveDir is where the logs of a removed sandbox are kept.
+func LogArchiveDir(storageRoot, name string) string {
+	return filepath.Join(storageRoot, ".archive", name)
+}
+
+// ArchiveLogs writes the sandbox's full log to a gzip file in dir and
+// returns its path. Each line is stored as "<timestamp> <stream> <text>".
+func (e *Dockerengine) ArchiveLogs(ctx context.Context, name, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("logs-%s.log.gz", time.Now().UTC().Format("20060102T150405Z")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	zw.Name = name + ".log"
+	err = e.StreamLogs(ctx, name, LogOptions{}, func(line LogLine) error {
+		_, err := fmt.Fprintf(zw, "%s %s %s\n", line.Time.UTC().Format(time.RFC3339Nano), line.Stream, line.Text)
+		return err
+	})
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// ListLogArchives returns the archived log files in dir, oldest first.
+func ListLogArchives(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "logs-*.log.gz"))
+	sort.Strings(paths)
+	return paths, err
+}
+
+// ParseLogTime reads a --since or --until value the way the Docker API
+// does: an RFC3339 time, a Unix timestamp, or a duration before now.
+func ParseLogTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', expected an RFC3339 time, a Unix timestamp or a duration", value)
+}
+
+// ReadLogArchive replays an archive written by ArchiveLogs, applying grep
+// like StreamLogs does.
+func ReadLogArchive(path, name string, grep *regexp.Regexp, fn func(LogLine) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		ts, rest, _ := strings.Cut(scanner.Text(), " ")
+		stream, text, _ := strings.Cut(rest, " ")
+		line := LogLine{Sandbox: name, Stream: stream, Text: text}
+		line.Time, _ = time.Parse(time.RFC3339Nano, ts)
+		if grep != nil && !grep.MatchString(line.Text) {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
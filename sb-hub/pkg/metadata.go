@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SandboxMeta holds label values that changed after the container was
+// created. Docker labels are immutable, so updates such as a resize are
+// recorded here and layered over the container's own labels.
+type SandboxMeta struct {
+	Labels map[string]string `json:"labels"`
+	Init   *InitRecord       `json:"init,omitempty"`
+	// Context is the endpoint the sandbox was placed on.
+	Context string `json:"context,omitempty"`
+	// Created is when the sandbox was first created. Recreates reset the
+	// container's own creation time, so owner MaxTTLs count from this.
+	Created   time.Time `json:"created,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// InitRecord marks that a sandbox's init scripts and sbfile init commands
+// completed. It survives recreates so renew and attach do not run them
+// again.
+type InitRecord struct {
+	Scripts   []string  `json:"scripts"`
+	Commands  []string  `json:"commands,omitempty"`
+	Completed time.Time `json:"completed"`
+}
+
+// MetaStore keeps one JSON file of SandboxMeta per sandbox in Dir.
+type MetaStore struct {
+	Dir string
+}
+
+// MetaStoreFor returns the metadata store kept under storageRoot.
+func MetaStoreFor(storageRoot string) *MetaStore {
+	return &MetaStore{Dir: filepath.Join(storageRoot, ".meta")}
+}
+
+func (s *MetaStore) path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
+
+// Load returns the stored metadata of a sandbox, or an empty record.
+func (s *MetaStore) Load(name string) (SandboxMeta, error) {
+	meta := SandboxMeta{Labels: map[string]string{}}
+	data, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, err
+	}
+	if meta.Labels == nil {
+		meta.Labels = map[string]string{}
+	}
+	return meta, nil
+}
+
+// SetLabels merges labels into the sandbox's record.
+func (s *MetaStore) SetLabels(name string, labels map[string]string) error {
+	meta, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	for k, v := range labels {
+		meta.Labels[k] = v
+	}
+	return s.save(name, meta)
+}
+
+// ClearLabels drops the label overrides of a sandbox but keeps its init
+// record, context and creation time.
+func (s *MetaStore) ClearLabels(name string) error {
+	meta, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	if meta.Init == nil && meta.Context == "" && meta.Created.IsZero() {
+		return s.Remove(name)
+	}
+	meta.Labels = map[string]string{}
+	return s.save(name, meta)
+}
+
+// MarkInit records that scripts completed in the sandbox.
+func (s *MetaStore) MarkInit(name string, scripts []string) error {
+	meta, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	meta.Init = &InitRecord{Scripts: scripts, Completed: time.Now()}
+	return s.save(name, meta)
+}
+
+// MarkInitCommands records that shell commands completed in the sandbox,
+// after any scripts MarkInit recorded.
+func (s *MetaStore) MarkInitCommands(name string, commands []string) error {
+	meta, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	if meta.Init == nil {
+		meta.Init = &InitRecord{}
+	}
+	meta.Init.Commands = commands
+	meta.Init.Completed = time.Now()
+	return s.save(name, meta)
+}
+
+// KeepCreated records when the sandbox was first created, unless an
+// earlier recreate already did.
+func (s *MetaStore) KeepCreated(name string, created time.Time) error {
+	meta, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	if !meta.Created.IsZero() {
+		return nil
+	}
+	meta.Created = created
+	return s.save(name, meta)
+}
+
+// SetContext records the endpoint the sandbox runs on.
+func (s *MetaStore) SetContext(name, context string) error {
+	meta, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	meta.Context = context
+	return s.save(name, meta)
+}
+
+func (s *MetaStore) save(name string, meta SandboxMeta) error {
+	meta.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(name), data, 0644)
+}
+
+// Remove deletes the sandbox's record.
+func (s *MetaStore) Remove(name string) error {
+	err := os.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// EffectiveLabels returns the container labels with any values recorded
+// in the metadata store layered on top. Without a store it returns labels
+// unchanged.
+func (e *Dockerengine) EffectiveLabels(name string, labels map[string]string) map[string]string {
+	if e.Meta == nil {
+		return labels
+	}
+	meta, err := e.Meta.Load(name)
+	if err != nil || len(meta.Labels) == 0 {
+		return labels
+	}
+	merged := make(map[string]string, len(labels)+len(meta.Labels))
+	for k, v := range labels {
+		merged[k] = v
+	}
+	for k, v := range meta.Labels {
+		merged[k] = v
+	}
+	return merged
+}
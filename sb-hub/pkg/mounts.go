@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DataVolumeName is the managed volume backing /data when a sandbox is
+// created with volume storage instead of a host directory.
+func DataVolumeName(sandbox string) string {
+	return fmt.Sprintf("sbhub-%s-data", sandbox)
+}
+
+// ParseMountFlag parses "src:dst[:ro|rw]" into a bind string. Host paths are
+// made absolute; dst must be an absolute container path.
+func ParseMountFlag(flag string) (string, error) {
+	parts := strings.Split(flag, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return "", fmt.Errorf("invalid mount '%s', expected src:dst[:ro]", flag)
+	}
+	if !filepath.IsAbs(parts[1]) {
+		return "", fmt.Errorf("invalid mount '%s', destination must be an absolute path", flag)
+	}
+	if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+		return "", fmt.Errorf("invalid mount '%s', mode must be ro or rw", flag)
+	}
+
+	src, err := filepath.Abs(parts[0])
+	if err != nil {
+		return "", err
+	}
+	parts[0] = src
+	return strings.Join(parts, ":"), nil
+}
+
+// ParseVolumeFlag parses "name:dst" into a bind string for a named volume.
+func ParseVolumeFlag(flag string) (string, string, error) {
+	name, dst, ok := strings.Cut(flag, ":")
+	if !ok || name == "" || strings.Contains(name, "/") || !filepath.IsAbs(dst) {
+		return "", "", fmt.Errorf("invalid volume '%s', expected name:/path", flag)
+	}
+	return name, fmt.Sprintf("%s:%s", name, dst), nil
+}
+
+// BindTarget returns the container path of a bind string.
+func BindTarget(bind string) string {
+	parts := strings.Split(bind, ":")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// ReplaceBind swaps the bind mounted at target for newBind, leaving every
+// other bind untouched. newBind is appended if nothing was mounted there.
+func ReplaceBind(binds []string, target, newBind string) []string {
+	out := RemoveBind(binds, target)
+	return append(out, newBind)
+}
+
+// RemoveBind drops the bind mounted at target.
+func RemoveBind(binds []string, target string) []string {
+	var out []string
+	for _, b := range binds {
+		if BindTarget(b) != filepath.Clean(target) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
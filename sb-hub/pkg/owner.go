@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// OwnerPolicy limits what one owner may hold. Zero fields are unlimited.
+type OwnerPolicy struct {
+	MaxSandboxes     int     `yaml:"max_sandboxes"`
+	MaxCPUs          float64 `yaml:"max_cpus"`
+	MaxMemoryMB      uint64  `yaml:"max_memory_mb"`
+	MaxSnapshotBytes int64   `yaml:"max_snapshot_bytes"`
+	// MaxTTL caps how long a sandbox may live after it was (re)created,
+	// whatever its expiry label says. The janitor enforces it.
+	MaxTTL time.Duration `yaml:"max_ttl"`
+}
+
+// OwnerUsage is what an owner currently holds.
+type OwnerUsage struct {
+	Sandboxes     int
+	CPUs          float64
+	MemoryMB      uint64
+	SnapshotBytes int64
+}
+
+// CurrentOwner returns the configured owner, or the OS user name.
+func (c *Config) CurrentOwner() string {
+	if c.Owner != "" {
+		return c.Owner
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	return "unknown"
+}
+
+// PolicyFor returns the policy of owner layered over the default policy.
+func (c *Config) PolicyFor(owner string) OwnerPolicy {
+	policy := c.Owners["default"]
+	o, ok := c.Owners[owner]
+	if !ok {
+		return policy
+	}
+	if o.MaxSandboxes != 0 {
+		policy.MaxSandboxes = o.MaxSandboxes
+	}
+	if o.MaxCPUs != 0 {
+		policy.MaxCPUs = o.MaxCPUs
+	}
+	if o.MaxMemoryMB != 0 {
+		policy.MaxMemoryMB = o.MaxMemoryMB
+	}
+	if o.MaxSnapshotBytes != 0 {
+		policy.MaxSnapshotBytes = o.MaxSnapshotBytes
+	}
+	if o.MaxTTL != 0 {
+		policy.MaxTTL = o.MaxTTL
+	}
+	return policy
+}
+
+// CheckCreate returns an error when adding a sandbox of size would take
+// the owner past the policy.
+func (p OwnerPolicy) CheckCreate(usage OwnerUsage, size string) error {
+	spec, ok := SandboxSpecs[size]
+	if !ok {
+		return fmt.Errorf("unknown size preset '%s'", size)
+	}
+	if p.MaxSandboxes > 0 && usage.Sandboxes+1 > p.MaxSandboxes {
+		return fmt.Errorf("quota reached: %d of %d sandboxes", usage.Sandboxes, p.MaxSandboxes)
+	}
+	if p.MaxCPUs > 0 && usage.CPUs+spec.CPUCores > p.MaxCPUs {
+		return fmt.Errorf("CPU quota: %.1f of %.1f cores in use, %s needs %.1f", usage.CPUs, p.MaxCPUs, size, spec.CPUCores)
+	}
+	if p.MaxMemoryMB > 0 && usage.MemoryMB+spec.MemoryMB > p.MaxMemoryMB {
+		return fmt.Errorf("memory quota: %d of %d MB in use, %s needs %d MB", usage.MemoryMB, p.MaxMemoryMB, size, spec.MemoryMB)
+	}
+	return nil
+}
+
+// CheckSnapshot returns an error when a snapshot of size bytes would take
+// the owner past the snapshot quota.
+func (p OwnerPolicy) CheckSnapshot(usage OwnerUsage, size int64) error {
+	if p.MaxSnapshotBytes > 0 && usage.SnapshotBytes+size > p.MaxSnapshotBytes {
+		return fmt.Errorf("snapshot quota: %s of %s used, snapshot needs %s",
+			HumanBytes(usage.SnapshotBytes), HumanBytes(p.MaxSnapshotBytes), HumanBytes(size))
+	}
+	return nil
+}
+
+// ClampTTL shortens ttl to the policy's MaxTTL.
+func (p OwnerPolicy) ClampTTL(ttl time.Duration) time.Duration {
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		return p.MaxTTL
+	}
+	return ttl
+}
+
+// OwnerUsage sums the managed sandboxes of owner, except exclude. Stopped
+// sandboxes count too: they keep their reservation until removed.
+func (e *Dockerengine) OwnerUsage(ctx context.Context, owner, exclude string) (OwnerUsage, error) {
+	active, err := e.GetActiveSandboxes(ctx)
+	if err != nil {
+		return OwnerUsage{}, err
+	}
+	var usage OwnerUsage
+	for name, c := range active {
+		if name == exclude || c.Labels["com.sbhub.managed"] != "true" || c.Labels["com.sbhub.owner"] != owner {
+			continue
+		}
+		usage.Sandboxes++
+		if spec, ok := SandboxSpecs[e.EffectiveLabels(name, c.Labels)["com.sbhub.size"]]; ok {
+			usage.CPUs += spec.CPUCores
+			usage.MemoryMB += spec.MemoryMB
+		}
+	}
+	return usage, nil
+}
+
+// SnapshotBytes adds up the snapshots under storageRoot that the metadata
+// store records as belonging to owner.
+func SnapshotBytes(storageRoot string, meta *MetaStore, owner string) int64 {
+	entries, _ := os.ReadDir(storageRoot)
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.Contains(entry.Name(), "_snap_") {
+			continue
+		}
+		if m, err := meta.Load(entry.Name()); err == nil && m.Labels["com.sbhub.owner"] == owner {
+			total += TreeSize(filepath.Join(storageRoot, entry.Name()))
+		}
+	}
+	return total
+}
+
+// KeepCreated carries the sandbox's first creation time over to the
+// container that is about to replace it.
+func (e *Dockerengine) KeepCreated(name string, inspect container.InspectResponse) error {
+	if e.Meta == nil || inspect.ContainerJSONBase == nil {
+		return nil
+	}
+	created, err := time.Parse(time.RFC3339Nano, inspect.Created)
+	if err != nil {
+		return err
+	}
+	return e.Meta.KeepCreated(name, created)
+}
+
+// ExpiredByPolicy returns running sandboxes that have outlived their
+// owner's MaxTTL, counted from when the sandbox was first created, so
+// renews and other recreates do not extend it.
+func (e *Dockerengine) ExpiredByPolicy(ctx context.Context, maxTTL func(owner string) time.Duration) ([]container.Summary, error) {
+	active, err := e.GetActiveSandboxes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var expired []container.Summary
+	seen := map[string]bool{}
+	for _, c := range active {
+		if c.State != "running" || c.Labels["com.sbhub.managed"] != "true" || seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		created := time.Unix(c.Created, 0)
+		if e.Meta != nil {
+			if m, err := e.Meta.Load(filepath.Base(c.Names[0])); err == nil && !m.Created.IsZero() {
+				created = m.Created
+			}
+		}
+		limit := maxTTL(c.Labels["com.sbhub.owner"])
+		if limit > 0 && time.Since(created) > limit {
+			expired = append(expired, c)
+		}
+	}
+	return expired, nil
+}
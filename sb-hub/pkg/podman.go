@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PodmanSocket returns the address of the local Podman API socket:
+// $CONTAINER_HOST when it is a unix socket, the rootless socket under
+// $XDG_RUNTIME_DIR, or the rootful one.
+func PodmanSocket() string {
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Getuid() != 0 {
+		sock := filepath.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(sock); err == nil {
+			return "unix://" + sock
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// PodmanRuntime runs sandboxes through Podman's Docker-compatible API.
+type PodmanRuntime struct {
+	*DockerRuntime
+	// Relabel adds the SELinux "z" option to host binds so sandboxes can
+	// read them under an enforcing policy, as on RHEL.
+	Relabel bool
+}
+
+// NewPodmanRuntime wraps a client connected to a Podman socket. Binds are
+// relabelled when the socket is local and SELinux is enforcing.
+func NewPodmanRuntime(cli DockerClient, local bool) *PodmanRuntime {
+	return &PodmanRuntime{DockerRuntime: NewDockerRuntime(cli), Relabel: local && SELinuxEnforcing()}
+}
+
+func (r *PodmanRuntime) Name() string { return RuntimePodman }
+
+func (r *PodmanRuntime) Create(ctx context.Context, cfg SandboxConfig) (string, error) {
+	if r.Relabel {
+		cfg.Binds = RelabelBinds(cfg.Binds)
+	}
+	return r.DockerRuntime.Create(ctx, cfg)
+}
+
+// Relabels reports whether host binds on the endpoint need the SELinux
+// label option: Podman on this machine with SELinux enforcing.
+func (ep Endpoint) Relabels() bool {
+	return ep.Runtime == RuntimePodman && ep.Local() && SELinuxEnforcing()
+}
+
+// SELinuxEnforcing reports whether this host enforces an SELinux policy.
+func SELinuxEnforcing() bool {
+	data, err := os.ReadFile("/sys/fs/selinux/enforce")
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+// RelabelBinds adds the shared SELinux label option "z" to binds of host
+// paths. Named volumes and binds that already carry a label are left alone.
+func RelabelBinds(binds []string) []string {
+	out := make([]string, 0, len(binds))
+	for _, bind := range binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) < 2 || !filepath.IsAbs(parts[0]) {
+			out = append(out, bind)
+			continue
+		}
+		if len(parts) == 2 {
+			parts = append(parts, "z")
+		} else {
+			opts := strings.Split(parts[2], ",")
+			labelled := false
+			for _, opt := range opts {
+				labelled = labelled || opt == "z" || opt == "Z"
+			}
+			if !labelled {
+				parts[2] += ",z"
+			}
+		}
+		out = append(out, strings.Join(parts, ":"))
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// HumanBytes formats a byte count using binary units.
+func HumanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ProgressReader counts bytes read through it and redraws a one-line
+// progress indicator on Out at most every 250ms. Transfers that finish
+// before the first redraw print nothing.
+type ProgressReader struct {
+	R     io.Reader
+	Out   io.Writer
+	Label string
+	// Total is the expected size; 0 means unknown.
+	Total int64
+
+	read    int64
+	start   time.Time
+	last    time.Time
+	printed bool
+}
+
+func (p *ProgressReader) Read(b []byte) (int, error) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+		p.last = p.start
+	}
+	n, err := p.R.Read(b)
+	p.read += int64(n)
+	if p.Out != nil && time.Since(p.last) >= 250*time.Millisecond {
+		p.last = time.Now()
+		p.draw()
+	}
+	return n, err
+}
+
+func (p *ProgressReader) draw() {
+	p.printed = true
+	if p.Total > 0 {
+		pct := float64(p.read) / float64(p.Total) * 100
+		if pct > 100 {
+			pct = 100
+		}
+		fmt.Fprintf(p.Out, "\r📦 %s %s / %s (%.0f%%)   ", p.Label, HumanBytes(p.read), HumanBytes(p.Total), pct)
+		return
+	}
+	fmt.Fprintf(p.Out, "\r📦 %s %s   ", p.Label, HumanBytes(p.read))
+}
+
+// Done finishes the progress line if one was drawn.
+func (p *ProgressReader) Done() {
+	if !p.printed {
+		return
+	}
+	elapsed := time.Since(p.start).Round(100 * time.Millisecond)
+	fmt.Fprintf(p.Out, "\r📦 %s %s in %s          \n", p.Label, HumanBytes(p.read), elapsed)
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// InitScriptDir is where init scripts are copied inside a sandbox.
+const InitScriptDir = "/tmp/sbhub-init"
+
+// ResolveInitScripts expands a leading ~ in each path, makes it absolute
+// and checks that it is a readable file.
+func ResolveInitScripts(paths []string) ([]string, error) {
+	var scripts []string
+	for _, p := range paths {
+		abs, err := filepath.Abs(expandHome(p))
+		if err != nil {
+			return nil, err
+		}
+		fi, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("init script: %v", err)
+		}
+		if fi.IsDir() {
+			return nil, fmt.Errorf("init script %s is a directory", p)
+		}
+		scripts = append(scripts, abs)
+	}
+	return scripts, nil
+}
+
+// RunInitScripts copies the host scripts into the sandbox and runs them in
+// order with /bin/sh, streaming their output to out. It stops at the first
+// script that fails.
+func (e *Dockerengine) RunInitScripts(ctx context.Context, name string, scripts []string, out io.Writer) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	dir := strings.TrimPrefix(InitScriptDir, "/")
+	if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: time.Now()}); err != nil {
+		return err
+	}
+	targets := make([]string, len(scripts))
+	for i, script := range scripts {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			return fmt.Errorf("init script: %v", err)
+		}
+		targets[i] = path.Join(InitScriptDir, fmt.Sprintf("%02d-%s", i+1, filepath.Base(script)))
+		hdr := &tar.Header{Name: strings.TrimPrefix(targets[i], "/"), Mode: 0755, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := e.Client.CopyToContainer(ctx, name, "/", &buf, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("copying init scripts: %v", err)
+	}
+
+	for i, target := range targets {
+		fmt.Fprintf(out, "⚙️  [%s] %s\n", name, filepath.Base(scripts[i]))
+		code, err := e.Exec(ctx, name, ExecRequest{Cmd: []string{"/bin/sh", target}, Stdout: out, Stderr: out})
+		if err != nil {
+			return fmt.Errorf("init script %s failed: %v", filepath.Base(scripts[i]), err)
+		}
+		if code != 0 {
+			return fmt.Errorf("init script %s exited with code %d", filepath.Base(scripts[i]), code)
+		}
+	}
+	return nil
+}
+
+// InitSandbox runs scripts in a new sandbox and records their completion
+// in the metadata store.
+func (e *Dockerengine) InitSandbox(ctx context.Context, name string, scripts []string, out io.Writer) error {
+	if err := e.RunInitScripts(ctx, name, scripts, out); err != nil {
+		return err
+	}
+	if e.Meta == nil || len(scripts) == 0 {
+		return nil
+	}
+	return e.Meta.MarkInit(name, scripts)
+}
+
+// RunInitCommands runs shell commands in the sandbox in order, streaming
+// their output to out, and records them in the metadata store once all
+// succeeded.
+func (e *Dockerengine) RunInitCommands(ctx context.Context, name string, commands []string, out io.Writer) error {
+	for _, c := range commands {
+		fmt.Fprintf(out, "⚙️  [%s] %s\n", name, c)
+		code, err := e.Exec(ctx, name, ExecRequest{Cmd: []string{"/bin/sh", "-c", c}, Stdout: out, Stderr: out})
+		if err != nil {
+			return fmt.Errorf("init command '%s' failed: %v", c, err)
+		}
+		if code != 0 {
+			return fmt.Errorf("init command '%s' exited with code %d", c, code)
+		}
+	}
+	if e.Meta == nil || len(commands) == 0 {
+		return nil
+	}
+	return e.Meta.MarkInitCommands(name, commands)
+}
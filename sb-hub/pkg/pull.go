@@ -0,0 +1,266 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PullPolicy decides when EnsureImage contacts the registry.
+type PullPolicy string
+
+const (
+	PullAlways  PullPolicy = "always"
+	PullMissing PullPolicy = "missing"
+	PullNever   PullPolicy = "never"
+)
+
+// DefaultPullTimeout bounds a pull when PullOptions.Timeout is unset.
+const DefaultPullTimeout = 10 * time.Minute
+
+// ParsePullPolicy validates a --pull value. An empty string means missing.
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	switch p := PullPolicy(s); p {
+	case "":
+		return PullMissing, nil
+	case PullAlways, PullMissing, PullNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid pull policy '%s' (expected always, missing or never)", s)
+}
+
+// PullOptions controls how EnsureImage fetches an image.
+type PullOptions struct {
+	Policy  PullPolicy
+	Timeout time.Duration
+	// Out receives progress; nil means stdout.
+	Out io.Writer
+	// Progress draws a bar per layer. Otherwise only a summary line is
+	// printed once the pull finishes.
+	Progress bool
+	// Strict makes a failed PullAlways pull an error instead of falling
+	// back to the local copy.
+	Strict bool
+	// Auth resolves the registry login; nil reads only the Docker config.
+	Auth *Credentials
+}
+
+// LayerProgress is the state of one layer during a pull.
+type LayerProgress struct {
+	ID      string
+	Status  string
+	Current int64
+	Total   int64
+}
+
+// PullSummary is what a finished pull stream reported.
+type PullSummary struct {
+	Layers     int
+	Downloaded int64
+	Digest     string
+	Status     string
+}
+
+// ReadPullStream decodes the JSON messages of an image pull, calling fn
+// with the state of every layer after each update. An error message in
+// the stream is returned as an error.
+func ReadPullStream(r io.Reader, fn func([]LayerProgress)) (PullSummary, error) {
+	var summary PullSummary
+	var layers []LayerProgress
+	index := map[string]int{}
+
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return summary, fmt.Errorf("reading pull progress: %v", err)
+		}
+		if msg.Error != nil {
+			return summary, msg.Error
+		}
+		if msg.ErrorMessage != "" {
+			return summary, fmt.Errorf("%s", msg.ErrorMessage)
+		}
+
+		switch {
+		case strings.HasPrefix(msg.Status, "Digest: "):
+			summary.Digest = strings.TrimPrefix(msg.Status, "Digest: ")
+			continue
+		case strings.HasPrefix(msg.Status, "Status: "):
+			summary.Status = strings.TrimPrefix(msg.Status, "Status: ")
+			continue
+		case msg.ID == "" || strings.HasPrefix(msg.Status, "Pulling from"):
+			continue
+		}
+
+		i, ok := index[msg.ID]
+		if !ok {
+			i = len(layers)
+			index[msg.ID] = i
+			layers = append(layers, LayerProgress{ID: msg.ID})
+		}
+		l := &layers[i]
+		l.Status = msg.Status
+		switch {
+		case msg.Status == "Downloading" && msg.Progress != nil:
+			l.Current, l.Total = msg.Progress.Current, msg.Progress.Total
+		case msg.Status == "Download complete" || msg.Status == "Pull complete":
+			l.Current = l.Total
+		}
+		if fn != nil {
+			fn(layers)
+		}
+	}
+
+	summary.Layers = len(layers)
+	for _, l := range layers {
+		summary.Downloaded += l.Total
+	}
+	return summary, nil
+}
+
+// EnsureImage makes imageName available locally with the default policy,
+// pulling it only when it is missing.
+func (e *Dockerengine) EnsureImage(ctx context.Context, imageName string) error {
+	return e.PullImage(ctx, imageName, PullOptions{})
+}
+
+// PullImage applies the pull policy to imageName. With PullAlways a failed
+// pull falls back to a local copy unless opts.Strict is set, so sandboxes
+// can still be created offline.
+func (e *Dockerengine) PullImage(ctx context.Context, imageName string, opts PullOptions) error {
+	if opts.Policy == "" {
+		opts.Policy = PullMissing
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultPullTimeout
+	}
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	_, inspectErr := e.Client.ImageInspect(ctx, imageName)
+	local := inspectErr == nil
+	switch {
+	case opts.Policy == PullNever && !local:
+		return fmt.Errorf("image '%s' is not available locally and the pull policy is never", imageName)
+	case opts.Policy == PullNever, opts.Policy == PullMissing && local:
+		return nil
+	}
+
+	fmt.Fprintf(out, "⬇️  Pulling %s...\n", imageName)
+	start := time.Now()
+	summary, err := e.pull(ctx, imageName, opts, out)
+	if err != nil {
+		if local && !opts.Strict {
+			fmt.Fprintf(out, "⚠️  Pull of %s failed (%v); using the local copy\n", imageName, err)
+			return nil
+		}
+		return fmt.Errorf("pulling %s: %v", imageName, err)
+	}
+	if e.Meta != nil {
+		if img, err := e.Client.ImageInspect(ctx, imageName); err == nil {
+			e.Meta.RecordPull(imageName, img.ID)
+		}
+	}
+
+	status := summary.Status
+	if status == "" {
+		status = "Pulled " + imageName
+	}
+	fmt.Fprintf(out, "✅ %s (%d layers, %s in %s)\n", status, summary.Layers, HumanBytes(summary.Downloaded), time.Since(start).Round(100*time.Millisecond))
+	return nil
+}
+
+func (e *Dockerengine) pull(ctx context.Context, imageName string, opts PullOptions, out io.Writer) (PullSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	auth := opts.Auth
+	if auth == nil {
+		auth = &Credentials{}
+	}
+	registryAuth, err := auth.ForImage(imageName)
+	if err != nil {
+		fmt.Fprintf(out, "⚠️  Could not read credentials for %s (%v); pulling anonymously\n", imageName, err)
+	}
+
+	stream, err := e.Client.ImagePull(ctx, imageName, image.PullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return PullSummary{}, err
+	}
+	defer stream.Close()
+
+	var bars *layerBars
+	if opts.Progress {
+		bars = &layerBars{out: out}
+	}
+	summary, err := ReadPullStream(stream, func(layers []LayerProgress) {
+		if bars != nil {
+			bars.draw(layers, false)
+		}
+	})
+	if bars != nil {
+		bars.finish()
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return summary, fmt.Errorf("timed out after %s", opts.Timeout)
+	}
+	return summary, err
+}
+
+// layerBars redraws one progress line per layer in place.
+type layerBars struct {
+	out    io.Writer
+	layers []LayerProgress
+	drawn  int
+	last   time.Time
+}
+
+func (b *layerBars) draw(layers []LayerProgress, force bool) {
+	b.layers = layers
+	if !force && time.Since(b.last) < 100*time.Millisecond {
+		return
+	}
+	b.last = time.Now()
+	if b.drawn > 0 {
+		fmt.Fprintf(b.out, "\033[%dA", b.drawn)
+	}
+	for _, l := range layers {
+		fmt.Fprintf(b.out, "\r\033[K%s\n", FormatLayer(l))
+	}
+	b.drawn = len(layers)
+}
+
+func (b *layerBars) finish() {
+	if b.layers != nil {
+		b.draw(b.layers, true)
+	}
+}
+
+// FormatLayer renders a layer as "id: status [=====>    ] 1.2 MiB / 3.4 MiB".
+func FormatLayer(l LayerProgress) string {
+	if l.Total <= 0 || l.Status != "Downloading" {
+		return fmt.Sprintf("%s: %s", l.ID, l.Status)
+	}
+	const width = 30
+	filled := int(float64(l.Current) / float64(l.Total) * width)
+	if filled > width {
+		filled = width
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+	return fmt.Sprintf("%s: %s [%s] %s / %s", l.ID, l.Status, bar, HumanBytes(l.Current), HumanBytes(l.Total))
+}
@@ -0,0 +1,416 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// DockerHubServer is the key Docker uses for Docker Hub credentials.
+const DockerHubServer = "https://index.docker.io/v1/"
+
+// RegistryConfig is a login kept in the sb-hub config. PasswordEnv names
+// an environment variable holding the password or token, so it need not
+// be written to the file.
+type RegistryConfig struct {
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	PasswordEnv string `yaml:"password_env"`
+}
+
+// RegistryHost returns the registry of an image reference, with Docker
+// Hub as "docker.io".
+func RegistryHost(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", err
+	}
+	return reference.Domain(named), nil
+}
+
+// NormalizeRegistry turns a config.json key or a login argument such as
+// "https://index.docker.io/v1/" into a registry host.
+func NormalizeRegistry(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	host = strings.ToLower(host)
+	switch host {
+	case "", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
+
+// serverAddress is the key Docker stores the credentials of host under.
+func serverAddress(host string) string {
+	if host == "docker.io" {
+		return DockerHubServer
+	}
+	return host
+}
+
+// DockerConfigPath returns $DOCKER_CONFIG/config.json or
+// ~/.docker/config.json.
+func DockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// dockerAuth is one entry of the auths section of config.json.
+type dockerAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// DockerConfig is the part of ~/.docker/config.json that holds logins.
+type DockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// LoadDockerConfig reads the logins in the Docker config at path. A
+// missing file yields an empty config.
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	cfg := &DockerConfig{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || path == "" {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// helperFor returns the credential helper that holds host's login, if any.
+func (d *DockerConfig) helperFor(host string) string {
+	for server, helper := range d.CredHelpers {
+		if NormalizeRegistry(server) == host {
+			return helper
+		}
+	}
+	return d.CredsStore
+}
+
+// authFor returns the auths entry of host and the key it is stored under.
+func (d *DockerConfig) authFor(host string) (dockerAuth, string, bool) {
+	for server, a := range d.Auths {
+		if NormalizeRegistry(server) == host {
+			return a, server, true
+		}
+	}
+	return dockerAuth{}, "", false
+}
+
+// credentialHelperTimeout bounds each call to a docker-credential-* binary.
+const credentialHelperTimeout = 10 * time.Second
+
+// helperCredentials is the JSON a credential helper reads and writes.
+type helperCredentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// runCredentialHelper calls docker-credential-<helper> <action> with input
+// on stdin and returns its stdout.
+func runCredentialHelper(helper, action string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "docker-credential-"+helper, action)
+	c.Stdin = bytes.NewReader(input)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		msg := strings.TrimSpace(stdout.String() + stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("docker-credential-%s %s: %s", helper, action, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// errCredentialsNotFound is how helpers report a missing login.
+const errCredentialsNotFound = "credentials not found in native keychain"
+
+// Credentials resolves registry logins, first from the sb-hub config, then
+// from the Docker config and its credential helpers.
+type Credentials struct {
+	Registries map[string]RegistryConfig
+	// DockerConfig is the config.json to read; empty means DockerConfigPath.
+	DockerConfig string
+}
+
+func (c *Credentials) dockerConfigPath() string {
+	if c.DockerConfig != "" {
+		return c.DockerConfig
+	}
+	return DockerConfigPath()
+}
+
+// Lookup returns the login for a registry host, or false when there is
+// none and pulls should be anonymous.
+func (c *Credentials) Lookup(host string) (registry.AuthConfig, bool, error) {
+	host = NormalizeRegistry(host)
+	for server, rc := range c.Registries {
+		if NormalizeRegistry(server) != host {
+			continue
+		}
+		password := rc.Password
+		if rc.PasswordEnv != "" {
+			password = os.Getenv(rc.PasswordEnv)
+			if password == "" {
+				return registry.AuthConfig{}, false, fmt.Errorf("registry %s: $%s is not set", server, rc.PasswordEnv)
+			}
+		}
+		return registry.AuthConfig{Username: rc.Username, Password: password, ServerAddress: serverAddress(host)}, true, nil
+	}
+
+	dc, err := LoadDockerConfig(c.dockerConfigPath())
+	if err != nil {
+		return registry.AuthConfig{}, false, err
+	}
+	if helper := dc.helperFor(host); helper != "" {
+		out, err := runCredentialHelper(helper, "get", []byte(serverAddress(host)))
+		if err != nil {
+			if strings.Contains(err.Error(), errCredentialsNotFound) {
+				return registry.AuthConfig{}, false, nil
+			}
+			return registry.AuthConfig{}, false, err
+		}
+		var creds helperCredentials
+		if err := json.Unmarshal(out, &creds); err != nil {
+			return registry.AuthConfig{}, false, fmt.Errorf("docker-credential-%s: %v", helper, err)
+		}
+		auth := registry.AuthConfig{ServerAddress: serverAddress(host)}
+		if creds.Username == "<token>" {
+			auth.IdentityToken = creds.Secret
+		} else {
+			auth.Username, auth.Password = creds.Username, creds.Secret
+		}
+		return auth, true, nil
+	}
+
+	entry, _, ok := dc.authFor(host)
+	if !ok {
+		return registry.AuthConfig{}, false, nil
+	}
+	auth := registry.AuthConfig{ServerAddress: serverAddress(host), IdentityToken: entry.IdentityToken}
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return registry.AuthConfig{}, false, fmt.Errorf("auth for %s: %v", host, err)
+		}
+		user, pass, _ := strings.Cut(string(decoded), ":")
+		auth.Username, auth.Password = user, pass
+	}
+	return auth, true, nil
+}
+
+// ForImage returns the encoded RegistryAuth for pulling ref, or "" for an
+// anonymous pull.
+func (c *Credentials) ForImage(ref string) (string, error) {
+	host, err := RegistryHost(ref)
+	if err != nil {
+		return "", err
+	}
+	auth, ok, err := c.Lookup(host)
+	if err != nil || !ok {
+		return "", err
+	}
+	return registry.EncodeAuthConfig(auth)
+}
+
+// RegistryLogin is a known login, without its secret.
+type RegistryLogin struct {
+	Host     string
+	Username string
+	// Source is "sbhub config", "docker config" or "helper <name>".
+	Source string
+}
+
+// Logins lists the registries with known credentials. Usernames held by a
+// credential helper are looked up; one that cannot be read is left empty.
+func (c *Credentials) Logins() ([]RegistryLogin, error) {
+	seen := map[string]bool{}
+	var logins []RegistryLogin
+	for server, rc := range c.Registries {
+		host := NormalizeRegistry(server)
+		seen[host] = true
+		logins = append(logins, RegistryLogin{Host: host, Username: rc.Username, Source: "sbhub config"})
+	}
+
+	dc, err := LoadDockerConfig(c.dockerConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	hosts := map[string]bool{}
+	for server := range dc.Auths {
+		hosts[NormalizeRegistry(server)] = true
+	}
+	for server := range dc.CredHelpers {
+		hosts[NormalizeRegistry(server)] = true
+	}
+	for host := range hosts {
+		if seen[host] {
+			continue
+		}
+		login := RegistryLogin{Host: host, Source: "docker config"}
+		if helper := dc.helperFor(host); helper != "" {
+			login.Source = "helper " + helper
+		}
+		if auth, ok, err := c.Lookup(host); err == nil && ok {
+			login.Username = auth.Username
+			if auth.IdentityToken != "" && auth.Username == "" {
+				login.Username = "<token>"
+			}
+		} else if err == nil {
+			continue
+		}
+		logins = append(logins, login)
+	}
+	sort.Slice(logins, func(i, j int) bool { return logins[i].Host < logins[j].Host })
+	return logins, nil
+}
+
+// Login checks auth against the registry through the daemon. When the
+// registry hands out an identity token it replaces the password.
+func (e *Dockerengine) Login(ctx context.Context, auth registry.AuthConfig) (registry.AuthConfig, error) {
+	res, err := e.Client.RegistryLogin(ctx, auth)
+	if err != nil {
+		return auth, err
+	}
+	if res.IdentityToken != "" {
+		auth.Password, auth.IdentityToken = "", res.IdentityToken
+	}
+	return auth, nil
+}
+
+// StoreDockerLogin saves a login the way docker login does: in the
+// registry's credential helper when one is configured, otherwise in the
+// auths section of the Docker config. Other settings in the file are kept.
+func StoreDockerLogin(path string, auth registry.AuthConfig) error {
+	host := NormalizeRegistry(auth.ServerAddress)
+	dc, err := LoadDockerConfig(path)
+	if err != nil {
+		return err
+	}
+	if helper := dc.helperFor(host); helper != "" {
+		creds := helperCredentials{ServerURL: serverAddress(host), Username: auth.Username, Secret: auth.Password}
+		if auth.IdentityToken != "" {
+			creds.Username, creds.Secret = "<token>", auth.IdentityToken
+		}
+		input, _ := json.Marshal(creds)
+		_, err := runCredentialHelper(helper, "store", input)
+		return err
+	}
+
+	entry := dockerAuth{IdentityToken: auth.IdentityToken}
+	if auth.IdentityToken == "" {
+		entry.Auth = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+	} else {
+		entry.Auth = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":"))
+	}
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return editDockerAuths(path, func(auths map[string]json.RawMessage) {
+		if _, key, ok := dc.authFor(host); ok {
+			delete(auths, key)
+		}
+		auths[serverAddress(host)] = encoded
+	})
+}
+
+// RemoveDockerLogin erases a login from the registry's credential helper
+// or from the auths section. It reports false when there was none.
+func RemoveDockerLogin(path, server string) (bool, error) {
+	host := NormalizeRegistry(server)
+	dc, err := LoadDockerConfig(path)
+	if err != nil {
+		return false, err
+	}
+	if helper := dc.helperFor(host); helper != "" {
+		if _, err := runCredentialHelper(helper, "erase", []byte(serverAddress(host))); err != nil {
+			if strings.Contains(err.Error(), errCredentialsNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		// A credsStore login also leaves an empty auths entry behind
+		if _, key, ok := dc.authFor(host); ok {
+			return true, editDockerAuths(path, func(auths map[string]json.RawMessage) { delete(auths, key) })
+		}
+		return true, nil
+	}
+	_, key, ok := dc.authFor(host)
+	if !ok {
+		return false, nil
+	}
+	return true, editDockerAuths(path, func(auths map[string]json.RawMessage) { delete(auths, key) })
+}
+
+// editDockerAuths rewrites the auths section of the Docker config with
+// mode 0600. Entries are edited as raw JSON, so fields sb-hub does not
+// know survive, as does every other key of the file.
+func editDockerAuths(path string, edit func(map[string]json.RawMessage)) error {
+	raw := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	auths := map[string]json.RawMessage{}
+	if a, ok := raw["auths"]; ok {
+		if err := json.Unmarshal(a, &auths); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	edit(auths)
+	encoded, err := json.Marshal(auths)
+	if err != nil {
+		return err
+	}
+	raw["auths"] = encoded
+
+	out, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,292 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// Container runtimes a context can use.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+	// RuntimeFake keeps a command's sandbox changes in memory, for dry runs
+	RuntimeFake = "fake"
+)
+
+// ErrNotFound is returned, wrapped, by runtimes for a sandbox that does
+// not exist.
+var ErrNotFound = errors.New("sandbox not found")
+
+// SandboxConfig describes a sandbox to create, independent of the backend.
+type SandboxConfig struct {
+	Name  string
+	Image string
+	// Size is a preset from SandboxSpecs; it sets the CPU and memory limits.
+	Size   string
+	TTL    time.Duration
+	Env    []string
+	Cmd    []string
+	Labels map[string]string
+	// Binds are "src:dst[:ro]" mounts as ParseMountFlag returns them.
+	Binds []string
+	// Ports maps container ports such as "80/tcp" to host ports.
+	Ports     map[string]string
+	Network   string
+	HealthCmd string
+}
+
+// SandboxInfo is the state of a sandbox as a runtime reports it.
+type SandboxInfo struct {
+	ID    string
+	Name  string
+	Image string
+	// State is created, running, paused, restarting, exited or dead.
+	State string
+	// Health is healthy, unhealthy, starting, or empty without a
+	// healthcheck.
+	Health    string
+	ExitCode  int
+	Labels    map[string]string
+	Ports     map[string]string
+	Created   time.Time
+	StartedAt time.Time
+}
+
+// Running reports whether the sandbox's main process is up.
+func (s SandboxInfo) Running() bool {
+	return s.State == "running"
+}
+
+// Runtime creates and drives sandboxes on a container backend. Sandboxes
+// are addressed by name.
+type Runtime interface {
+	// Name is the backend, such as "docker".
+	Name() string
+	// Create creates a stopped sandbox and returns its ID.
+	Create(ctx context.Context, cfg SandboxConfig) (string, error)
+	Start(ctx context.Context, name string) error
+	Stop(ctx context.Context, name string, timeout time.Duration) error
+	// Remove deletes the sandbox, stopping it first if needed.
+	Remove(ctx context.Context, name string) error
+	// List returns every sb-hub sandbox, running or not.
+	List(ctx context.Context) ([]SandboxInfo, error)
+	Inspect(ctx context.Context, name string) (SandboxInfo, error)
+	Exec(ctx context.Context, name string, req ExecRequest) (int, error)
+	Logs(ctx context.Context, name string, opts LogOptions, fn func(LogLine) error) error
+}
+
+// NewRuntime connects to the backend of an endpoint.
+func NewRuntime(ep Endpoint) (Runtime, error) {
+	if ep.Runtime == RuntimeFake {
+		return NewFakeRuntime(), nil
+	}
+	cli, err := NewDockerClient(ep)
+	if err != nil {
+		return nil, err
+	}
+	return RuntimeFor(ep, &Dockerengine{Client: cli})
+}
+
+// RuntimeFor returns the backend of an endpoint on an engine already
+// connected to it, so commands can mix both.
+func RuntimeFor(ep Endpoint, engine *Dockerengine) (Runtime, error) {
+	switch ep.Runtime {
+	case "", RuntimeDocker:
+		return &DockerRuntime{Engine: engine}, nil
+	case RuntimePodman:
+		return &PodmanRuntime{DockerRuntime: &DockerRuntime{Engine: engine}, Relabel: ep.Relabels()}, nil
+	case RuntimeFake:
+		return NewFakeRuntime(), nil
+	}
+	return nil, fmt.Errorf("unknown runtime '%s'", ep.Runtime)
+}
+
+// DockerRuntime runs sandboxes through the Docker API.
+type DockerRuntime struct {
+	Engine *Dockerengine
+}
+
+// NewDockerRuntime wraps a Docker client. Set Engine.Meta to have label
+// overrides dropped when a sandbox is created again.
+func NewDockerRuntime(cli DockerClient) *DockerRuntime {
+	return &DockerRuntime{Engine: &Dockerengine{Client: cli}}
+}
+
+func (r *DockerRuntime) Name() string { return RuntimeDocker }
+
+func (r *DockerRuntime) Create(ctx context.Context, cfg SandboxConfig) (string, error) {
+	config, hostConfig, err := dockerConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.Engine.Client.ContainerCreate(ctx, config, hostConfig, nil, nil, cfg.Name)
+	if err != nil {
+		return "", err
+	}
+	// The new labels are authoritative again
+	if r.Engine.Meta != nil {
+		r.Engine.Meta.ClearLabels(cfg.Name)
+	}
+	return resp.ID, nil
+}
+
+func (r *DockerRuntime) Start(ctx context.Context, name string) error {
+	return notFound(name, r.Engine.Client.ContainerStart(ctx, name, container.StartOptions{}))
+}
+
+func (r *DockerRuntime) Stop(ctx context.Context, name string, timeout time.Duration) error {
+	seconds := int(timeout.Seconds())
+	return notFound(name, r.Engine.Client.ContainerStop(ctx, name, container.StopOptions{Timeout: &seconds}))
+}
+
+func (r *DockerRuntime) Remove(ctx context.Context, name string) error {
+	return notFound(name, r.Engine.Client.ContainerRemove(ctx, name, container.RemoveOptions{Force: true}))
+}
+
+func (r *DockerRuntime) List(ctx context.Context) ([]SandboxInfo, error) {
+	f := filters.NewArgs()
+	f.Add("label", "com.sbhub.managed=true")
+	containers, err := r.Engine.Client.ContainerList(ctx, container.ListOptions{All: true, Filters: f})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]SandboxInfo, 0, len(containers))
+	for _, c := range containers {
+		infos = append(infos, summaryInfo(c))
+	}
+	return infos, nil
+}
+
+func (r *DockerRuntime) Inspect(ctx context.Context, name string) (SandboxInfo, error) {
+	inspect, err := r.Engine.Client.ContainerInspect(ctx, name)
+	if err != nil {
+		return SandboxInfo{}, notFound(name, err)
+	}
+	return inspectInfo(inspect), nil
+}
+
+func (r *DockerRuntime) Exec(ctx context.Context, name string, req ExecRequest) (int, error) {
+	code, err := r.Engine.Exec(ctx, name, req)
+	return code, notFound(name, err)
+}
+
+func (r *DockerRuntime) Logs(ctx context.Context, name string, opts LogOptions, fn func(LogLine) error) error {
+	return notFound(name, r.Engine.StreamLogs(ctx, name, opts, fn))
+}
+
+// notFound wraps Docker's not-found errors in ErrNotFound.
+func notFound(name string, err error) error {
+	if err != nil && client.IsErrNotFound(err) {
+		return fmt.Errorf("%w: %s (%v)", ErrNotFound, name, err)
+	}
+	return err
+}
+
+// dockerConfig turns a SandboxConfig into the container and host config
+// that create uses for the same sandbox.
+func dockerConfig(cfg SandboxConfig) (*container.Config, *container.HostConfig, error) {
+	spec, ok := SandboxSpecs[cfg.Size]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown size preset '%s'", cfg.Size)
+	}
+	labels := make(map[string]string, len(cfg.Labels))
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	config := &container.Config{
+		Image:     cfg.Image,
+		Env:       cfg.Env,
+		Cmd:       cfg.Cmd,
+		Labels:    SandboxLabels(labels, cfg.TTL, cfg.Size),
+		Tty:       true,
+		OpenStdin: true,
+	}
+	if cfg.HealthCmd != "" {
+		config.Healthcheck = HealthCheck(cfg.HealthCmd)
+	}
+
+	hostConfig := &container.HostConfig{
+		Binds:       cfg.Binds,
+		NetworkMode: container.NetworkMode(cfg.Network),
+		Resources: container.Resources{
+			NanoCPUs: int64(spec.CPUCores * 1e9),
+			Memory:   int64(spec.MemoryMB * 1024 * 1024),
+		},
+	}
+	if len(cfg.Ports) > 0 {
+		config.ExposedPorts = nat.PortSet{}
+		hostConfig.PortBindings = nat.PortMap{}
+		for port, hostPort := range cfg.Ports {
+			config.ExposedPorts[nat.Port(port)] = struct{}{}
+			hostConfig.PortBindings[nat.Port(port)] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: hostPort}}
+		}
+	}
+	return config, hostConfig, nil
+}
+
+func summaryInfo(c container.Summary) SandboxInfo {
+	info := SandboxInfo{
+		ID:      c.ID,
+		Image:   c.Image,
+		State:   string(c.State),
+		Labels:  c.Labels,
+		Ports:   map[string]string{},
+		Created: time.Unix(c.Created, 0),
+	}
+	if len(c.Names) > 0 {
+		info.Name = strings.TrimPrefix(c.Names[0], "/")
+	}
+	switch {
+	case strings.Contains(c.Status, "(healthy)"):
+		info.Health = "healthy"
+	case strings.Contains(c.Status, "(unhealthy)"):
+		info.Health = "unhealthy"
+	case strings.Contains(c.Status, "(health: starting)"):
+		info.Health = "starting"
+	}
+	for _, p := range c.Ports {
+		if p.PublicPort != 0 {
+			info.Ports[fmt.Sprintf("%d/%s", p.PrivatePort, p.Type)] = fmt.Sprintf("%d", p.PublicPort)
+		}
+	}
+	return info
+}
+
+func inspectInfo(inspect container.InspectResponse) SandboxInfo {
+	info := SandboxInfo{Labels: map[string]string{}, Ports: map[string]string{}}
+	if base := inspect.ContainerJSONBase; base != nil {
+		info.ID = base.ID
+		info.Name = strings.TrimPrefix(base.Name, "/")
+		info.Created, _ = time.Parse(time.RFC3339Nano, base.Created)
+		if state := base.State; state != nil {
+			info.State = string(state.Status)
+			info.ExitCode = state.ExitCode
+			info.StartedAt, _ = time.Parse(time.RFC3339Nano, state.StartedAt)
+			if state.Health != nil && state.Health.Status != container.NoHealthcheck {
+				info.Health = string(state.Health.Status)
+			}
+		}
+	}
+	if inspect.Config != nil {
+		info.Image = inspect.Config.Image
+		for k, v := range inspect.Config.Labels {
+			info.Labels[k] = v
+		}
+	}
+	if inspect.NetworkSettings != nil {
+		for port, bindings := range inspect.NetworkSettings.Ports {
+			if len(bindings) > 0 {
+				info.Ports[string(port)] = bindings[0].HostPort
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,223 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"gopkg.in/yaml.v3"
+)
+
+// SandboxDefinition describes one sandbox declared in an sbhub.yaml file.
+type SandboxDefinition struct {
+	Preset string            `yaml:"preset" json:"preset"`
+	Image  string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Build  string            `yaml:"build,omitempty" json:"build,omitempty"`
+	Ports  []string          `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Env    map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Mounts []string          `yaml:"mounts,omitempty" json:"mounts,omitempty"`
+	TTL    string            `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	Init   []string          `yaml:"init,omitempty" json:"init,omitempty"`
+}
+
+// SandboxFile is the parsed form of a repo-level sbhub.yaml.
+type SandboxFile struct {
+	Project   string                       `yaml:"project"`
+	Sandboxes map[string]SandboxDefinition `yaml:"sandboxes"`
+
+	// Dir is the directory the file was loaded from; relative build and
+	// mount paths are resolved against it.
+	Dir string `yaml:"-"`
+}
+
+// LoadSandboxFile reads and validates an sbhub.yaml. The project name
+// defaults to the name of the directory holding the file.
+func LoadSandboxFile(path string) (*SandboxFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	var f SandboxFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	f.Dir = filepath.Dir(abs)
+	if f.Project == "" {
+		f.Project = filepath.Base(f.Dir)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Validate checks every definition for unknown presets, conflicting image
+// sources and malformed TTLs, ports or mounts.
+func (f *SandboxFile) Validate() error {
+	if len(f.Sandboxes) == 0 {
+		return fmt.Errorf("no sandboxes defined")
+	}
+	for name, def := range f.Sandboxes {
+		if def.Preset == "" {
+			def.Preset = "small"
+			f.Sandboxes[name] = def
+		}
+		if _, ok := SandboxSpecs[def.Preset]; !ok {
+			return fmt.Errorf("sandbox '%s': invalid preset '%s'", name, def.Preset)
+		}
+		if def.Image != "" && def.Build != "" {
+			return fmt.Errorf("sandbox '%s': image and build are mutually exclusive", name)
+		}
+		if def.TTL != "" {
+			ttl, err := time.ParseDuration(def.TTL)
+			if err != nil {
+				return fmt.Errorf("sandbox '%s': invalid ttl: %v", name, err)
+			}
+			if ttl <= 0 {
+				return fmt.Errorf("sandbox '%s': ttl must be positive, got %s", name, def.TTL)
+			}
+		}
+		for _, m := range def.Mounts {
+			if _, err := f.MountBind(m); err != nil {
+				return fmt.Errorf("sandbox '%s': %v", name, err)
+			}
+		}
+		for _, p := range def.Ports {
+			if _, _, err := ParsePortMapping(p); err != nil {
+				return fmt.Errorf("sandbox '%s': %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SandboxName returns the container name used for a definition.
+func (f *SandboxFile) SandboxName(name string) string {
+	return fmt.Sprintf("%s-%s", f.Project, name)
+}
+
+// ResolvePath makes a path from the file relative to the file's directory.
+func (f *SandboxFile) ResolvePath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(f.Dir, p)
+}
+
+// MountBind turns a mounts entry into a bind string. Relative host paths
+// are taken from the file's directory.
+func (f *SandboxFile) MountBind(m string) (string, error) {
+	if src, rest, ok := strings.Cut(m, ":"); ok && src != "" {
+		m = f.ResolvePath(src) + ":" + rest
+	}
+	return ParseMountFlag(m)
+}
+
+// Hash returns a stable digest of the definition. It is stamped on the
+// container as com.sbhub.spec-hash so apply can tell what changed.
+func (d SandboxDefinition) Hash() string {
+	data, _ := json.Marshal(d)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// ParsePortMapping splits "80" or "8080:80" into host and container port.
+// An empty host port means one should be auto-assigned.
+func ParsePortMapping(p string) (string, string, error) {
+	parts := strings.Split(p, ":")
+	var host, ctr string
+	switch len(parts) {
+	case 1:
+		ctr = parts[0]
+	case 2:
+		host, ctr = parts[0], parts[1]
+		if !validPort(host) {
+			return "", "", fmt.Errorf("invalid port mapping '%s', host port must be 1-65535", p)
+		}
+	default:
+		return "", "", fmt.Errorf("invalid port mapping '%s'", p)
+	}
+	num, proto, hasProto := strings.Cut(ctr, "/")
+	if !validPort(num) || (hasProto && proto != "tcp" && proto != "udp" && proto != "sctp") {
+		return "", "", fmt.Errorf("invalid port mapping '%s', container port must be 1-65535 with an optional /tcp, /udp or /sctp", p)
+	}
+	return host, ctr, nil
+}
+
+func validPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+type PlanAction string
+
+const (
+	PlanCreate    PlanAction = "create"
+	PlanRecreate  PlanAction = "recreate"
+	PlanRemove    PlanAction = "remove"
+	PlanUnchanged PlanAction = "unchanged"
+	// PlanConflict marks a name taken by a container apply must not touch.
+	PlanConflict PlanAction = "conflict"
+)
+
+// PlanStep is a single reconciliation step produced by PlanSandboxes.
+type PlanStep struct {
+	Name       string
+	Definition string
+	Action     PlanAction
+	Reason     string
+}
+
+// PlanSandboxes compares the desired state in f with the containers in
+// active and returns the steps needed to reconcile them, sorted by name.
+// Containers of other projects, or not managed by sb-hub, are never
+// replaced; their names are planned as conflicts.
+func PlanSandboxes(f *SandboxFile, active map[string]container.Summary) []PlanStep {
+	var steps []PlanStep
+	desired := make(map[string]bool)
+
+	for defName, def := range f.Sandboxes {
+		name := f.SandboxName(defName)
+		desired[name] = true
+
+		c, exists := active[name]
+		switch {
+		case !exists:
+			steps = append(steps, PlanStep{Name: name, Definition: defName, Action: PlanCreate, Reason: "not found"})
+		case c.Labels["com.sbhub.managed"] != "true":
+			steps = append(steps, PlanStep{Name: name, Definition: defName, Action: PlanConflict, Reason: "not managed by sb-hub"})
+		case c.Labels["com.sbhub.project"] != f.Project:
+			reason := "created outside any project"
+			if owner := c.Labels["com.sbhub.project"]; owner != "" {
+				reason = fmt.Sprintf("owned by project %s", owner)
+			}
+			steps = append(steps, PlanStep{Name: name, Definition: defName, Action: PlanConflict, Reason: reason})
+		case c.Labels["com.sbhub.spec-hash"] != def.Hash():
+			steps = append(steps, PlanStep{Name: name, Definition: defName, Action: PlanRecreate, Reason: "definition changed"})
+		default:
+			steps = append(steps, PlanStep{Name: name, Definition: defName, Action: PlanUnchanged})
+		}
+	}
+
+	for name, c := range active {
+		if c.Labels["com.sbhub.managed"] == "true" && c.Labels["com.sbhub.project"] == f.Project && !desired[name] {
+			steps = append(steps, PlanStep{Name: name, Action: PlanRemove, Reason: "no longer defined"})
+		}
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Name < steps[j].Name })
+	return steps
+}
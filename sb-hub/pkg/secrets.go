@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretsMountDir is where secrets appear inside a sandbox.
+const SecretsMountDir = "/run/secrets"
+
+// secretNamePattern needs a leading letter or digit, which rules out "."
+// and ".." as file names in the store.
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// SecretStore keeps secrets AES-GCM encrypted on disk, one file per secret.
+// The key comes from SBHUB_SECRETS_KEY when set, otherwise from a random key
+// file created on first use.
+type SecretStore struct {
+	Dir string
+	key []byte
+}
+
+// OpenSecretStore opens (creating if needed) the store in dir.
+func OpenSecretStore(dir, keyPath string) (*SecretStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	key, err := loadSecretKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretStore{Dir: dir, key: key}, nil
+}
+
+func loadSecretKey(keyPath string) ([]byte, error) {
+	if pass := os.Getenv("SBHUB_SECRETS_KEY"); pass != "" {
+		sum := sha256.Sum256([]byte(pass))
+		return sum[:], nil
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("secret key %s is corrupt", keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(keyPath, key, 0600)
+}
+
+// ValidateSecretName rejects names that can't be used as a file name.
+func ValidateSecretName(name string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name '%s'", name)
+	}
+	return nil
+}
+
+func (s *SecretStore) path(name string) string {
+	return filepath.Join(s.Dir, name+".enc")
+}
+
+func (s *SecretStore) Set(name string, value []byte) error {
+	if err := ValidateSecretName(name); err != nil {
+		return err
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(name), gcm.Seal(nonce, nonce, value, []byte(name)), 0600)
+}
+
+func (s *SecretStore) Get(name string) ([]byte, error) {
+	if err := ValidateSecretName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("secret '%s' not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secret '%s' is corrupt", name)
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	value, err := gcm.Open(nil, nonce, sealed, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("secret '%s' could not be decrypted (wrong key?)", name)
+	}
+	return value, nil
+}
+
+func (s *SecretStore) Remove(name string) error {
+	if err := ValidateSecretName(name); err != nil {
+		return err
+	}
+	return os.Remove(s.path(name))
+}
+
+func (s *SecretStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".enc") {
+			names = append(names, strings.TrimSuffix(e.Name(), ".enc"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// MountedSecretsDir is where the decrypted secrets of a sandbox are kept
+// for bind mounting while the sandbox exists.
+func MountedSecretsDir(storageRoot, sandbox string) string {
+	return filepath.Join(storageRoot, ".secrets", "mounted", sandbox)
+}
+
+// RemoveMountedSecrets deletes the decrypted secrets of a sandbox. It must
+// run whenever a sandbox is removed, so plaintext never outlives it or
+// leaks into a later sandbox of the same name.
+func RemoveMountedSecrets(storageRoot, sandbox string) error {
+	return os.RemoveAll(MountedSecretsDir(storageRoot, sandbox))
+}
+
+// Materialize decrypts a secret into dir so it can be bind mounted, and
+// returns the path of the plaintext file.
+func (s *SecretStore) Materialize(name, dir string) (string, error) {
+	value, err := s.Get(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	os.Remove(path)
+	return path, os.WriteFile(path, value, 0400)
+}
+
+func (s *SecretStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParseSecretFlag splits a --secret value. "NAME=path" reads the secret from
+// a host file; a bare "NAME" reads it from the encrypted store.
+func ParseSecretFlag(flag string) (string, string, error) {
+	name, path, _ := strings.Cut(flag, "=")
+	if err := ValidateSecretName(name); err != nil {
+		return "", "", err
+	}
+	return name, path, nil
+}
+
+// SecretBind returns the read-only bind that exposes hostPath as
+// /run/secrets/<name> inside the sandbox.
+func SecretBind(name, hostPath string) string {
+	return fmt.Sprintf("%s:%s/%s:ro", hostPath, SecretsMountDir, name)
+}
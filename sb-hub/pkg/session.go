@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CastHeader is the first line of an asciicast v2 recording.
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     uint              `json:"width"`
+	Height    uint              `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// CastEvent is one recorded event: seconds since start, event type ("o"
+// for output, "r" for resize) and data.
+type CastEvent struct {
+	Time float64
+	Type string
+	Data string
+}
+
+// SessionRecorder writes a TTY stream as asciicast v2. It is an io.Writer
+// for the output side and is safe for concurrent use.
+type SessionRecorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	start   time.Time
+	pending []byte
+}
+
+// NewSessionRecorder writes the cast header to w and returns a recorder
+// timing events from now.
+func NewSessionRecorder(w io.Writer, size TermSize, title string, env map[string]string) (*SessionRecorder, error) {
+	start := time.Now()
+	header, err := json.Marshal(CastHeader{
+		Version:   2,
+		Width:     size.Width,
+		Height:    size.Height,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env:       env,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", header); err != nil {
+		return nil, err
+	}
+	return &SessionRecorder{w: w, start: start}, nil
+}
+
+// Write records p as an output event. Multi-byte characters split across
+// writes are held back until complete so every event is valid UTF-8.
+func (r *SessionRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.pending, p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return len(p), nil
+	}
+	if err := r.event("o", string(data[:cut])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize records a terminal size change.
+func (r *SessionRecorder) Resize(size TermSize) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.event("r", fmt.Sprintf("%dx%d", size.Width, size.Height))
+}
+
+// Close flushes any held-back partial character.
+func (r *SessionRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		return nil
+	}
+	err := r.event("o", string(r.pending))
+	r.pending = nil
+	return err
+}
+
+func (r *SessionRecorder) event(kind, data string) error {
+	line, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), kind, data})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s\n", line)
+	return err
+}
+
+// ReadCast parses an asciicast v2 stream.
+func ReadCast(r io.Reader) (CastHeader, []CastEvent, error) {
+	var header CastHeader
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, fmt.Errorf("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if header.Version != 2 {
+		return header, nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	var events []CastEvent
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var raw []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil || len(raw) != 3 {
+			return header, events, fmt.Errorf("invalid event on line %d", len(events)+2)
+		}
+		t, _ := raw[0].(float64)
+		kind, _ := raw[1].(string)
+		data, _ := raw[2].(string)
+		events = append(events, CastEvent{Time: t, Type: kind, Data: data})
+	}
+	return header, events, scanner.Err()
+}
+
+// PlayCast replays the output events of a recording to out, sleeping
+// between them. speed scales the timing and pauses longer than maxIdle are
+// shortened to maxIdle (zero keeps them).
+func PlayCast(r io.Reader, out io.Writer, speed float64, maxIdle time.Duration) error {
+	_, events, err := ReadCast(r)
+	if err != nil {
+		return err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+	last := 0.0
+	for _, ev := range events {
+		delay := time.Duration((ev.Time - last) / speed * float64(time.Second))
+		last = ev.Time
+		if maxIdle > 0 && delay > maxIdle {
+			delay = maxIdle
+		}
+		if ev.Type != "o" {
+			continue
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if _, err := io.WriteString(out, ev.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SessionInfo describes one recording on disk.
+type SessionInfo struct {
+	ID       string
+	Sandbox  string
+	Started  time.Time
+	Duration time.Duration
+	Size     int64
+	Path     string
+}
+
+// NewSessionID names a recording after its sandbox and start time. A
+// random suffix keeps consoles started in the same second apart.
+func NewSessionID(sandbox string, t time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%s-%s", sandbox, t.UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix))
+}
+
+// SessionPath is where a recording is stored under dir.
+func SessionPath(dir, sandbox, id string) string {
+	return filepath.Join(dir, sandbox, id+".cast")
+}
+
+// ListSessions returns the recordings for sandbox under dir, oldest first.
+func ListSessions(dir, sandbox string) ([]SessionInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, sandbox, "*.cast"))
+	if err != nil {
+		return nil, err
+	}
+	var sessions []SessionInfo
+	for _, p := range paths {
+		info := SessionInfo{
+			ID:      strings.TrimSuffix(filepath.Base(p), ".cast"),
+			Sandbox: sandbox,
+			Path:    p,
+		}
+		if fi, err := os.Stat(p); err == nil {
+			info.Size = fi.Size()
+		}
+		if f, err := os.Open(p); err == nil {
+			header, events, _ := ReadCast(f)
+			f.Close()
+			info.Started = time.Unix(header.Timestamp, 0)
+			if len(events) > 0 {
+				info.Duration = time.Duration(events[len(events)-1].Time * float64(time.Second))
+			}
+		}
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.Before(sessions[j].Started) })
+	return sessions, nil
+}
+
+// FindSession locates a recording by ID in any sandbox under dir.
+func FindSession(dir, id string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*", id+".cast"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("session '%s' not found", id)
+	}
+	return matches[0], nil
+}
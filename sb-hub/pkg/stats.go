@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// SandboxStats is one resource usage sample of a sandbox. CPUPercent is
+// relative to one host core, like docker stats; CPULimitPercent and
+// MemPercent are relative to the sandbox's preset limits.
+type SandboxStats struct {
+	Name            string  `json:"name"`
+	Size            string  `json:"size"`
+	CPUPercent      float64 `json:"cpu_percent"`
+	CPULimitPercent float64 `json:"cpu_limit_percent"`
+	MemUsage        uint64  `json:"mem_usage_bytes"`
+	MemLimit        uint64  `json:"mem_limit_bytes"`
+	MemPercent      float64 `json:"mem_percent"`
+	NetRx           uint64  `json:"net_rx_bytes"`
+	NetTx           uint64  `json:"net_tx_bytes"`
+	BlockRead       uint64  `json:"block_read_bytes"`
+	BlockWrite      uint64  `json:"block_write_bytes"`
+	Pids            uint64  `json:"pids"`
+}
+
+// ComputeStats turns a raw stats response into a sample, using the size
+// preset for the CPU limit since the daemon does not report NanoCPUs.
+func ComputeStats(name, size string, s container.StatsResponse) SandboxStats {
+	out := SandboxStats{Name: name, Size: size, Pids: s.PidsStats.Current}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	online := float64(s.CPUStats.OnlineCPUs)
+	if online == 0 {
+		online = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && sysDelta > 0 {
+		out.CPUPercent = cpuDelta / sysDelta * online * 100
+	}
+	if spec, ok := SandboxSpecs[size]; ok && spec.CPUCores > 0 {
+		out.CPULimitPercent = out.CPUPercent / spec.CPUCores
+	}
+
+	// Page cache is reclaimable, so leave it out like docker stats does
+	out.MemUsage = s.MemoryStats.Usage
+	cache, ok := s.MemoryStats.Stats["inactive_file"]
+	if !ok {
+		cache = s.MemoryStats.Stats["total_inactive_file"]
+	}
+	if cache < out.MemUsage {
+		out.MemUsage -= cache
+	}
+	out.MemLimit = s.MemoryStats.Limit
+	if out.MemLimit > 0 {
+		out.MemPercent = float64(out.MemUsage) / float64(out.MemLimit) * 100
+	}
+
+	for _, n := range s.Networks {
+		out.NetRx += n.RxBytes
+		out.NetTx += n.TxBytes
+	}
+	for _, b := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(b.Op) {
+		case "read":
+			out.BlockRead += b.Value
+		case "write":
+			out.BlockWrite += b.Value
+		}
+	}
+	return out
+}
+
+// SampleStats takes a single usage sample of the sandbox.
+func (e *Dockerengine) SampleStats(ctx context.Context, name, size string) (SandboxStats, error) {
+	resp, err := e.Client.ContainerStats(ctx, name, false)
+	if err != nil {
+		return SandboxStats{}, err
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return SandboxStats{}, err
+	}
+	return ComputeStats(name, size, raw), nil
+}
+
+// WatchStats streams usage samples of the sandbox to fn, about one per
+// second, until ctx is cancelled or the container stops.
+func (e *Dockerengine) WatchStats(ctx context.Context, name, size string, fn func(SandboxStats)) error {
+	resp, err := e.Client.ContainerStats(ctx, name, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var raw container.StatsResponse
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		fn(ComputeStats(name, size, raw))
+	}
+}
+
+// LimitWatch flags sandboxes that keep running at their preset limits.
+// A warning is raised once per streak of Samples consecutive samples at or
+// above Threshold percent of the CPU or memory limit.
+type LimitWatch struct {
+	Threshold float64
+	Samples   int
+	cpu       map[string]int
+	mem       map[string]int
+}
+
+func NewLimitWatch(threshold float64, samples int) *LimitWatch {
+	return &LimitWatch{Threshold: threshold, Samples: samples, cpu: map[string]int{}, mem: map[string]int{}}
+}
+
+// Observe records a sample and returns any warnings it triggers.
+func (w *LimitWatch) Observe(s SandboxStats) []string {
+	var warnings []string
+	if w.streak(w.cpu, s.Name, s.CPULimitPercent) {
+		warnings = append(warnings, fmt.Sprintf("%s is using %.0f%% of its %s CPU limit", s.Name, s.CPULimitPercent, s.Size))
+	}
+	if w.streak(w.mem, s.Name, s.MemPercent) {
+		warnings = append(warnings, fmt.Sprintf("%s is using %.0f%% of its %s memory limit (%s)", s.Name, s.MemPercent, s.Size, HumanBytes(int64(s.MemLimit))))
+	}
+	return warnings
+}
+
+func (w *LimitWatch) streak(counts map[string]int, name string, percent float64) bool {
+	if percent < w.Threshold {
+		counts[name] = 0
+		return false
+	}
+	counts[name]++
+	return counts[name] == w.Samples
+}
@@ -0,0 +1,273 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/moby/patternmatcher"
+)
+
+// IgnoreMatcher decides which paths a sync leaves alone. A nil matcher
+// ignores nothing.
+type IgnoreMatcher struct {
+	pm *patternmatcher.PatternMatcher
+}
+
+// NewIgnoreMatcher compiles gitignore-style patterns. A pattern without a
+// slash matches at any depth, a leading slash anchors it to the root, and a
+// leading '!' re-includes a path.
+func NewIgnoreMatcher(lines []string) (*IgnoreMatcher, error) {
+	var patterns []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		neg := ""
+		if strings.HasPrefix(line, "!") {
+			neg, line = "!", line[1:]
+		}
+		line = strings.TrimSuffix(line, "/")
+		if strings.HasPrefix(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		patterns = append(patterns, neg+filepath.FromSlash(line))
+	}
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &IgnoreMatcher{pm: pm}, nil
+}
+
+// LoadIgnoreMatcher reads .gitignore and .sbignore from dir. .git is always
+// ignored.
+func LoadIgnoreMatcher(dir string) (*IgnoreMatcher, error) {
+	lines := []string{".git"}
+	for _, name := range []string{".gitignore", ".sbignore"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+	}
+	return NewIgnoreMatcher(lines)
+}
+
+// Ignored reports whether rel (relative to the sync root) or any of its
+// parent directories is ignored.
+func (m *IgnoreMatcher) Ignored(rel string) bool {
+	if m == nil || rel == "." {
+		return false
+	}
+	ok, _ := m.pm.MatchesOrParentMatches(rel)
+	return ok
+}
+
+// ParseSyncSpec splits "<localdir>:<path>" into an absolute local directory
+// and an absolute container path.
+func ParseSyncSpec(spec string) (string, string, error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 || i == len(spec)-1 {
+		return "", "", fmt.Errorf("invalid sync spec '%s', expected <localdir>:<path>", spec)
+	}
+	local, remote := spec[:i], spec[i+1:]
+	if !path.IsAbs(remote) {
+		return "", "", fmt.Errorf("invalid sync spec '%s', container path must be absolute", spec)
+	}
+	abs, err := filepath.Abs(local)
+	if err != nil {
+		return "", "", err
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return "", "", err
+	}
+	if !fi.IsDir() {
+		return "", "", fmt.Errorf("%s is not a directory", local)
+	}
+	return abs, path.Clean(remote), nil
+}
+
+type SyncOptions struct {
+	Sandbox       string
+	LocalDir      string
+	ContainerPath string
+	// TwoWay also polls the sandbox every Interval and pulls back files
+	// that are newer than their host copy.
+	TwoWay   bool
+	Interval time.Duration
+	Ignore   *IgnoreMatcher
+}
+
+// PushTree copies LocalDir (or only rels within it) into the sandbox using
+// the Docker archive API.
+func (e *Dockerengine) PushTree(ctx context.Context, opts SyncOptions, rels []string) error {
+	content := TarTree(opts.LocalDir, opts.ContainerPath, rels, opts.Ignore)
+	defer content.Close()
+	return e.CopyToSandbox(ctx, opts.Sandbox, "/", content, false)
+}
+
+// PullTree copies files from the sandbox that are missing on the host or
+// newer than the host copy. pulled records the mtime given to each file so
+// the watcher doesn't push it straight back.
+func (e *Dockerengine) PullTree(ctx context.Context, opts SyncOptions, pulled map[string]time.Time) error {
+	content, _, err := e.CopyFromSandbox(ctx, opts.Sandbox, opts.ContainerPath)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	return ExtractTar(content, opts.LocalDir, func(rel string, hdr *tar.Header) bool {
+		if rel == "." || opts.Ignore.Ignored(rel) {
+			return false
+		}
+		fi, err := os.Lstat(filepath.Join(opts.LocalDir, rel))
+		if hdr.Typeflag != tar.TypeReg {
+			return os.IsNotExist(err)
+		}
+		if err == nil && !hdr.ModTime.After(fi.ModTime()) {
+			return false
+		}
+		fmt.Printf("⬇️  %s\n", rel)
+		pulled[rel] = hdr.ModTime
+		return true
+	})
+}
+
+// WatchSync pushes LocalDir into the sandbox once, then keeps it in sync as
+// files change until ctx is cancelled. Deletions on the host are mirrored;
+// in two-way mode deletions inside the sandbox are not.
+func (e *Dockerengine) WatchSync(ctx context.Context, opts SyncOptions) error {
+	if err := e.PushTree(ctx, opts, nil); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := addWatches(watcher, opts.LocalDir, ".", opts.Ignore); err != nil {
+		return err
+	}
+
+	var poll <-chan time.Time
+	if opts.TwoWay {
+		interval := opts.Interval
+		if interval <= 0 {
+			interval = 2 * time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	changed := make(map[string]bool)
+	removed := make(map[string]bool)
+	pulled := make(map[string]time.Time)
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			rel, err := filepath.Rel(opts.LocalDir, ev.Name)
+			if err != nil || opts.Ignore.Ignored(rel) {
+				continue
+			}
+			if ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				removed[rel] = true
+				delete(changed, rel)
+			} else {
+				changed[rel] = true
+				delete(removed, rel)
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() && ev.Op&fsnotify.Create != 0 {
+					addWatches(watcher, opts.LocalDir, rel, opts.Ignore)
+				}
+			}
+			debounce.Reset(300 * time.Millisecond)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Printf("⚠️  Watch error: %v\n", err)
+
+		case <-debounce.C:
+			var push []string
+			for rel := range changed {
+				fi, err := os.Lstat(filepath.Join(opts.LocalDir, rel))
+				if err != nil {
+					continue
+				}
+				if mtime, ok := pulled[rel]; ok && mtime.Equal(fi.ModTime()) {
+					continue
+				}
+				push = append(push, rel)
+				fmt.Printf("⬆️  %s\n", rel)
+			}
+			if len(push) > 0 {
+				if err := e.PushTree(ctx, opts, push); err != nil {
+					fmt.Printf("❌ Push failed: %v\n", err)
+				}
+			}
+
+			if len(removed) > 0 {
+				rmCmd := []string{"rm", "-rf", "--"}
+				for rel := range removed {
+					rmCmd = append(rmCmd, path.Join(opts.ContainerPath, filepath.ToSlash(rel)))
+					fmt.Printf("🗑️  %s\n", rel)
+				}
+				if err := e.RunInSandbox(ctx, opts.Sandbox, rmCmd); err != nil {
+					fmt.Printf("❌ Delete failed: %v\n", err)
+				}
+			}
+			changed = make(map[string]bool)
+			removed = make(map[string]bool)
+
+		case <-poll:
+			if err := e.PullTree(ctx, opts, pulled); err != nil {
+				fmt.Printf("❌ Pull failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// addWatches registers rel and every non-ignored directory beneath it;
+// fsnotify does not watch recursively on its own.
+func addWatches(w *fsnotify.Watcher, root, rel string, ignore *IgnoreMatcher) error {
+	return filepath.Walk(filepath.Join(root, rel), func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		r, _ := filepath.Rel(root, p)
+		if ignore.Ignored(r) {
+			return filepath.SkipDir
+		}
+		return w.Add(p)
+	})
+}
@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+)
+
+// ImageID returns the ID of the local image ref.
+func (e *Dockerengine) ImageID(ctx context.Context, ref string) (string, error) {
+	img, err := e.Client.ImageInspect(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return img.ID, nil
+}
+
+// ImageDigest returns the registry digest of an image, or its ID when it
+// was never pulled from or pushed to a registry.
+func ImageDigest(img image.InspectResponse) string {
+	for _, rd := range img.RepoDigests {
+		if _, digest, ok := strings.Cut(rd, "@"); ok {
+			return digest
+		}
+	}
+	return img.ID
+}
+
+// RebuildOptions recovers how an image was built from the labels
+// BuildImageWith put on it. Build args are not recorded and must be passed
+// again.
+func RebuildOptions(labels map[string]string) (string, BuildOptions, error) {
+	source := labels["com.sbhub.source"]
+	if source == "" {
+		return "", BuildOptions{}, fmt.Errorf("image was not built by sb import (no com.sbhub.source label)")
+	}
+	opts := BuildOptions{Target: labels["com.sbhub.target"]}
+	if df := labels["com.sbhub.dockerfile"]; df != "" {
+		opts.Dockerfile = source + "/" + df
+	}
+	return source, opts, nil
+}
+
+// ownConfig returns the container config without the values it inherited
+// from its image (labels, env entries, command, entrypoint, working
+// directory and healthcheck), so a new image can supply its own.
+func ownConfig(config container.Config, image *dockerspec.DockerOCIImageConfig) container.Config {
+	if image == nil {
+		return config
+	}
+	labels := map[string]string{}
+	for k, v := range config.Labels {
+		if iv, ok := image.Labels[k]; !ok || iv != v {
+			labels[k] = v
+		}
+	}
+	config.Labels = labels
+
+	inherited := map[string]bool{}
+	for _, kv := range image.Env {
+		inherited[kv] = true
+	}
+	var env []string
+	for _, kv := range config.Env {
+		if !inherited[kv] {
+			env = append(env, kv)
+		}
+	}
+	config.Env = env
+
+	if reflect.DeepEqual([]string(config.Cmd), image.Cmd) {
+		config.Cmd = nil
+	}
+	if reflect.DeepEqual([]string(config.Entrypoint), image.Entrypoint) {
+		config.Entrypoint = nil
+	}
+	if config.WorkingDir == image.WorkingDir {
+		config.WorkingDir = ""
+	}
+	if config.Healthcheck != nil && image.Healthcheck != nil && reflect.DeepEqual(config.Healthcheck.Test, image.Healthcheck.Test) {
+		config.Healthcheck = nil
+	}
+	return config
+}
+
+// RecreateSandbox replaces the sandbox's container with one running image.
+// Binds, port mappings, env, labels and the remaining TTL are kept. If
+// the new container cannot be created, the old image is started again.
+func (e *Dockerengine) RecreateSandbox(ctx context.Context, name string, inspect container.InspectResponse, image string) (string, error) {
+	labels := e.EffectiveLabels(name, inspect.Config.Labels)
+	ttl := RemainingTTL(labels, time.Hour)
+	size := labels["com.sbhub.size"]
+
+	config := *inspect.Config
+	if old, err := e.Client.ImageInspect(ctx, inspect.Image); err == nil {
+		config = ownConfig(config, old.Config)
+	}
+	config.Image = image
+
+	e.KeepCreated(name, inspect)
+	if err := e.RemoveSandbox(ctx, name, "", false); err != nil {
+		return "", err
+	}
+	id, err := e.CreateSandbox(ctx, name, ttl, size, &config, inspect.HostConfig)
+	if err == nil {
+		return id, nil
+	}
+
+	e.RemoveSandbox(ctx, name, "", false)
+	previous := *inspect.Config
+	previous.Image = inspect.Image
+	if _, rerr := e.CreateSandbox(ctx, name, ttl, size, &previous, inspect.HostConfig); rerr != nil {
+		return "", fmt.Errorf("%v (restoring the old container also failed: %v)", err, rerr)
+	}
+	return "", fmt.Errorf("%v (the old container was restored)", err)
+}
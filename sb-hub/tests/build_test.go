@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types"
+)
+
+func buildProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, body := range map[string]string{
+		"Dockerfile":            "FROM alpine\n",
+		"docker/Dockerfile.dev": "FROM alpine AS dev\n",
+		"main.go":               "package main\n",
+		"node_modules/left/pad": "module\n",
+		".git/HEAD":             "ref: refs/heads/main\n",
+		".dockerignore":         "node_modules\n.git\n",
+	} {
+		full := filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseBuildArgs(t *testing.T) {
+	t.Setenv("SB_BUILD_TOKEN", "secret")
+	args, err := pkg.ParseBuildArgs([]string{"VERSION=1.2", "SB_BUILD_TOKEN", "SB_UNSET_ARG"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *args["VERSION"] != "1.2" || *args["SB_BUILD_TOKEN"] != "secret" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	if _, ok := args["SB_UNSET_ARG"]; ok {
+		t.Fatal("expected unset pass-through arg to be dropped")
+	}
+	if _, err := pkg.ParseBuildArgs([]string{"=x"}); err == nil {
+		t.Fatal("expected error for an empty name")
+	}
+}
+
+func TestContextHash_IgnoresExcludedFilesAndTimestamps(t *testing.T) {
+	dir := buildProject(t)
+	excludes, err := pkg.ReadDockerignore(dir, "Dockerfile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before, _ := pkg.ContextHash(dir, excludes)
+
+	os.WriteFile(filepath.Join(dir, "node_modules/left/pad"), []byte("changed\n"), 0644)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "main.go"), future, future)
+	if after, _ := pkg.ContextHash(dir, excludes); after != before {
+		t.Fatal("expected ignored files and timestamps not to change the hash")
+	}
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // v2\n"), 0644)
+	if after, _ := pkg.ContextHash(dir, excludes); after == before {
+		t.Fatal("expected a source change to change the hash")
+	}
+}
+
+func TestBuildImageWith_OptionsLabelsAndDockerignore(t *testing.T) {
+	dir := buildProject(t)
+	var got types.ImageBuildOptions
+	var files []string
+	mock := &MockDockerClient{
+		ImageBuildFn: func(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+			got = options
+			tr := tar.NewReader(buildContext)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					break
+				}
+				files = append(files, hdr.Name)
+			}
+			return types.ImageBuildResponse{Body: io.NopCloser(strings.NewReader(`{"stream":"Successfully built abc\n"}`))}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	v := "1.2"
+	var out bytes.Buffer
+	err := engine.BuildImageWith(context.Background(), dir, "sb-local-app", pkg.BuildOptions{
+		Dockerfile: filepath.Join(dir, "docker/Dockerfile.dev"),
+		BuildArgs:  map[string]*string{"VERSION": &v},
+		Target:     "dev",
+		NoCache:    true,
+		Out:        &out,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Dockerfile != "docker/Dockerfile.dev" || got.Target != "dev" || !got.NoCache || *got.BuildArgs["VERSION"] != "1.2" {
+		t.Fatalf("unexpected build options: %+v", got)
+	}
+	if got.Labels["com.sbhub.source"] != dir || len(got.Labels["com.sbhub.context-hash"]) != 64 {
+		t.Fatalf("unexpected labels: %v", got.Labels)
+	}
+	joined := strings.Join(files, " ")
+	if strings.Contains(joined, "node_modules") || strings.Contains(joined, ".git/") || !strings.Contains(joined, "main.go") {
+		t.Fatalf("expected .dockerignore to be honoured, sent %v", files)
+	}
+	if !strings.Contains(out.String(), "Successfully built abc") {
+		t.Fatalf("expected build log, got %q", out.String())
+	}
+}
+
+func TestBuildImageWith_StreamError(t *testing.T) {
+	dir := buildProject(t)
+	mock := &MockDockerClient{
+		ImageBuildFn: func(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+			io.Copy(io.Discard, buildContext)
+			stream := `{"stream":"Step 1/2 : FROM alpine\n"}
+{"errorDetail":{"message":"The command '/bin/sh -c make' returned a non-zero code: 2"},"error":"The command '/bin/sh -c make' returned a non-zero code: 2"}
+`
+			return types.ImageBuildResponse{Body: io.NopCloser(strings.NewReader(stream))}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	err := engine.BuildImageWith(context.Background(), dir, "sb-local-app", pkg.BuildOptions{Out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "non-zero code: 2") {
+		t.Fatalf("expected the build error from the stream, got %v", err)
+	}
+}
+
+func TestBuildImageWith_DockerfileOutsideContext(t *testing.T) {
+	dir := buildProject(t)
+	outside := filepath.Join(t.TempDir(), "Dockerfile")
+	os.WriteFile(outside, []byte("FROM alpine\n"), 0644)
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}}
+
+	err := engine.BuildImageWith(context.Background(), dir, "tag", pkg.BuildOptions{Dockerfile: outside, Out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "outside the build context") {
+		t.Fatalf("expected outside-context error, got %v", err)
+	}
+}
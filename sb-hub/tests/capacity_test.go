@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/system"
+)
+
+func capacityMock() *MockDockerClient {
+	return &MockDockerClient{
+		InfoFn: func(ctx context.Context) (system.Info, error) {
+			return system.Info{NCPU: 4, MemTotal: 16 << 30}, nil
+		},
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			managed := func(size string) map[string]string {
+				return map[string]string{"com.sbhub.managed": "true", "com.sbhub.size": size}
+			}
+			return []container.Summary{
+				{Names: []string{"/busy"}, State: "running", Labels: managed("medium")},
+				{Names: []string{"/box"}, State: "running", Labels: managed("small")},
+				{Names: []string{"/idle"}, State: "exited", Labels: managed("large")},
+				{Names: []string{"/other"}, State: "running", Labels: map[string]string{"com.sbhub.size": "xlarge"}},
+			}, nil
+		},
+	}
+}
+
+func TestCapacity_CommittedTotals(t *testing.T) {
+	engine := &pkg.Dockerengine{Client: capacityMock()}
+
+	report, err := engine.Capacity(context.Background(), "", pkg.CapacityPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Sandboxes != 3 || report.Overcommit != 1 {
+		t.Fatalf("expected 3 managed sandboxes at ratio 1, got %+v", report)
+	}
+	// CPU and memory only count running sandboxes; disk counts all of them
+	if report.Committed.CPUs != 2.5 || report.Committed.MemoryMB != 4608 || report.Committed.DiskGB != 70 {
+		t.Fatalf("unexpected committed resources: %+v", report.Committed)
+	}
+	cpus, mem, _ := report.Headroom()
+	if cpus != 1.5 || mem != 16384-4608 {
+		t.Fatalf("unexpected headroom: %.1f cores, %d MB", cpus, mem)
+	}
+}
+
+func TestCheckCapacity_Overcommit(t *testing.T) {
+	engine := &pkg.Dockerengine{Client: capacityMock()}
+	ctx := context.Background()
+
+	if err := engine.CheckCapacity(ctx, "box", "medium", pkg.CapacityPolicy{}); err != nil {
+		t.Fatalf("expected medium to fit when replacing box, got %v", err)
+	}
+	err := engine.CheckCapacity(ctx, "new", "large", pkg.CapacityPolicy{})
+	if err == nil || !strings.Contains(err.Error(), "CPU") {
+		t.Fatalf("expected CPU shortfall for large, got %v", err)
+	}
+	if err := engine.CheckCapacity(ctx, "new", "large", pkg.CapacityPolicy{Overcommit: 2}); err != nil {
+		t.Fatalf("expected large to fit with 2x overcommit, got %v", err)
+	}
+}
+
+func TestCapacityReport_DiskLimit(t *testing.T) {
+	report := pkg.CapacityReport{
+		Host:       pkg.Resources{CPUs: 64, MemoryMB: 1 << 20, DiskGB: 45},
+		Committed:  pkg.Resources{DiskGB: 30},
+		Overcommit: 1,
+	}
+	if err := report.Admit("medium"); err == nil || !strings.Contains(err.Error(), "disk") {
+		t.Fatalf("expected disk shortfall, got %v", err)
+	}
+	if err := report.Admit("small"); err != nil {
+		t.Fatalf("expected small to fit, got %v", err)
+	}
+}
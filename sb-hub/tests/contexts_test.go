@@ -0,0 +1,245 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/system"
+)
+
+func TestEndpoint_Validate(t *testing.T) {
+	cases := []struct {
+		ep      pkg.Endpoint
+		wantErr bool
+	}{
+		{pkg.Endpoint{}, false},
+		{pkg.Endpoint{Host: "unix:///var/run/docker.sock"}, false},
+		{pkg.Endpoint{Host: "tcp://build01:2376", TLS: &pkg.TLSConfig{CA: "ca.pem"}}, false},
+		{pkg.Endpoint{Host: "ssh://owen@build02"}, false},
+		{pkg.Endpoint{Host: "http://build01:2375"}, true},
+		{pkg.Endpoint{Host: "ssh://owen@build02", TLS: &pkg.TLSConfig{CA: "ca.pem"}}, true},
+	}
+	for _, c := range cases {
+		if err := c.ep.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%s: unexpected error %v", c.ep.Host, err)
+		}
+	}
+}
+
+func TestEndpoint_Local(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	cases := []struct {
+		ep   pkg.Endpoint
+		want bool
+	}{
+		{pkg.Endpoint{}, true},
+		{pkg.Endpoint{Host: "unix:///var/run/docker.sock"}, true},
+		{pkg.Endpoint{Host: "tcp://build01:2376"}, false},
+		{pkg.Endpoint{Host: "ssh://owen@build02"}, false},
+	}
+	for _, c := range cases {
+		if got := c.ep.Local(); got != c.want {
+			t.Errorf("%q: Local() = %v, want %v", c.ep.Host, got, c.want)
+		}
+	}
+
+	for host, want := range map[string]string{
+		"":                            "localhost",
+		"unix:///var/run/docker.sock": "localhost",
+		"tcp://build01:2376":          "build01",
+		"ssh://owen@gpu01":            "gpu01",
+	} {
+		if got := (pkg.Endpoint{Host: host}).PublishedHost(); got != want {
+			t.Errorf("%q: PublishedHost() = %s, want %s", host, got, want)
+		}
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://build01:2376")
+	if (pkg.Endpoint{}).Local() {
+		t.Error("empty host should follow a remote DOCKER_HOST")
+	}
+}
+
+func TestConfig_Contexts(t *testing.T) {
+	cfg := &pkg.Config{Contexts: map[string]pkg.Endpoint{
+		"gpu":   {Host: "ssh://gpu01"},
+		"build": {Host: "tcp://build01:2376"},
+	}}
+	if names := cfg.ContextNames(); !reflect.DeepEqual(names, []string{"build", "default", "gpu"}) {
+		t.Fatalf("unexpected context names: %v", names)
+	}
+	if ep, err := cfg.Endpoint("gpu"); err != nil || ep.Host != "ssh://gpu01" {
+		t.Fatalf("unexpected endpoint: %+v (%v)", ep, err)
+	}
+	if ep, err := cfg.Endpoint(pkg.DefaultContext); err != nil || ep.Host != "" {
+		t.Fatalf("expected the environment for default, got %+v (%v)", ep, err)
+	}
+	if _, err := cfg.Endpoint("laptop"); err == nil || !strings.Contains(err.Error(), "build, default, gpu") {
+		t.Fatalf("expected unknown context error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Contexts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("context: build\ncontexts:\n  build:\n    host: tcp://build01:2376\n    tls:\n      ca: ~/.sb-hub/ca.pem\n"), 0644)
+	cfg, err := pkg.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Context != "build" || cfg.Contexts["build"].TLS == nil || cfg.Contexts["build"].TLS.CA != "~/.sb-hub/ca.pem" {
+		t.Fatalf("unexpected contexts: %+v", cfg)
+	}
+
+	os.WriteFile(path, []byte("context: gpu\ncontexts:\n  build:\n    host: tcp://build01:2376\n"), 0644)
+	if _, err := pkg.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "gpu") {
+		t.Fatalf("expected unknown default context error, got %v", err)
+	}
+
+	os.WriteFile(path, []byte("contexts:\n  build:\n    host: http://build01\n"), 0644)
+	if _, err := pkg.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "build") {
+		t.Fatalf("expected invalid host error, got %v", err)
+	}
+}
+
+func TestSSHCommand(t *testing.T) {
+	u, _ := url.Parse("ssh://owen@gpu01:2222")
+	want := []string{"-l", "owen", "-p", "2222", "--", "gpu01", "docker", "system", "dial-stdio"}
+	if got := pkg.SSHCommand(u); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected ssh args: %v", got)
+	}
+	u, _ = url.Parse("ssh://gpu01")
+	if got := pkg.SSHCommand(u); !reflect.DeepEqual(got, want[4:]) {
+		t.Fatalf("unexpected ssh args: %v", got)
+	}
+}
+
+func TestNewDockerClient_Host(t *testing.T) {
+	cli, err := pkg.NewDockerClient(pkg.Endpoint{Host: "tcp://build01:2375"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cli.Close()
+	if cli.DaemonHost() != "tcp://build01:2375" {
+		t.Fatalf("unexpected daemon host: %s", cli.DaemonHost())
+	}
+}
+
+// hostMock is a Docker host with memGB of memory running one small
+// sandbox.
+func hostMock(cpus int, memGB int64) *MockDockerClient {
+	return &MockDockerClient{
+		InfoFn: func(ctx context.Context) (system.Info, error) {
+			return system.Info{NCPU: cpus, MemTotal: memGB << 30}, nil
+		},
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			return []container.Summary{{
+				Names:  []string{"/box"},
+				State:  "running",
+				Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.size": "small"},
+			}}, nil
+		},
+	}
+}
+
+func TestPlaceSandbox_MostFreeMemory(t *testing.T) {
+	engines := map[string]*pkg.Dockerengine{
+		"default": {Client: hostMock(8, 8)},
+		"build":   {Client: hostMock(8, 32)},
+		"tiny":    {Client: hostMock(1, 64)},
+	}
+	chosen, placements, err := pkg.PlaceSandbox(context.Background(), engines, "medium", pkg.CapacityPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// tiny has the most memory but not enough CPU for a medium sandbox
+	if chosen != "build" {
+		t.Fatalf("expected build, got %s", chosen)
+	}
+	if len(placements) != 3 || placements[0].Context != "tiny" || placements[0].Err == nil {
+		t.Fatalf("unexpected placements: %+v", placements)
+	}
+}
+
+func TestPlaceSandbox_NoneFits(t *testing.T) {
+	engines := map[string]*pkg.Dockerengine{
+		"default": {Client: hostMock(1, 2)},
+		"build":   {Client: hostMock(1, 4)},
+	}
+	_, _, err := pkg.PlaceSandbox(context.Background(), engines, "large", pkg.CapacityPolicy{})
+	if err == nil || !strings.Contains(err.Error(), "build:") || !strings.Contains(err.Error(), "default:") {
+		t.Fatalf("expected every endpoint in the error, got %v", err)
+	}
+}
+
+// ownedMock lists one managed sandbox of owner with the given name and size.
+func ownedMock(name, owner, size string) *MockDockerClient {
+	return &MockDockerClient{
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			if f := options.Filters.Get("name"); len(f) > 0 && f[0] != "^/"+name+"$" {
+				return nil, nil
+			}
+			return []container.Summary{{
+				Names:  []string{"/" + name},
+				State:  "running",
+				Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.owner": owner, "com.sbhub.size": size},
+			}}, nil
+		},
+	}
+}
+
+func TestTotalOwnerUsage_AcrossContexts(t *testing.T) {
+	engines := map[string]*pkg.Dockerengine{
+		"default": {Client: ownedMock("api", "owen", "small")},
+		"build":   {Client: ownedMock("ci", "owen", "medium")},
+		"gpu": {Client: &MockDockerClient{
+			ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+				return nil, errors.New("connection refused")
+			},
+		}},
+	}
+	usage, unreachable := pkg.TotalOwnerUsage(context.Background(), engines, "owen", "")
+	if usage.Sandboxes != 2 || usage.CPUs != 2.5 || usage.MemoryMB != 4608 {
+		t.Fatalf("expected usage summed over both hosts, got %+v", usage)
+	}
+	if len(unreachable) != 1 || unreachable[0] != "gpu" {
+		t.Fatalf("expected gpu to be reported unreachable, got %v", unreachable)
+	}
+	if usage, _ := pkg.TotalOwnerUsage(context.Background(), engines, "owen", "ci"); usage.Sandboxes != 1 {
+		t.Fatalf("expected the excluded sandbox to be left out, got %+v", usage)
+	}
+}
+
+func TestSandboxContexts(t *testing.T) {
+	engines := map[string]*pkg.Dockerengine{
+		"default": {Client: ownedMock("api", "owen", "small")},
+		"build":   {Client: ownedMock("ci", "owen", "small")},
+	}
+	if got := pkg.SandboxContexts(context.Background(), engines, "ci"); len(got) != 1 || got[0] != "build" {
+		t.Fatalf("expected ci on build, got %v", got)
+	}
+	if got := pkg.SandboxContexts(context.Background(), engines, "web"); len(got) != 0 {
+		t.Fatalf("expected no context for web, got %v", got)
+	}
+}
+
+func TestMetaStore_ContextSurvivesClearLabels(t *testing.T) {
+	meta := pkg.MetaStoreFor(t.TempDir())
+	meta.SetLabels("box", map[string]string{"com.sbhub.size": "large"})
+	meta.SetContext("box", "build")
+	if err := meta.ClearLabels("box"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, _ := meta.Load("box")
+	if m.Context != "build" || len(m.Labels) != 0 {
+		t.Fatalf("expected only the context to remain, got %+v", m)
+	}
+}
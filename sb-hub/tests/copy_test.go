@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestParseCopyRef(t *testing.T) {
+	cases := map[string]pkg.CopyRef{
+		"box:/var/log":   {Sandbox: "box", Path: "/var/log"},
+		"./local":        {Path: "./local"},
+		"/tmp/a:b":       {Path: "/tmp/a:b"},
+		"relative/x:y/z": {Path: "relative/x:y/z"},
+	}
+	for arg, want := range cases {
+		if got := pkg.ParseCopyRef(arg); got != want {
+			t.Errorf("%s: expected %+v, got %+v", arg, want, got)
+		}
+	}
+}
+
+func TestCopyIn_IntoExistingDirectory(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "config")
+	os.MkdirAll(src, 0755)
+	os.WriteFile(filepath.Join(src, "app.yaml"), []byte("a: 1"), 0600)
+
+	var names []string
+	var modes []int64
+	mock := &MockDockerClient{
+		ContainerStatPathFn: func(ctx context.Context, containerID, path string) (container.PathStat, error) {
+			return container.PathStat{Name: "app", Mode: os.ModeDir | 0755}, nil
+		},
+		CopyToContainerFn: func(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+			if !options.CopyUIDGID {
+				t.Fatal("expected CopyUIDGID when preserving owner")
+			}
+			tr := tar.NewReader(content)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					break
+				}
+				names = append(names, hdr.Name)
+				modes = append(modes, hdr.Mode)
+			}
+			return nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	err := engine.CopyIn(context.Background(), src, pkg.CopyRef{Sandbox: "box", Path: "/etc/app"}, pkg.CopyOptions{PreserveOwner: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 2 || names[0] != "etc/app/config/" || names[1] != "etc/app/config/app.yaml" {
+		t.Fatalf("unexpected entries: %v", names)
+	}
+	if modes[1]&0777 != 0600 {
+		t.Fatalf("expected file mode 0600 to be preserved, got %o", modes[1])
+	}
+}
+
+func TestCopyIn_RenamesWhenDestinationMissing(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(src, []byte("hi"), 0644)
+
+	var names []string
+	mock := &MockDockerClient{
+		CopyToContainerFn: func(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+			names = tarNames(t, content)
+			return nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	err := engine.CopyIn(context.Background(), src, pkg.CopyRef{Sandbox: "box", Path: "/tmp/renamed.txt"}, pkg.CopyOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "tmp/renamed.txt" {
+		t.Fatalf("unexpected entries: %v", names)
+	}
+}
+
+func TestCopyIn_RelativeSandboxPath(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(src, []byte("hi"), 0644)
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}}
+
+	if err := engine.CopyIn(context.Background(), src, pkg.CopyRef{Sandbox: "box", Path: "tmp"}, pkg.CopyOptions{}); err == nil {
+		t.Fatal("expected error for relative sandbox path")
+	}
+}
+
+func TestCopyOut_DirectoryWithSymlink(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0750})
+	tw.WriteHeader(&tar.Header{Name: "logs/app.log", Typeflag: tar.TypeReg, Mode: 0640, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.WriteHeader(&tar.Header{Name: "logs/latest", Typeflag: tar.TypeSymlink, Linkname: "app.log"})
+	tw.Close()
+
+	mock := &MockDockerClient{
+		CopyFromContainerFn: func(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+			return io.NopCloser(&buf), container.PathStat{Name: "logs", Mode: os.ModeDir | 0750}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	dst := t.TempDir()
+	err := engine.CopyOut(context.Background(), pkg.CopyRef{Sandbox: "box", Path: "/var/logs"}, dst, pkg.CopyOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "logs", "app.log"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("expected copied file, got '%s' (%v)", data, err)
+	}
+	fi, _ := os.Stat(filepath.Join(dst, "logs", "app.log"))
+	if fi.Mode().Perm() != 0640 {
+		t.Fatalf("expected mode 0640, got %o", fi.Mode().Perm())
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "logs", "latest")); err != nil || link != "app.log" {
+		t.Fatalf("expected symlink to app.log, got '%s' (%v)", link, err)
+	}
+}
+
+func TestCopyOut_RejectsHostileArchive(t *testing.T) {
+	outside := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "logs/a", Typeflag: tar.TypeSymlink, Linkname: outside})
+	tw.WriteHeader(&tar.Header{Name: "logs/a/x", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("pwned"))
+	tw.Close()
+
+	mock := &MockDockerClient{
+		CopyFromContainerFn: func(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+			return io.NopCloser(&buf), container.PathStat{Name: "logs", Mode: os.ModeDir | 0755}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	err := engine.CopyOut(context.Background(), pkg.CopyRef{Sandbox: "box", Path: "/var/logs"}, t.TempDir(), pkg.CopyOptions{})
+	if err == nil {
+		t.Fatal("expected error for an archive escaping through a symlink")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written outside the destination, got %v", err)
+	}
+}
+
+func TestCopyOut_FollowLink(t *testing.T) {
+	var requested []string
+	mock := &MockDockerClient{
+		CopyFromContainerFn: func(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+			requested = append(requested, srcPath)
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			if srcPath == "/current" {
+				tw.WriteHeader(&tar.Header{Name: "current", Typeflag: tar.TypeSymlink, Linkname: "/releases/v2"})
+				tw.Close()
+				return io.NopCloser(&buf), container.PathStat{Name: "current", Mode: os.ModeSymlink, LinkTarget: "/releases/v2"}, nil
+			}
+			tw.WriteHeader(&tar.Header{Name: "v2", Typeflag: tar.TypeReg, Mode: 0644, Size: 2})
+			tw.Write([]byte("v2"))
+			tw.Close()
+			return io.NopCloser(&buf), container.PathStat{Name: "v2", Mode: 0644, Size: 2}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	dst := t.TempDir()
+	err := engine.CopyOut(context.Background(), pkg.CopyRef{Sandbox: "box", Path: "/current"}, dst, pkg.CopyOptions{FollowLink: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requested) != 2 || requested[1] != "/releases/v2" {
+		t.Fatalf("expected link target to be fetched, got %v", requested)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "current")); string(data) != "v2" {
+		t.Fatalf("expected link target contents under the link name, got '%s'", data)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	cases := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range cases {
+		if got := pkg.HumanBytes(n); got != want {
+			t.Errorf("%d: expected '%s', got '%s'", n, want, got)
+		}
+	}
+}
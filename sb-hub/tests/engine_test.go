@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"regexp"
 	"strings"
@@ -13,9 +14,14 @@ import (
 
 	"github.com/NjariaOwen/sb-hub/pkg"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
@@ -25,18 +31,38 @@ import (
 // ---------------------------------------------------------------------------
 
 type MockDockerClient struct {
-	PingFn             func(ctx context.Context) (types.Ping, error)
-	ContainerListFn    func(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
-	ContainerCreateFn  func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
-	ContainerStartFn   func(ctx context.Context, containerID string, options container.StartOptions) error
-	ContainerStopFn    func(ctx context.Context, containerID string, options container.StopOptions) error
-	ContainerRemoveFn  func(ctx context.Context, containerID string, options container.RemoveOptions) error
-	ContainerInspectFn func(ctx context.Context, containerID string) (container.InspectResponse, error)
-	ContainerLogsFn    func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
-	ImagePullFn        func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
-	ImageBuildFn       func(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
-	NetworkInspectFn   func(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
-	NetworkCreateFn    func(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error)
+	PingFn                 func(ctx context.Context) (types.Ping, error)
+	ContainerListFn        func(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerCreateFn      func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStartFn       func(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerStopFn        func(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerRemoveFn      func(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ContainerInspectFn     func(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerLogsFn        func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	ImagePullFn            func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+	ImageInspectFn         func(ctx context.Context, imageID string) (image.InspectResponse, error)
+	ImageBuildFn           func(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
+	ImageListFn            func(ctx context.Context, options image.ListOptions) ([]image.Summary, error)
+	ImageLoadFn            func(ctx context.Context, input io.Reader) (image.LoadResponse, error)
+	ImageSaveFn            func(ctx context.Context, imageIDs []string) (io.ReadCloser, error)
+	ImageRemoveFn          func(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error)
+	BuildCachePruneFn      func(ctx context.Context, opts build.CachePruneOptions) (*build.CachePruneReport, error)
+	NetworkInspectFn       func(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+	NetworkCreateFn        func(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error)
+	VolumeCreateFn         func(ctx context.Context, options volume.CreateOptions) (volume.Volume, error)
+	VolumeRemoveFn         func(ctx context.Context, volumeID string, force bool) error
+	ContainerExecCreateFn  func(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error)
+	ContainerExecStartFn   func(ctx context.Context, execID string, config container.ExecStartOptions) error
+	ContainerExecInspectFn func(ctx context.Context, execID string) (container.ExecInspect, error)
+	CopyToContainerFn      func(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error
+	CopyFromContainerFn    func(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error)
+	ContainerStatPathFn    func(ctx context.Context, containerID, path string) (container.PathStat, error)
+	ContainerExecAttachFn  func(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecResizeFn  func(ctx context.Context, execID string, options container.ResizeOptions) error
+	ContainerStatsFn       func(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error)
+	ContainerUpdateFn      func(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.UpdateResponse, error)
+	InfoFn                 func(ctx context.Context) (system.Info, error)
+	RegistryLoginFn        func(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error)
 }
 
 func (m *MockDockerClient) Ping(ctx context.Context) (types.Ping, error) {
@@ -102,6 +128,13 @@ func (m *MockDockerClient) ImagePull(ctx context.Context, refStr string, options
 	return io.NopCloser(strings.NewReader("")), nil
 }
 
+func (m *MockDockerClient) ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	if m.ImageInspectFn != nil {
+		return m.ImageInspectFn(ctx, imageID)
+	}
+	return image.InspectResponse{}, fmt.Errorf("No such image: %s", imageID)
+}
+
 func (m *MockDockerClient) ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
 	if m.ImageBuildFn != nil {
 		return m.ImageBuildFn(ctx, buildContext, options)
@@ -123,6 +156,142 @@ func (m *MockDockerClient) NetworkCreate(ctx context.Context, name string, optio
 	return network.CreateResponse{}, nil
 }
 
+func (m *MockDockerClient) VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error) {
+	if m.VolumeCreateFn != nil {
+		return m.VolumeCreateFn(ctx, options)
+	}
+	return volume.Volume{Name: options.Name}, nil
+}
+
+func (m *MockDockerClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	if m.VolumeRemoveFn != nil {
+		return m.VolumeRemoveFn(ctx, volumeID, force)
+	}
+	return nil
+}
+
+func (m *MockDockerClient) ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+	if m.ContainerExecCreateFn != nil {
+		return m.ContainerExecCreateFn(ctx, containerID, options)
+	}
+	return container.ExecCreateResponse{ID: "mock-exec-id"}, nil
+}
+
+func (m *MockDockerClient) ContainerExecStart(ctx context.Context, execID string, config container.ExecStartOptions) error {
+	if m.ContainerExecStartFn != nil {
+		return m.ContainerExecStartFn(ctx, execID, config)
+	}
+	return nil
+}
+
+func (m *MockDockerClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	if m.ContainerExecInspectFn != nil {
+		return m.ContainerExecInspectFn(ctx, execID)
+	}
+	return container.ExecInspect{ExecID: execID}, nil
+}
+
+func (m *MockDockerClient) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+	if m.CopyToContainerFn != nil {
+		return m.CopyToContainerFn(ctx, containerID, dstPath, content, options)
+	}
+	_, err := io.Copy(io.Discard, content)
+	return err
+}
+
+func (m *MockDockerClient) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	if m.CopyFromContainerFn != nil {
+		return m.CopyFromContainerFn(ctx, containerID, srcPath)
+	}
+	return io.NopCloser(strings.NewReader("")), container.PathStat{}, nil
+}
+
+func (m *MockDockerClient) ContainerStatPath(ctx context.Context, containerID, path string) (container.PathStat, error) {
+	if m.ContainerStatPathFn != nil {
+		return m.ContainerStatPathFn(ctx, containerID, path)
+	}
+	return container.PathStat{}, errors.New("No such container:path")
+}
+
+func (m *MockDockerClient) ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+	if m.ContainerExecAttachFn != nil {
+		return m.ContainerExecAttachFn(ctx, execID, config)
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return types.NewHijackedResponse(client, ""), nil
+}
+
+func (m *MockDockerClient) ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error) {
+	if m.ContainerStatsFn != nil {
+		return m.ContainerStatsFn(ctx, containerID, stream)
+	}
+	return container.StatsResponseReader{Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func (m *MockDockerClient) ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.UpdateResponse, error) {
+	if m.ContainerUpdateFn != nil {
+		return m.ContainerUpdateFn(ctx, containerID, updateConfig)
+	}
+	return container.UpdateResponse{}, nil
+}
+
+func (m *MockDockerClient) ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+	if m.ImageListFn != nil {
+		return m.ImageListFn(ctx, options)
+	}
+	return nil, nil
+}
+
+func (m *MockDockerClient) ImageLoad(ctx context.Context, input io.Reader, loadOpts ...client.ImageLoadOption) (image.LoadResponse, error) {
+	if m.ImageLoadFn != nil {
+		return m.ImageLoadFn(ctx, input)
+	}
+	return image.LoadResponse{Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func (m *MockDockerClient) ImageSave(ctx context.Context, imageIDs []string, saveOpts ...client.ImageSaveOption) (io.ReadCloser, error) {
+	if m.ImageSaveFn != nil {
+		return m.ImageSaveFn(ctx, imageIDs)
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (m *MockDockerClient) ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+	if m.ImageRemoveFn != nil {
+		return m.ImageRemoveFn(ctx, imageID, options)
+	}
+	return []image.DeleteResponse{{Deleted: imageID}}, nil
+}
+
+func (m *MockDockerClient) BuildCachePrune(ctx context.Context, opts build.CachePruneOptions) (*build.CachePruneReport, error) {
+	if m.BuildCachePruneFn != nil {
+		return m.BuildCachePruneFn(ctx, opts)
+	}
+	return &build.CachePruneReport{}, nil
+}
+
+func (m *MockDockerClient) RegistryLogin(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error) {
+	if m.RegistryLoginFn != nil {
+		return m.RegistryLoginFn(ctx, auth)
+	}
+	return registry.AuthenticateOKBody{Status: "Login Succeeded"}, nil
+}
+
+func (m *MockDockerClient) Info(ctx context.Context) (system.Info, error) {
+	if m.InfoFn != nil {
+		return m.InfoFn(ctx)
+	}
+	return system.Info{NCPU: 8, MemTotal: 32 << 30}, nil
+}
+
+func (m *MockDockerClient) ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error {
+	if m.ContainerExecResizeFn != nil {
+		return m.ContainerExecResizeFn(ctx, execID, options)
+	}
+	return nil
+}
+
 // ---------------------------------------------------------------------------
 // Tests: Ping
 // ---------------------------------------------------------------------------
@@ -222,7 +391,7 @@ func TestEnsureImage_Success(t *testing.T) {
 			if refStr != "alpine:latest" {
 				t.Fatalf("expected image 'alpine:latest', got '%s'", refStr)
 			}
-			return io.NopCloser(strings.NewReader("pulling...")), nil
+			return io.NopCloser(strings.NewReader(`{"status":"Pulling from library/alpine","id":"latest"}`)), nil
 		},
 	}
 	engine := &pkg.Dockerengine{Client: mock}
@@ -725,6 +894,77 @@ func TestInspectSandbox_NotFound(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Tests: StartSandbox / WaitReady
+// ---------------------------------------------------------------------------
+
+func TestStartSandbox_WaitsForHealthy(t *testing.T) {
+	started := false
+	polls := 0
+	mock := &MockDockerClient{
+		ContainerStartFn: func(ctx context.Context, containerID string, options container.StartOptions) error {
+			started = true
+			return nil
+		},
+		ContainerInspectFn: func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			polls++
+			health := container.Starting
+			if polls > 1 {
+				health = container.Healthy
+			}
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					State: &container.State{Running: true, Status: "running", Health: &container.Health{Status: health}},
+				},
+			}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	if err := engine.StartSandbox(context.Background(), "box", 5*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !started || polls != 2 {
+		t.Fatalf("expected start and two polls, got started=%v polls=%d", started, polls)
+	}
+}
+
+func TestWaitReady_ExitedContainer(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerInspectFn: func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					State: &container.State{Status: "exited", ExitCode: 1},
+				},
+			}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	err := engine.WaitReady(context.Background(), "box", 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Fatalf("expected exited error, got %v", err)
+	}
+}
+
+func TestWaitReady_Timeout(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerInspectFn: func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					State: &container.State{Running: true, Status: "running", Health: &container.Health{Status: container.Starting}},
+				},
+			}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	err := engine.WaitReady(context.Background(), "box", 300*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Tests: GenerateRandomName
 // ---------------------------------------------------------------------------
@@ -769,7 +1009,7 @@ func TestBuildImage_Success(t *testing.T) {
 			if options.Dockerfile != "Dockerfile" {
 				t.Fatalf("expected Dockerfile, got '%s'", options.Dockerfile)
 			}
-			return types.ImageBuildResponse{Body: io.NopCloser(strings.NewReader("build output"))}, nil
+			return types.ImageBuildResponse{Body: io.NopCloser(strings.NewReader(`{"stream":"Step 1/1 : FROM alpine:latest\n"}`))}, nil
 		},
 	}
 	engine := &pkg.Dockerengine{Client: mock}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/cmd"
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/spf13/pflag"
+)
+
+func TestExec_DemuxesOutputAndReturnsExitCode(t *testing.T) {
+	var created container.ExecOptions
+	mock := &MockDockerClient{
+		ContainerExecCreateFn: func(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+			created = options
+			return container.ExecCreateResponse{ID: "exec-1"}, nil
+		},
+		ContainerExecAttachFn: func(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+			client, server := net.Pipe()
+			go func() {
+				stdcopy.NewStdWriter(server, stdcopy.Stdout).Write([]byte("out\n"))
+				stdcopy.NewStdWriter(server, stdcopy.Stderr).Write([]byte("err\n"))
+				server.Close()
+			}()
+			return types.NewHijackedResponse(client, ""), nil
+		},
+		ContainerExecInspectFn: func(ctx context.Context, execID string) (container.ExecInspect, error) {
+			return container.ExecInspect{ExecID: execID, ExitCode: 3}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var stdout, stderr bytes.Buffer
+	code, err := engine.Exec(context.Background(), "box", pkg.ExecRequest{
+		Cmd:    []string{"ls", "/data"},
+		User:   "app",
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 3 {
+		t.Fatalf("expected exit code 3, got %d", code)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Fatalf("expected demuxed streams, got stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+	if created.User != "app" || created.AttachStdin || created.Tty {
+		t.Fatalf("unexpected exec options: %+v", created)
+	}
+}
+
+func TestExec_TtyForwardsStdinAndResizes(t *testing.T) {
+	resized := make(chan container.ResizeOptions, 1)
+	received := make(chan string, 1)
+	mock := &MockDockerClient{
+		ContainerExecAttachFn: func(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+			if !config.Tty {
+				t.Error("expected a TTY attach")
+			}
+			client, server := net.Pipe()
+			go func() {
+				buf := make([]byte, 64)
+				n, _ := server.Read(buf)
+				received <- string(buf[:n])
+				server.Write([]byte("$ "))
+				server.Close()
+			}()
+			return types.NewHijackedResponse(client, ""), nil
+		},
+		ContainerExecResizeFn: func(ctx context.Context, execID string, options container.ResizeOptions) error {
+			resized <- options
+			return nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	sizes := make(chan pkg.TermSize, 1)
+	sizes <- pkg.TermSize{Height: 40, Width: 120}
+	close(sizes)
+
+	var stdout bytes.Buffer
+	_, err := engine.Exec(context.Background(), "box", pkg.ExecRequest{
+		Cmd:    []string{"/bin/sh"},
+		Tty:    true,
+		Stdin:  strings.NewReader("exit\n"),
+		Stdout: &stdout,
+		Resize: sizes,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := <-received; got != "exit\n" {
+		t.Fatalf("expected stdin to be forwarded, got %q", got)
+	}
+	if stdout.String() != "$ " {
+		t.Fatalf("expected raw TTY output, got %q", stdout.String())
+	}
+	select {
+	case size := <-resized:
+		if size.Height != 40 || size.Width != 120 {
+			t.Fatalf("expected resize to 40x120, got %+v", size)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the terminal size to be sent")
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerStatPathFn: func(ctx context.Context, containerID, path string) (container.PathStat, error) {
+			if path == "/usr/bin/zsh" {
+				return container.PathStat{Name: "zsh"}, nil
+			}
+			return container.PathStat{}, context.Canceled
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+	if got := engine.DetectShell(context.Background(), "box"); got != "/usr/bin/zsh" {
+		t.Fatalf("expected /usr/bin/zsh, got %s", got)
+	}
+
+	engine = &pkg.Dockerengine{Client: &MockDockerClient{}}
+	if got := engine.DetectShell(context.Background(), "box"); got != "/bin/sh" {
+		t.Fatalf("expected /bin/sh fallback, got %s", got)
+	}
+}
+
+func TestSplitExecArgs(t *testing.T) {
+	// Parse like sb exec does: flags stop at the sandbox name
+	parse := func(argv ...string) []string {
+		fs := pflag.NewFlagSet("exec", pflag.ContinueOnError)
+		fs.SetInterspersed(false)
+		fs.BoolP("interactive", "i", false, "")
+		if err := fs.Parse(argv); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		return fs.Args()
+	}
+
+	cases := []struct {
+		argv []string
+		want []string
+	}{
+		{[]string{"box", "--", "ls", "-la"}, []string{"ls", "-la"}},
+		{[]string{"-i", "box", "--", "cat"}, []string{"cat"}},
+		{[]string{"box", "ls", "--", "x"}, []string{"ls", "--", "x"}},
+		{[]string{"box", "--", "--", "x"}, []string{"--", "x"}},
+	}
+	for _, c := range cases {
+		name, command, err := cmd.SplitExecArgs(parse(c.argv...))
+		if err != nil || name != "box" || strings.Join(command, " ") != strings.Join(c.want, " ") {
+			t.Errorf("%v: got %s %v (%v)", c.argv, name, command, err)
+		}
+	}
+	for _, argv := range [][]string{{"box"}, {"box", "--"}} {
+		if _, _, err := cmd.SplitExecArgs(parse(argv...)); err == nil {
+			t.Errorf("%v: expected an error without a command", argv)
+		}
+	}
+}
+
+func TestAfterRecreate_ExecRenew(t *testing.T) {
+	// exec --renew recreates the sandbox on an engine that may lack a store
+	var out bytes.Buffer
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}}
+	if err := cmd.AfterRecreate(context.Background(), engine, "box", false, &out); err != nil || out.Len() > 0 {
+		t.Fatalf("expected nothing to do without a metadata store, got %v %q", err, out.String())
+	}
+
+	engine.Meta = &pkg.MetaStore{Dir: t.TempDir()}
+	engine.Meta.MarkInit("box", []string{"/tmp/setup.sh"})
+	if err := cmd.AfterRecreate(context.Background(), engine, "box", false, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "--rerun-init") {
+		t.Fatalf("expected skipped init scripts to be reported, got %q", out.String())
+	}
+}
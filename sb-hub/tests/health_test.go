@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func runningInspect(status string) func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+		return container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				State: &container.State{Running: status == "running", Status: status, ExitCode: 2},
+			},
+		}, nil
+	}
+}
+
+func TestHealthCheck_UsesShell(t *testing.T) {
+	hc := pkg.HealthCheck("curl -f localhost")
+	if len(hc.Test) != 2 || hc.Test[0] != "CMD-SHELL" || hc.Test[1] != "curl -f localhost" {
+		t.Fatalf("unexpected healthcheck test: %v", hc.Test)
+	}
+	if hc.Retries == 0 || hc.Interval == 0 {
+		t.Fatalf("expected interval and retries to be set: %+v", hc)
+	}
+}
+
+func TestReadinessURL(t *testing.T) {
+	if got := pkg.ReadinessURL("localhost", "8001", "healthz"); got != "http://localhost:8001/healthz" {
+		t.Fatalf("unexpected URL: %s", got)
+	}
+	if got := pkg.ReadinessURL("build01", "8001", "/healthz"); got != "http://build01:8001/healthz" {
+		t.Fatalf("unexpected URL: %s", got)
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	if err := pkg.ProbeHTTP(context.Background(), srv.URL+"/ok", time.Second); err != nil {
+		t.Fatalf("expected ready, got %v", err)
+	}
+	if err := pkg.ProbeHTTP(context.Background(), srv.URL+"/down", time.Second); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected 503 error, got %v", err)
+	}
+}
+
+func TestWaitHealthy_PollsUntilHTTPReady(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{ContainerInspectFn: runningInspect("running")}}
+
+	if err := engine.WaitHealthy(context.Background(), "box", srv.URL, 5*time.Second); err != nil {
+		t.Fatalf("expected ready, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("expected 3 probes, got %d", calls)
+	}
+}
+
+func TestWaitHealthy_TimesOut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{ContainerInspectFn: runningInspect("running")}}
+
+	err := engine.WaitHealthy(context.Background(), "box", srv.URL, 700*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
+func TestWaitHealthy_ContainerExited(t *testing.T) {
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{ContainerInspectFn: runningInspect("exited")}}
+
+	err := engine.WaitHealthy(context.Background(), "box", "http://localhost:1/", 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Fatalf("expected exited error, got %v", err)
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	cases := []struct {
+		summary container.Summary
+		want    string
+	}{
+		{container.Summary{State: "running", Status: "Up 2 minutes (healthy)"}, "healthy"},
+		{container.Summary{State: "running", Status: "Up 2 minutes (unhealthy)"}, "unhealthy"},
+		{container.Summary{State: "running", Status: "Up 3 seconds (health: starting)"}, "starting"},
+		{container.Summary{State: "running", Status: "Up 2 minutes"}, "-"},
+		{container.Summary{State: "running", Status: "Up 2 minutes", Labels: map[string]string{"com.sbhub.hostport": port, "com.sbhub.health-http": "/"}}, "healthy"},
+		{container.Summary{State: "exited", Status: "Exited (0)", Labels: map[string]string{"com.sbhub.hostport": port, "com.sbhub.health-http": "/"}}, "-"},
+	}
+	for _, c := range cases {
+		if got := pkg.HealthStatus(context.Background(), c.summary, "127.0.0.1"); got != c.want {
+			t.Errorf("%q: expected %s, got %s", c.summary.Status, c.want, got)
+		}
+	}
+	labels := map[string]string{"com.sbhub.hostport": "8001", "com.sbhub.health-http": "/healthz"}
+	if url := pkg.HealthProbeURL(container.Summary{State: "running", Labels: labels}, "build01"); url != "http://build01:8001/healthz" {
+		t.Fatalf("expected probe on the endpoint's host, got '%s'", url)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func writeHook(t *testing.T, dir, name, body string) pkg.Hook {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return pkg.Hook{Command: path}
+}
+
+func TestRunHook_ReceivesPayloadAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	hook := writeHook(t, dir, "record", `cat > "`+dir+`/payload.json"; echo "$SBHUB_EVENT $SBHUB_SANDBOX" > "`+dir+`/env"`)
+
+	payload := pkg.HookPayload{Event: pkg.HookPostCreate, Name: "box", Ports: map[string]string{"80/tcp": "8001"}}
+	if err := pkg.RunHook(context.Background(), hook, payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got pkg.HookPayload
+	data, _ := os.ReadFile(filepath.Join(dir, "payload.json"))
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid payload %q: %v", data, err)
+	}
+	if got.Name != "box" || got.Ports["80/tcp"] != "8001" {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+	if env, _ := os.ReadFile(filepath.Join(dir, "env")); strings.TrimSpace(string(env)) != "post-create box" {
+		t.Fatalf("unexpected env: %q", env)
+	}
+}
+
+func TestRunHook_FailureAndTimeout(t *testing.T) {
+	dir := t.TempDir()
+	fail := writeHook(t, dir, "fail", "echo 'vpn not connected' >&2\nexit 3\n")
+	if err := pkg.RunHook(context.Background(), fail, pkg.HookPayload{}); err == nil || !strings.Contains(err.Error(), "vpn not connected") {
+		t.Fatalf("expected hook output in error, got %v", err)
+	}
+
+	slow := writeHook(t, dir, "slow", "sleep 5\n")
+	slow.Timeout = 200 * time.Millisecond
+	start := time.Now()
+	err := pkg.RunHook(context.Background(), slow, pkg.HookPayload{})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("hook was not killed at its timeout")
+	}
+}
+
+func TestRunHooks_PreVetoStopsPostContinues(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	hooks := []pkg.Hook{
+		writeHook(t, dir, "veto", "exit 1\n"),
+		writeHook(t, dir, "after", `touch "`+marker+`"`),
+	}
+
+	if err := pkg.RunHooks(context.Background(), hooks, pkg.HookPayload{Event: pkg.HookPreRemove}); err == nil {
+		t.Fatal("expected pre-remove veto")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("expected hooks after a veto to be skipped")
+	}
+
+	if err := pkg.RunHooks(context.Background(), hooks, pkg.HookPayload{Event: pkg.HookPostRemove}); err == nil {
+		t.Fatal("expected post-remove failure to be reported")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatal("expected every post-remove hook to run")
+	}
+}
+
+func TestHooksFor_GlobalThenPreset(t *testing.T) {
+	cfg := &pkg.Config{
+		Hooks:   pkg.HookConfig{pkg.HookPostCreate: {{Command: "global"}}},
+		Presets: map[string]pkg.PresetConfig{"large": {Hooks: pkg.HookConfig{pkg.HookPostCreate: {{Command: "large"}}}}},
+	}
+	hooks := cfg.HooksFor(pkg.HookPostCreate, "large")
+	if len(hooks) != 2 || hooks[0].Command != "global" || hooks[1].Command != "large" {
+		t.Fatalf("unexpected hooks: %+v", hooks)
+	}
+	if hooks := cfg.HooksFor(pkg.HookPostCreate, "small"); len(hooks) != 1 {
+		t.Fatalf("expected only the global hook, got %+v", hooks)
+	}
+}
+
+func TestLoadConfig_Hooks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("hooks:\n  pre-create:\n    - command: /bin/true\n      timeout: 5s\n"), 0644)
+	cfg, err := pkg.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h := cfg.Hooks[pkg.HookPreCreate]; len(h) != 1 || h[0].Timeout != 5*time.Second {
+		t.Fatalf("unexpected hooks: %+v", cfg.Hooks)
+	}
+
+	os.WriteFile(path, []byte("hooks:\n  on-create:\n    - command: /bin/true\n"), 0644)
+	if _, err := pkg.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "on-create") {
+		t.Fatalf("expected unknown event error, got %v", err)
+	}
+}
+
+func TestNewHookPayload(t *testing.T) {
+	config := &container.Config{Image: "alpine", Labels: map[string]string{"com.sbhub.size": "small"}}
+	hostConfig := &container.HostConfig{PortBindings: nat.PortMap{"80/tcp": {{HostPort: "8004"}}}}
+
+	p := pkg.NewHookPayload(pkg.HookPreRemove, "box", config, hostConfig, "/srv/box")
+	if p.Size != "small" || p.Image != "alpine" || p.Ports["80/tcp"] != "8004" || p.StoragePath != "/srv/box" {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+	if post := p.As(pkg.HookPostRemove); post.Event != pkg.HookPostRemove || post.Name != "box" {
+		t.Fatalf("unexpected converted payload: %+v", post)
+	}
+}
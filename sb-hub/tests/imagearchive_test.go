@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/image"
+)
+
+const loadStream = `{"stream":"Loaded image: sb-local-api:latest\n"}
+{"stream":"Loaded image ID: sha256:f00d\n"}
+`
+
+func TestReadLoadStream(t *testing.T) {
+	loaded, err := pkg.ReadLoadStream(strings.NewReader(loadStream), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Ref != "sb-local-api:latest" || !loaded[0].Tagged || loaded[1].Ref != "sha256:f00d" || loaded[1].Tagged {
+		t.Fatalf("unexpected images: %+v", loaded)
+	}
+
+	plain, err := pkg.ReadLoadStream(strings.NewReader("Loaded image: alpine:3.20\n"), false)
+	if err != nil || len(plain) != 1 || plain[0].Ref != "alpine:3.20" {
+		t.Fatalf("unexpected plain-text result: %+v %v", plain, err)
+	}
+
+	if _, err := pkg.ReadLoadStream(strings.NewReader(`{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}`), true); err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if _, err := pkg.ReadLoadStream(strings.NewReader(""), true); err == nil {
+		t.Fatal("expected error for an archive without images")
+	}
+}
+
+func TestLoadImageArchive_SendsFileAndRecordsImages(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "app.tar")
+	os.WriteFile(archive, []byte("tar-bytes"), 0644)
+
+	var sent []byte
+	mock := &MockDockerClient{
+		ImageLoadFn: func(ctx context.Context, input io.Reader) (image.LoadResponse, error) {
+			sent, _ = io.ReadAll(input)
+			return image.LoadResponse{Body: io.NopCloser(strings.NewReader(loadStream)), JSON: true}, nil
+		},
+		ImageInspectFn: func(ctx context.Context, imageID string) (image.InspectResponse, error) {
+			if imageID == "sha256:f00d" {
+				return image.InspectResponse{ID: imageID}, nil
+			}
+			return image.InspectResponse{ID: "sha256:a9e"}, nil
+		},
+	}
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	loaded, err := engine.LoadImageArchive(context.Background(), archive, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(sent) != "tar-bytes" || len(loaded) != 2 {
+		t.Fatalf("unexpected load: sent %q, loaded %+v", sent, loaded)
+	}
+	records, _ := store.PulledImages()
+	if len(records) != 2 || records[0].Origin != "loaded" || records[0].ID != "sha256:a9e" {
+		t.Fatalf("expected loaded images to be tracked, got %+v", records)
+	}
+
+	if _, err := engine.LoadImageArchive(context.Background(), filepath.Join(t.TempDir(), "missing.tar"), nil); err == nil {
+		t.Fatal("expected error for a missing archive")
+	}
+}
+
+func TestPickLoadedImage(t *testing.T) {
+	one := []pkg.LoadedImage{{Ref: "app:1.0", Tagged: true}}
+	if ref, err := pkg.PickLoadedImage(one, ""); err != nil || ref != "app:1.0" {
+		t.Fatalf("unexpected pick: %s %v", ref, err)
+	}
+
+	two := []pkg.LoadedImage{{Ref: "api:latest", Tagged: true}, {Ref: "db:16", Tagged: true}}
+	if _, err := pkg.PickLoadedImage(two, ""); err == nil || !strings.Contains(err.Error(), "--image") {
+		t.Fatalf("expected an ambiguity error, got %v", err)
+	}
+	if ref, _ := pkg.PickLoadedImage(two, "api"); ref != "api:latest" {
+		t.Fatalf("expected an untagged name to match latest, got %s", ref)
+	}
+	if _, err := pkg.PickLoadedImage(two, "web"); err == nil {
+		t.Fatal("expected error for an image not in the archive")
+	}
+}
+
+func TestSaveImages(t *testing.T) {
+	var refs []string
+	mock := &MockDockerClient{
+		ImageSaveFn: func(ctx context.Context, imageIDs []string) (io.ReadCloser, error) {
+			refs = imageIDs
+			return io.NopCloser(strings.NewReader("archive")), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var out bytes.Buffer
+	n, err := engine.SaveImages(context.Background(), []string{"sb-local-api:latest"}, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 7 || out.String() != "archive" || len(refs) != 1 || refs[0] != "sb-local-api:latest" {
+		t.Fatalf("unexpected save: %d %q %v", n, out.String(), refs)
+	}
+}
@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestMetaStore_PulledImages(t *testing.T) {
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	store.RecordPull("alpine", "sha256:a1")
+	store.RecordPull("alpine", "sha256:a1")
+	store.RecordPull("alpine", "sha256:a2")
+
+	pulled, err := store.PulledImages()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pulled) != 2 {
+		t.Fatalf("expected one record per image ID, got %v", pulled)
+	}
+
+	store.ForgetPulls("sha256:a1")
+	if pulled, _ := store.PulledImages(); len(pulled) != 1 || pulled[0].ID != "sha256:a2" {
+		t.Fatalf("unexpected records after forget: %v", pulled)
+	}
+}
+
+func TestPullImage_RecordsPull(t *testing.T) {
+	pulledOnce := false
+	mock := &MockDockerClient{
+		ImagePullFn: func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+			pulledOnce = true
+			return io.NopCloser(strings.NewReader(pullStream)), nil
+		},
+		ImageInspectFn: func(ctx context.Context, imageID string) (image.InspectResponse, error) {
+			if !pulledOnce {
+				return image.InspectResponse{}, errors.New("No such image")
+			}
+			return image.InspectResponse{ID: "sha256:fresh"}, nil
+		},
+	}
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	if err := engine.PullImage(context.Background(), "alpine:latest", pkg.PullOptions{Out: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pulled, _ := store.PulledImages()
+	if len(pulled) != 1 || pulled[0].Ref != "alpine:latest" || pulled[0].ID != "sha256:fresh" {
+		t.Fatalf("unexpected records: %v", pulled)
+	}
+}
+
+// imageHost fakes a daemon with a built image, a pulled image in use, the
+// dangling image left by a rebuild and an image sb-hub never touched.
+func imageHost(t *testing.T) (*MockDockerClient, *pkg.MetaStore) {
+	t.Helper()
+	old := time.Now().Add(-48 * time.Hour).Unix()
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	store.RecordPull("alpine", "sha256:alpine")
+	store.RecordPull("redis", "sha256:gone")
+
+	mock := &MockDockerClient{
+		ImageListFn: func(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+			return []image.Summary{
+				{ID: "sha256:built", RepoTags: []string{"sb-local-api:latest"}, Size: 300, Created: old, Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.source": "/src/api"}},
+				{ID: "sha256:alpine", RepoTags: []string{"alpine:latest"}, Size: 100, Created: old},
+				{ID: "sha256:stale", RepoTags: []string{"<none>:<none>"}, Size: 200, Created: time.Now().Unix(), Labels: map[string]string{"com.sbhub.managed": "true"}},
+				{ID: "sha256:other", RepoTags: []string{"postgres:16"}, Size: 400, Created: old},
+			}, nil
+		},
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			return []container.Summary{{Names: []string{"/box"}, ImageID: "sha256:alpine"}}, nil
+		},
+	}
+	return mock, store
+}
+
+func TestListImages(t *testing.T) {
+	mock, store := imageHost(t)
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	images, err := engine.ListImages(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byID := map[string]pkg.SandboxImage{}
+	for _, img := range images {
+		byID[img.ID] = img
+	}
+	if len(images) != 3 || byID["sha256:other"].ID != "" {
+		t.Fatalf("expected only sb-hub images, got %+v", images)
+	}
+	if b := byID["sha256:built"]; b.Origin != "built" || b.Source != "/src/api" {
+		t.Fatalf("unexpected built image: %+v", b)
+	}
+	if a := byID["sha256:alpine"]; a.Origin != "pulled" || len(a.UsedBy) != 1 || a.UsedBy[0] != "box" {
+		t.Fatalf("unexpected pulled image: %+v", a)
+	}
+	if !byID["sha256:stale"].Dangling() {
+		t.Fatal("expected the untagged image to be dangling")
+	}
+	if pulled, _ := store.PulledImages(); len(pulled) != 1 {
+		t.Fatalf("expected the record of a deleted image to be dropped, got %v", pulled)
+	}
+}
+
+func TestPruneImages(t *testing.T) {
+	mock, store := imageHost(t)
+	var removed []string
+	mock.ImageRemoveFn = func(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+		removed = append(removed, imageID)
+		return nil, nil
+	}
+	mock.BuildCachePruneFn = func(ctx context.Context, opts build.CachePruneOptions) (*build.CachePruneReport, error) {
+		if opts.All {
+			t.Error("expected only dangling build cache to be pruned")
+		}
+		return &build.CachePruneReport{SpaceReclaimed: 1024}, nil
+	}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	report, err := engine.PruneImages(context.Background(), pkg.ImagePruneOptions{BuildCache: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(removed)
+	if strings.Join(removed, ",") != "sb-local-api:latest,sha256:stale" {
+		t.Fatalf("expected unused sb-hub images to be removed by tag or ID, got %v", removed)
+	}
+	if report.Reclaimed != 500 || report.CacheReclaimed != 1024 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestPruneImages_Filters(t *testing.T) {
+	mock, store := imageHost(t)
+	mock.ImageRemoveFn = func(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+		t.Errorf("dry run removed %s", imageID)
+		return nil, nil
+	}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	report, _ := engine.PruneImages(context.Background(), pkg.ImagePruneOptions{DanglingOnly: true, DryRun: true})
+	if len(report.Removed) != 1 || report.Removed[0].ID != "sha256:stale" {
+		t.Fatalf("expected only the dangling image, got %+v", report.Removed)
+	}
+	report, _ = engine.PruneImages(context.Background(), pkg.ImagePruneOptions{OlderThan: 24 * time.Hour, DryRun: true})
+	if len(report.Removed) != 1 || report.Removed[0].ID != "sha256:built" {
+		t.Fatalf("expected only the old unused image, got %+v", report.Removed)
+	}
+}
+
+func TestPruneImages_ReportsConflicts(t *testing.T) {
+	mock, store := imageHost(t)
+	mock.ImageRemoveFn = func(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+		if imageID == "sha256:stale" {
+			return nil, errors.New("conflict: image is being used by stopped container 4f2a")
+		}
+		return nil, nil
+	}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	report, err := engine.PruneImages(context.Background(), pkg.ImagePruneOptions{})
+	if err == nil || len(report.Errors) != 1 {
+		t.Fatalf("expected the conflict to be reported, got %v", err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].ID != "sha256:built" {
+		t.Fatalf("expected the other image to be removed, got %+v", report.Removed)
+	}
+}
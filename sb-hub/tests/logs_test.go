@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// muxedLogs builds a multiplexed log stream as the daemon sends it for
+// non-TTY containers.
+func muxedLogs(stdout, stderr string) io.ReadCloser {
+	var buf bytes.Buffer
+	stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(stdout))
+	stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte(stderr))
+	return io.NopCloser(&buf)
+}
+
+func inspectWithTty(tty bool) func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+		return container.InspectResponse{Config: &container.Config{Tty: tty}}, nil
+	}
+}
+
+func TestStreamLogs_DemuxesAndFilters(t *testing.T) {
+	var opts container.LogsOptions
+	mock := &MockDockerClient{
+		ContainerInspectFn: inspectWithTty(false),
+		ContainerLogsFn: func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+			opts = options
+			return muxedLogs(
+				"2026-01-02T10:00:00.000000000Z started\n2026-01-02T10:00:01.000000000Z request ok\n",
+				"2026-01-02T10:00:02.000000000Z request failed\n",
+			), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var lines []pkg.LogLine
+	err := engine.StreamLogs(context.Background(), "box", pkg.LogOptions{Tail: "10", Since: "10m", Grep: regexp.MustCompile("request")}, func(l pkg.LogLine) error {
+		lines = append(lines, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Tail != "10" || opts.Since != "10m" || !opts.Timestamps {
+		t.Fatalf("unexpected log options: %+v", opts)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 matching lines, got %+v", lines)
+	}
+	if lines[0].Stream != "stdout" || lines[0].Text != "request ok" || lines[0].Time.Second() != 1 {
+		t.Fatalf("unexpected stdout line: %+v", lines[0])
+	}
+	if lines[1].Stream != "stderr" || lines[1].Text != "request failed" {
+		t.Fatalf("unexpected stderr line: %+v", lines[1])
+	}
+}
+
+func TestStreamLogs_TtyIsRaw(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerInspectFn: inspectWithTty(true),
+		ContainerLogsFn: func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("2026-01-02T10:00:00Z prompt\r\n2026-01-02T10:00:01Z no newline")), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var texts []string
+	engine.StreamLogs(context.Background(), "box", pkg.LogOptions{}, func(l pkg.LogLine) error {
+		texts = append(texts, l.Text)
+		return nil
+	})
+	if len(texts) != 2 || texts[0] != "prompt" || texts[1] != "no newline" {
+		t.Fatalf("unexpected lines: %q", texts)
+	}
+}
+
+func TestMergeLogs_SortsByTime(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerInspectFn: inspectWithTty(true),
+		ContainerLogsFn: func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+			if containerID == "api" {
+				return io.NopCloser(strings.NewReader("2026-01-02T10:00:00Z a1\n2026-01-02T10:00:02Z a2\n")), nil
+			}
+			return io.NopCloser(strings.NewReader("2026-01-02T10:00:01Z w1\n")), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var got []string
+	err := engine.MergeLogs(context.Background(), []string{"api", "worker"}, pkg.LogOptions{}, func(l pkg.LogLine) error {
+		got = append(got, l.Sandbox+":"+l.Text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, ",") != "api:a1,worker:w1,api:a2" {
+		t.Fatalf("unexpected merge order: %v", got)
+	}
+}
+
+func TestArchiveLogs_RoundTrip(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerInspectFn: inspectWithTty(false),
+		ContainerLogsFn: func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+			if options.Follow || options.Tail != "all" {
+				t.Errorf("expected the full log without follow, got %+v", options)
+			}
+			return muxedLogs("2026-01-02T10:00:00Z booted\n", "2026-01-02T10:00:01Z panic: out of memory\n"), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	dir := pkg.LogArchiveDir(t.TempDir(), "box")
+	path, err := engine.ArchiveLogs(context.Background(), "box", dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	archives, _ := pkg.ListLogArchives(dir)
+	if len(archives) != 1 || archives[0] != path {
+		t.Fatalf("expected archive %s to be listed, got %v", path, archives)
+	}
+
+	var lines []pkg.LogLine
+	err = pkg.ReadLogArchive(path, "box", regexp.MustCompile("panic"), func(l pkg.LogLine) error {
+		lines = append(lines, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 1 || lines[0].Stream != "stderr" || lines[0].Text != "panic: out of memory" || lines[0].Time.Second() != 1 {
+		t.Fatalf("unexpected archived lines: %+v", lines)
+	}
+}
+
+func TestArchiveLogs_LogsError(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerLogsFn: func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	dir := t.TempDir()
+	if _, err := engine.ArchiveLogs(context.Background(), "box", dir); err == nil {
+		t.Fatal("expected error")
+	}
+	if archives, _ := pkg.ListLogArchives(dir); len(archives) != 0 {
+		t.Fatalf("expected no partial archive, got %v", archives)
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2026-10-18T09:30:00Z": time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		"10m":                  now.Add(-10 * time.Minute),
+		"1792310400":           time.Unix(1792310400, 0),
+		"1792310400.5":         time.Unix(1792310400, 5e8),
+	}
+	for value, want := range cases {
+		got, err := pkg.ParseLogTime(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%s: expected %s, got %s (%v)", value, want, got, err)
+		}
+	}
+	if _, err := pkg.ParseLogTime("yesterday", now); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestParseMountFlag(t *testing.T) {
+	bind, err := pkg.ParseMountFlag("/srv/code:/app:ro")
+	if err != nil || bind != "/srv/code:/app:ro" {
+		t.Fatalf("unexpected result: %s %v", bind, err)
+	}
+
+	bind, err = pkg.ParseMountFlag("code:/app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	abs, _ := filepath.Abs("code")
+	if bind != abs+":/app" {
+		t.Fatalf("expected relative source to be made absolute, got '%s'", bind)
+	}
+
+	for _, bad := range []string{"/srv/code", "/srv/code:app", "/srv/code:/app:rx", ":/app"} {
+		if _, err := pkg.ParseMountFlag(bad); err == nil {
+			t.Errorf("expected error for '%s'", bad)
+		}
+	}
+}
+
+func TestParseVolumeFlag(t *testing.T) {
+	name, bind, err := pkg.ParseVolumeFlag("cache:/root/.cache")
+	if err != nil || name != "cache" || bind != "cache:/root/.cache" {
+		t.Fatalf("unexpected result: %s %s %v", name, bind, err)
+	}
+	for _, bad := range []string{"cache", "./cache:/x", "cache:relative"} {
+		if _, _, err := pkg.ParseVolumeFlag(bad); err == nil {
+			t.Errorf("expected error for '%s'", bad)
+		}
+	}
+}
+
+func TestReplaceBind_KeepsOtherMounts(t *testing.T) {
+	binds := []string{"/old:/data", "/code:/app:ro", "/s/key:/run/secrets/key:ro"}
+
+	got := pkg.ReplaceBind(binds, "/data", "/new:/data")
+	expected := []string{"/code:/app:ro", "/s/key:/run/secrets/key:ro", "/new:/data"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	got = pkg.RemoveBind(binds, "/app/")
+	expected = []string{"/old:/data", "/s/key:/run/secrets/key:ro"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestEnsureVolume_Labels(t *testing.T) {
+	mock := &MockDockerClient{
+		VolumeCreateFn: func(ctx context.Context, options volume.CreateOptions) (volume.Volume, error) {
+			if options.Name != pkg.DataVolumeName("box") {
+				t.Fatalf("expected volume '%s', got '%s'", pkg.DataVolumeName("box"), options.Name)
+			}
+			if options.Labels["com.sbhub.managed"] != "true" {
+				t.Fatal("expected managed label on volume")
+			}
+			return volume.Volume{Name: options.Name}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	if err := engine.EnsureVolume(context.Background(), pkg.DataVolumeName("box")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRemoveVolume_Error(t *testing.T) {
+	mock := &MockDockerClient{
+		VolumeRemoveFn: func(ctx context.Context, volumeID string, force bool) error {
+			return errors.New("volume in use")
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	if err := engine.RemoveVolume(context.Background(), "sbhub-box-data"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestPolicyFor_OverridesDefault(t *testing.T) {
+	cfg := &pkg.Config{Owners: map[string]pkg.OwnerPolicy{
+		"default": {MaxSandboxes: 3, MaxTTL: 8 * time.Hour},
+		"ci":      {MaxSandboxes: 10},
+	}}
+	ci := cfg.PolicyFor("ci")
+	if ci.MaxSandboxes != 10 || ci.MaxTTL != 8*time.Hour {
+		t.Fatalf("expected ci to override only max_sandboxes, got %+v", ci)
+	}
+	if other := cfg.PolicyFor("alice"); other.MaxSandboxes != 3 {
+		t.Fatalf("expected default policy, got %+v", other)
+	}
+	if cfg.PolicyFor("alice").ClampTTL(24*time.Hour) != 8*time.Hour {
+		t.Fatal("expected TTL to be clamped to max_ttl")
+	}
+}
+
+func TestCurrentOwner_Configured(t *testing.T) {
+	if got := (&pkg.Config{Owner: "alice"}).CurrentOwner(); got != "alice" {
+		t.Fatalf("expected configured owner, got %s", got)
+	}
+	if got := (&pkg.Config{}).CurrentOwner(); got == "" {
+		t.Fatal("expected an owner from the OS user")
+	}
+}
+
+func TestOwnerUsage_AndCheckCreate(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			owned := func(owner, size string) map[string]string {
+				return map[string]string{"com.sbhub.managed": "true", "com.sbhub.owner": owner, "com.sbhub.size": size}
+			}
+			return []container.Summary{
+				{Names: []string{"/a1"}, State: "running", Labels: owned("alice", "medium")},
+				{Names: []string{"/a2"}, State: "exited", Labels: owned("alice", "small")},
+				{Names: []string{"/b1"}, State: "running", Labels: owned("bob", "xlarge")},
+			}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	usage, err := engine.OwnerUsage(context.Background(), "alice", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage.Sandboxes != 2 || usage.CPUs != 2.5 || usage.MemoryMB != 4608 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+
+	policy := pkg.OwnerPolicy{MaxSandboxes: 3, MaxCPUs: 4}
+	if err := policy.CheckCreate(usage, "small"); err != nil {
+		t.Fatalf("expected small to fit, got %v", err)
+	}
+	if err := policy.CheckCreate(usage, "medium"); err == nil || !strings.Contains(err.Error(), "CPU") {
+		t.Fatalf("expected CPU quota error, got %v", err)
+	}
+	if err := (pkg.OwnerPolicy{MaxSandboxes: 2}).CheckCreate(usage, "small"); err == nil {
+		t.Fatal("expected sandbox count quota error")
+	}
+}
+
+func TestSnapshotBytes_CountsOwnedSnapshots(t *testing.T) {
+	root := t.TempDir()
+	meta := pkg.MetaStoreFor(root)
+	for dir, owner := range map[string]string{"box_snap_v1": "alice", "box_snap_v2": "bob", "box": "alice"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+		os.WriteFile(filepath.Join(root, dir, "data"), make([]byte, 100), 0644)
+		meta.SetLabels(dir, map[string]string{"com.sbhub.owner": owner})
+	}
+
+	used := pkg.SnapshotBytes(root, meta, "alice")
+	if used != 100 {
+		t.Fatalf("expected only alice's snapshot to count, got %d", used)
+	}
+	policy := pkg.OwnerPolicy{MaxSnapshotBytes: 150}
+	if err := policy.CheckSnapshot(pkg.OwnerUsage{SnapshotBytes: used}, 100); err == nil {
+		t.Fatal("expected snapshot quota error")
+	}
+}
+
+func TestExpiredByPolicy(t *testing.T) {
+	old := time.Now().Add(-10 * time.Hour).Unix()
+	mock := &MockDockerClient{
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			return []container.Summary{
+				{ID: "1", Names: []string{"/intern-box"}, State: "running", Created: old, Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.owner": "intern"}},
+				{ID: "2", Names: []string{"/lead-box"}, State: "running", Created: old, Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.owner": "lead"}},
+			}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	expired, err := engine.ExpiredByPolicy(context.Background(), func(owner string) time.Duration {
+		if owner == "intern" {
+			return 4 * time.Hour
+		}
+		return 0
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "1" {
+		t.Fatalf("expected only the intern's sandbox, got %+v", expired)
+	}
+}
+
+func TestExpiredByPolicy_SurvivesRenew(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			// Renewed a minute ago, which reset the container's creation time
+			return []container.Summary{
+				{ID: "1", Names: []string{"/intern-box"}, State: "running", Created: time.Now().Add(-time.Minute).Unix(), Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.owner": "intern"}},
+			}, nil
+		},
+	}
+	meta := pkg.MetaStoreFor(t.TempDir())
+	engine := &pkg.Dockerengine{Client: mock, Meta: meta}
+	first := time.Now().Add(-5 * time.Hour)
+	inspect := container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{Created: first.Format(time.RFC3339Nano)}}
+	if err := engine.KeepCreated("intern-box", inspect); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A second renew must not move the original creation time
+	inspect.Created = time.Now().Format(time.RFC3339Nano)
+	engine.KeepCreated("intern-box", inspect)
+	meta.ClearLabels("intern-box")
+
+	expired, err := engine.ExpiredByPolicy(context.Background(), func(owner string) time.Duration { return 4 * time.Hour })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(expired) != 1 {
+		t.Fatalf("expected the renewed sandbox to be past its max TTL, got %+v", expired)
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveInitScripts(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "setup.sh", "apk add git\n")
+
+	got, err := pkg.ResolveInitScripts([]string{script})
+	if err != nil || len(got) != 1 || got[0] != script {
+		t.Fatalf("expected %s, got %v (%v)", script, got, err)
+	}
+	if _, err := pkg.ResolveInitScripts([]string{filepath.Join(dir, "missing.sh")}); err == nil {
+		t.Fatal("expected error for a missing script")
+	}
+	if _, err := pkg.ResolveInitScripts([]string{dir}); err == nil {
+		t.Fatal("expected error for a directory")
+	}
+}
+
+func TestRunInitScripts_CopiesAndRunsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	scripts := []string{
+		writeScript(t, dir, "tools.sh", "apk add git curl\n"),
+		writeScript(t, dir, "seed.sh", "echo seeded\n"),
+	}
+
+	files := map[string]string{}
+	var ran [][]string
+	mock := &MockDockerClient{
+		CopyToContainerFn: func(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+			tr := tar.NewReader(content)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					return nil
+				}
+				data, _ := io.ReadAll(tr)
+				files[dstPath+hdr.Name] = string(data)
+			}
+		},
+		ContainerExecCreateFn: func(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+			ran = append(ran, options.Cmd)
+			return container.ExecCreateResponse{ID: "exec"}, nil
+		},
+	}
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	var out bytes.Buffer
+	if err := engine.InitSandbox(context.Background(), "box", scripts, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files["/tmp/sbhub-init/01-tools.sh"] != "apk add git curl\n" || files["/tmp/sbhub-init/02-seed.sh"] != "echo seeded\n" {
+		t.Fatalf("unexpected copied files: %v", files)
+	}
+	if len(ran) != 2 || ran[0][1] != "/tmp/sbhub-init/01-tools.sh" || ran[1][1] != "/tmp/sbhub-init/02-seed.sh" {
+		t.Fatalf("unexpected exec order: %v", ran)
+	}
+	if !strings.Contains(out.String(), "tools.sh") {
+		t.Fatalf("expected progress output, got %q", out.String())
+	}
+	meta, _ := store.Load("box")
+	if meta.Init == nil || len(meta.Init.Scripts) != 2 {
+		t.Fatalf("expected init marker, got %+v", meta.Init)
+	}
+}
+
+func TestRunInitScripts_StopsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	scripts := []string{writeScript(t, dir, "bad.sh", "exit 4\n"), writeScript(t, dir, "never.sh", "true\n")}
+
+	runs := 0
+	mock := &MockDockerClient{
+		ContainerExecCreateFn: func(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+			runs++
+			return container.ExecCreateResponse{ID: "exec"}, nil
+		},
+		ContainerExecInspectFn: func(ctx context.Context, execID string) (container.ExecInspect, error) {
+			return container.ExecInspect{ExitCode: 4}, nil
+		},
+	}
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	err := engine.InitSandbox(context.Background(), "box", scripts, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "bad.sh exited with code 4") {
+		t.Fatalf("expected exit code error, got %v", err)
+	}
+	if runs != 1 {
+		t.Fatalf("expected to stop after the first script, ran %d", runs)
+	}
+	if meta, _ := store.Load("box"); meta.Init != nil {
+		t.Fatal("expected no init marker after a failure")
+	}
+}
+
+func TestRunInitCommands_Recorded(t *testing.T) {
+	var ran [][]string
+	mock := &MockDockerClient{
+		ContainerExecCreateFn: func(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+			ran = append(ran, options.Cmd)
+			return container.ExecCreateResponse{ID: "exec"}, nil
+		},
+	}
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	store.MarkInit("box", []string{"/scripts/setup.sh"})
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	if err := engine.RunInitCommands(context.Background(), "box", []string{"make deps"}, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ran) != 1 || ran[0][2] != "make deps" {
+		t.Fatalf("unexpected exec: %v", ran)
+	}
+	meta, _ := store.Load("box")
+	if meta.Init == nil || len(meta.Init.Scripts) != 1 || len(meta.Init.Commands) != 1 || meta.Init.Commands[0] != "make deps" {
+		t.Fatalf("expected scripts and commands recorded, got %+v", meta.Init)
+	}
+}
+
+func TestCreateSandbox_KeepsInitMarker(t *testing.T) {
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	store.SetLabels("box", map[string]string{"com.sbhub.size": "large"})
+	store.MarkInit("box", []string{"/scripts/setup.sh"})
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}, Meta: store}
+
+	if _, err := engine.CreateSandbox(context.Background(), "box", time.Hour, "medium", &container.Config{}, &container.HostConfig{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta, _ := store.Load("box")
+	if len(meta.Labels) != 0 {
+		t.Fatalf("expected label overrides to be cleared, got %v", meta.Labels)
+	}
+	if meta.Init == nil || meta.Init.Scripts[0] != "/scripts/setup.sh" {
+		t.Fatalf("expected init marker to survive a recreate, got %+v", meta.Init)
+	}
+}
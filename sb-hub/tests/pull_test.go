@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/image"
+)
+
+const pullStream = `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a1"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"b2"}
+{"status":"Downloading","progressDetail":{"current":512,"total":2048},"id":"a1"}
+{"status":"Downloading","progressDetail":{"current":100,"total":1024},"id":"b2"}
+{"status":"Download complete","progressDetail":{},"id":"a1"}
+{"status":"Pull complete","progressDetail":{},"id":"a1"}
+{"status":"Download complete","progressDetail":{},"id":"b2"}
+{"status":"Pull complete","progressDetail":{},"id":"b2"}
+{"status":"Digest: sha256:abc"}
+{"status":"Status: Downloaded newer image for alpine:latest"}
+`
+
+func localImage(ctx context.Context, imageID string) (image.InspectResponse, error) {
+	return image.InspectResponse{ID: "sha256:local"}, nil
+}
+
+func TestParsePullPolicy(t *testing.T) {
+	if p, err := pkg.ParsePullPolicy(""); err != nil || p != pkg.PullMissing {
+		t.Fatalf("expected missing by default, got %s (%v)", p, err)
+	}
+	if p, err := pkg.ParsePullPolicy("never"); err != nil || p != pkg.PullNever {
+		t.Fatalf("expected never, got %s (%v)", p, err)
+	}
+	if _, err := pkg.ParsePullPolicy("sometimes"); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
+
+func TestReadPullStream(t *testing.T) {
+	updates := 0
+	var last []pkg.LayerProgress
+	summary, err := pkg.ReadPullStream(strings.NewReader(pullStream), func(layers []pkg.LayerProgress) {
+		updates++
+		last = append([]pkg.LayerProgress(nil), layers...)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Layers != 2 || summary.Downloaded != 3072 || summary.Digest != "sha256:abc" {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if !strings.HasPrefix(summary.Status, "Downloaded newer image") {
+		t.Fatalf("unexpected status: %q", summary.Status)
+	}
+	if updates != 8 || last[1].Status != "Pull complete" || last[1].Current != 1024 {
+		t.Fatalf("unexpected layer updates (%d): %+v", updates, last)
+	}
+}
+
+func TestReadPullStream_Error(t *testing.T) {
+	stream := `{"status":"Pulling fs layer","id":"a1"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}
+`
+	if _, err := pkg.ReadPullStream(strings.NewReader(stream), nil); err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Fatalf("expected stream error, got %v", err)
+	}
+}
+
+func TestFormatLayer(t *testing.T) {
+	got := pkg.FormatLayer(pkg.LayerProgress{ID: "a1", Status: "Downloading", Current: 1024, Total: 2048})
+	if !strings.Contains(got, "[===============>") || !strings.Contains(got, "1.0 KiB / 2.0 KiB") {
+		t.Fatalf("unexpected bar: %q", got)
+	}
+	if got := pkg.FormatLayer(pkg.LayerProgress{ID: "a1", Status: "Pull complete"}); got != "a1: Pull complete" {
+		t.Fatalf("unexpected line: %q", got)
+	}
+}
+
+func TestPullImage_Policies(t *testing.T) {
+	pulls := 0
+	pullOK := func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+		pulls++
+		return io.NopCloser(strings.NewReader(pullStream)), nil
+	}
+	pullFail := func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+		pulls++
+		return nil, errors.New("dial tcp: no route to host")
+	}
+
+	cases := []struct {
+		name    string
+		policy  pkg.PullPolicy
+		local   bool
+		pull    func(context.Context, string, image.PullOptions) (io.ReadCloser, error)
+		pulls   int
+		wantErr bool
+	}{
+		{"missing uses local copy", pkg.PullMissing, true, pullOK, 0, false},
+		{"missing pulls when absent", pkg.PullMissing, false, pullOK, 1, false},
+		{"always pulls", pkg.PullAlways, true, pullOK, 1, false},
+		{"always falls back offline", pkg.PullAlways, true, pullFail, 1, false},
+		{"offline without local copy", pkg.PullMissing, false, pullFail, 1, true},
+		{"never without local copy", pkg.PullNever, false, pullOK, 0, true},
+		{"never with local copy", pkg.PullNever, true, pullOK, 0, false},
+	}
+	for _, c := range cases {
+		pulls = 0
+		mock := &MockDockerClient{ImagePullFn: c.pull}
+		if c.local {
+			mock.ImageInspectFn = localImage
+		}
+		engine := &pkg.Dockerengine{Client: mock}
+
+		var out bytes.Buffer
+		err := engine.PullImage(context.Background(), "alpine:latest", pkg.PullOptions{Policy: c.policy, Out: &out})
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if pulls != c.pulls {
+			t.Errorf("%s: expected %d pulls, got %d", c.name, c.pulls, pulls)
+		}
+	}
+}
+
+func TestPullImage_QuietSummary(t *testing.T) {
+	mock := &MockDockerClient{
+		ImagePullFn: func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(pullStream)), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var out bytes.Buffer
+	if err := engine.PullImage(context.Background(), "alpine:latest", pkg.PullOptions{Policy: pkg.PullAlways, Out: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "{") || !strings.Contains(out.String(), "2 layers, 3.0 KiB") {
+		t.Fatalf("expected a decoded summary, got %q", out.String())
+	}
+}
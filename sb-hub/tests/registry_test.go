@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// writeDockerConfig writes a config.json and points DOCKER_CONFIG at it.
+func writeDockerConfig(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeCredentialHelper installs docker-credential-<name> on PATH. It
+// answers get for registry.example.com and logs every call to calls.
+func fakeCredentialHelper(t *testing.T, name string) (calls string) {
+	t.Helper()
+	dir := t.TempDir()
+	calls = filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+input=$(cat)
+echo "$1 $input" >> ` + calls + `
+case "$1" in
+get)
+	if [ "$input" = "registry.example.com" ]; then
+		echo '{"ServerURL":"registry.example.com","Username":"ci","Secret":"from-helper"}'
+		exit 0
+	fi
+	echo "credentials not found in native keychain"
+	exit 1;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker-credential-"+name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return calls
+}
+
+func basicAuth(user, pass string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+}
+
+func TestRegistryHost(t *testing.T) {
+	cases := map[string]string{
+		"alpine":                            "docker.io",
+		"library/alpine:3.20":               "docker.io",
+		"registry.example.com/team/api:1.2": "registry.example.com",
+		"localhost:5000/app":                "localhost:5000",
+	}
+	for ref, want := range cases {
+		if got, err := pkg.RegistryHost(ref); err != nil || got != want {
+			t.Errorf("RegistryHost(%s) = %s, %v; want %s", ref, got, err, want)
+		}
+	}
+	for server, want := range map[string]string{
+		pkg.DockerHubServer:                "docker.io",
+		"https://Registry.Example.com/v2/": "registry.example.com",
+		"localhost:5000":                   "localhost:5000",
+	} {
+		if got := pkg.NormalizeRegistry(server); got != want {
+			t.Errorf("NormalizeRegistry(%s) = %s; want %s", server, got, want)
+		}
+	}
+}
+
+func TestCredentials_DockerConfigAuths(t *testing.T) {
+	writeDockerConfig(t, `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "`+basicAuth("hubuser", "hubpass")+`"},
+		"registry.example.com": {"auth": "`+basicAuth("ci", "s3cret")+`"}
+	}}`)
+	creds := &pkg.Credentials{}
+
+	auth, ok, err := creds.Lookup("docker.io")
+	if err != nil || !ok || auth.Username != "hubuser" || auth.Password != "hubpass" || auth.ServerAddress != pkg.DockerHubServer {
+		t.Fatalf("unexpected Docker Hub login: %+v %v %v", auth, ok, err)
+	}
+	if _, ok, _ := creds.Lookup("ghcr.io"); ok {
+		t.Fatal("expected no login for an unknown registry")
+	}
+
+	encoded, err := creds.ForImage("registry.example.com/team/api:1.2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, _ := registry.DecodeAuthConfig(encoded)
+	if decoded.Username != "ci" || decoded.Password != "s3cret" {
+		t.Fatalf("unexpected encoded auth: %+v", decoded)
+	}
+}
+
+func TestCredentials_ConfigTakesPrecedence(t *testing.T) {
+	writeDockerConfig(t, `{"auths": {"registry.example.com": {"auth": "`+basicAuth("docker", "old")+`"}}}`)
+	t.Setenv("SB_REGISTRY_TOKEN", "token-from-env")
+	creds := &pkg.Credentials{Registries: map[string]pkg.RegistryConfig{
+		"registry.example.com": {Username: "sbhub", PasswordEnv: "SB_REGISTRY_TOKEN"},
+		"ghcr.io":              {Username: "x", PasswordEnv: "SB_UNSET_TOKEN"},
+	}}
+
+	auth, ok, err := creds.Lookup("registry.example.com")
+	if err != nil || !ok || auth.Username != "sbhub" || auth.Password != "token-from-env" {
+		t.Fatalf("expected the sb-hub config login, got %+v %v %v", auth, ok, err)
+	}
+	if _, _, err := creds.Lookup("ghcr.io"); err == nil {
+		t.Fatal("expected error for an unset password variable")
+	}
+}
+
+func TestCredentials_CredentialHelper(t *testing.T) {
+	fakeCredentialHelper(t, "sbtest")
+	writeDockerConfig(t, `{"credHelpers": {"registry.example.com": "sbtest"}, "credsStore": "sbtest"}`)
+	creds := &pkg.Credentials{}
+
+	auth, ok, err := creds.Lookup("registry.example.com")
+	if err != nil || !ok || auth.Username != "ci" || auth.Password != "from-helper" {
+		t.Fatalf("unexpected helper login: %+v %v %v", auth, ok, err)
+	}
+	if _, ok, err := creds.Lookup("docker.io"); err != nil || ok {
+		t.Fatalf("expected a helper miss to mean an anonymous pull, got %v %v", ok, err)
+	}
+
+	logins, err := creds.Logins()
+	if err != nil || len(logins) != 1 || logins[0].Source != "helper sbtest" || logins[0].Username != "ci" {
+		t.Fatalf("unexpected logins: %+v %v", logins, err)
+	}
+}
+
+func TestStoreAndRemoveDockerLogin(t *testing.T) {
+	path := writeDockerConfig(t, `{"auths": {}, "currentContext": "remote", "psFormat": "table {{.Names}}"}`)
+
+	err := pkg.StoreDockerLogin(path, registry.AuthConfig{Username: "ci", Password: "s3cret", ServerAddress: "registry.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var raw map[string]any
+	data, _ := os.ReadFile(path)
+	json.Unmarshal(data, &raw)
+	if raw["currentContext"] != "remote" || raw["psFormat"] == nil {
+		t.Fatalf("expected other settings to be kept: %s", data)
+	}
+	if auth, ok, _ := (&pkg.Credentials{}).Lookup("registry.example.com"); !ok || auth.Password != "s3cret" {
+		t.Fatalf("expected the stored login to be found, got %+v", auth)
+	}
+
+	removed, err := pkg.RemoveDockerLogin(path, "registry.example.com")
+	if err != nil || !removed {
+		t.Fatalf("expected the login to be removed: %v %v", removed, err)
+	}
+	if removed, _ := pkg.RemoveDockerLogin(path, "registry.example.com"); removed {
+		t.Fatal("expected a second logout to find nothing")
+	}
+}
+
+func TestStoreDockerLogin_KeepsOtherEntries(t *testing.T) {
+	path := writeDockerConfig(t, `{"auths": {"ghcr.io": {"auth": "eDp5", "email": "ci@example.com", "registrytoken": "abc"}}}`)
+	os.Chmod(path, 0644)
+
+	err := pkg.StoreDockerLogin(path, registry.AuthConfig{Username: "ci", Password: "s3cret", ServerAddress: "registry.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var raw struct {
+		Auths map[string]map[string]any `json:"auths"`
+	}
+	data, _ := os.ReadFile(path)
+	json.Unmarshal(data, &raw)
+	if ghcr := raw.Auths["ghcr.io"]; ghcr["email"] != "ci@example.com" || ghcr["registrytoken"] != "abc" {
+		t.Fatalf("expected unknown fields of other entries to be kept: %s", data)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600, got %v", fi.Mode().Perm())
+	}
+}
+
+func TestStoreDockerLogin_CredentialHelper(t *testing.T) {
+	calls := fakeCredentialHelper(t, "sbtest")
+	path := writeDockerConfig(t, `{"credsStore": "sbtest"}`)
+
+	err := pkg.StoreDockerLogin(path, registry.AuthConfig{IdentityToken: "refresh", ServerAddress: "registry.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(calls)
+	line := strings.TrimPrefix(strings.TrimSpace(string(data)), "store ")
+	var stored struct{ ServerURL, Username, Secret string }
+	if err := json.Unmarshal([]byte(line), &stored); err != nil || stored.Username != "<token>" || stored.Secret != "refresh" {
+		t.Fatalf("expected the identity token to be stored by the helper, got %s", data)
+	}
+	if config, _ := os.ReadFile(path); strings.Contains(string(config), "refresh") {
+		t.Fatal("expected no secret in config.json")
+	}
+}
+
+func TestPullImage_SendsRegistryAuth(t *testing.T) {
+	writeDockerConfig(t, `{"auths": {"registry.example.com": {"auth": "`+basicAuth("ci", "s3cret")+`"}}}`)
+	var sent string
+	mock := &MockDockerClient{
+		ImagePullFn: func(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+			sent = options.RegistryAuth
+			return io.NopCloser(strings.NewReader(pullStream)), nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	if err := engine.EnsureImage(context.Background(), "registry.example.com/team/api:1.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, _ := registry.DecodeAuthConfig(sent)
+	if decoded.Username != "ci" || decoded.Password != "s3cret" || decoded.ServerAddress != "registry.example.com" {
+		t.Fatalf("unexpected RegistryAuth: %+v", decoded)
+	}
+
+	sent = "unset"
+	engine.PullImage(context.Background(), "alpine", pkg.PullOptions{Policy: pkg.PullAlways, Out: &bytes.Buffer{}})
+	if sent != "" {
+		t.Fatalf("expected an anonymous pull for Docker Hub, got %q", sent)
+	}
+}
+
+func TestLogin_UsesIdentityToken(t *testing.T) {
+	mock := &MockDockerClient{
+		RegistryLoginFn: func(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error) {
+			if auth.Password != "s3cret" {
+				t.Errorf("unexpected password %q", auth.Password)
+			}
+			return registry.AuthenticateOKBody{Status: "Login Succeeded", IdentityToken: "refresh"}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	auth, err := engine.Login(context.Background(), registry.AuthConfig{Username: "ci", Password: "s3cret", ServerAddress: "registry.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if auth.Password != "" || auth.IdentityToken != "refresh" {
+		t.Fatalf("expected the identity token to replace the password, got %+v", auth)
+	}
+}
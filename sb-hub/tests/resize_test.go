@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestResizeSandbox_UpdatesLimitsAndMetadata(t *testing.T) {
+	var update container.UpdateConfig
+	mock := &MockDockerClient{
+		ContainerUpdateFn: func(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.UpdateResponse, error) {
+			update = updateConfig
+			return container.UpdateResponse{}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock, Meta: &pkg.MetaStore{Dir: t.TempDir()}}
+
+	if err := engine.ResizeSandbox(context.Background(), "box", "large", pkg.SandboxSpecs["large"].DefaultTTL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.NanoCPUs != 4e9 || update.Memory != 8192*1024*1024 {
+		t.Fatalf("unexpected resources: %+v", update.Resources)
+	}
+
+	labels := engine.EffectiveLabels("box", map[string]string{"com.sbhub.size": "small", "com.sbhub.hostport": "8001"})
+	if labels["com.sbhub.size"] != "large" || labels["com.sbhub.hostport"] != "8001" {
+		t.Fatalf("expected size override on top of labels, got %v", labels)
+	}
+	expiry, err := time.Parse(time.RFC3339, labels["com.sbhub.expires"])
+	if err != nil || time.Until(expiry) > 2*time.Hour || time.Until(expiry) < 119*time.Minute {
+		t.Fatalf("expected expiry reset to the large TTL, got %s", labels["com.sbhub.expires"])
+	}
+}
+
+func TestResizeSandbox_KeepsOrSetsExpiry(t *testing.T) {
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}, Meta: &pkg.MetaStore{Dir: t.TempDir()}}
+
+	if err := engine.ResizeSandbox(context.Background(), "box", "medium", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels := engine.EffectiveLabels("box", map[string]string{"com.sbhub.expires": "keep"}); labels["com.sbhub.expires"] != "keep" {
+		t.Fatalf("expected expiry to be kept without a ttl, got %s", labels["com.sbhub.expires"])
+	}
+
+	// A ttl clamped by the owner's policy replaces the preset default
+	if err := engine.ResizeSandbox(context.Background(), "box", "medium", 30*time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expiry, err := time.Parse(time.RFC3339, engine.EffectiveLabels("box", nil)["com.sbhub.expires"])
+	if err != nil || time.Until(expiry) > 30*time.Minute || time.Until(expiry) < 29*time.Minute {
+		t.Fatalf("expected expiry 30m from now, got %v (%v)", expiry, err)
+	}
+}
+
+func TestResizeSandbox_UnknownSize(t *testing.T) {
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}, Meta: &pkg.MetaStore{Dir: t.TempDir()}}
+	if err := engine.ResizeSandbox(context.Background(), "box", "huge", 0); err == nil {
+		t.Fatal("expected error for unknown size")
+	}
+}
+
+func TestCreateSandbox_ClearsMetadata(t *testing.T) {
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	store.SetLabels("box", map[string]string{"com.sbhub.size": "large"})
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}, Meta: store}
+
+	if _, err := engine.CreateSandbox(context.Background(), "box", time.Hour, "medium", &container.Config{}, &container.HostConfig{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta, _ := store.Load("box"); len(meta.Labels) != 0 {
+		t.Fatalf("expected metadata to be cleared, got %v", meta.Labels)
+	}
+}
+
+func TestGetExpiredSandboxes_MetadataOverride(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	store := &pkg.MetaStore{Dir: t.TempDir()}
+	store.SetLabels("box", map[string]string{"com.sbhub.expires": past})
+
+	mock := &MockDockerClient{
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			return []container.Summary{{Names: []string{"/box"}, Labels: map[string]string{"com.sbhub.expires": future}}}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock, Meta: store}
+
+	expired, err := engine.GetExpiredSandboxes(context.Background())
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("expected the recorded expiry to win, got %v (%v)", expired, err)
+	}
+}
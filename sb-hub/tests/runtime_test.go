@@ -0,0 +1,318 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// missingContainer is how the Docker client reports an unknown container.
+type missingContainer struct{}
+
+func (missingContainer) Error() string { return "No such container: ghost" }
+func (missingContainer) NotFound()     {}
+
+var (
+	_ pkg.Runtime = (*pkg.DockerRuntime)(nil)
+	_ pkg.Runtime = (*pkg.PodmanRuntime)(nil)
+	_ pkg.Runtime = (*pkg.FakeRuntime)(nil)
+)
+
+func TestDockerRuntime_Create(t *testing.T) {
+	var gotConfig *container.Config
+	var gotHost *container.HostConfig
+	started := false
+	mock := &MockDockerClient{
+		ContainerCreateFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+			gotConfig, gotHost = config, hostConfig
+			return container.CreateResponse{ID: "abc123"}, nil
+		},
+		ContainerStartFn: func(ctx context.Context, containerID string, options container.StartOptions) error {
+			started = true
+			return nil
+		},
+	}
+	rt := pkg.NewDockerRuntime(mock)
+	rt.Engine.Meta = pkg.MetaStoreFor(t.TempDir())
+	rt.Engine.Meta.SetLabels("box", map[string]string{"com.sbhub.size": "large"})
+
+	labels := map[string]string{"com.sbhub.owner": "alice"}
+	id, err := rt.Create(context.Background(), pkg.SandboxConfig{
+		Name:      "box",
+		Image:     "alpine:latest",
+		Size:      "medium",
+		TTL:       time.Hour,
+		Labels:    labels,
+		Ports:     map[string]string{"80/tcp": "8004"},
+		Network:   "sb-hub-net",
+		HealthCmd: "true",
+	})
+	if err != nil || id != "abc123" {
+		t.Fatalf("unexpected result %q (%v)", id, err)
+	}
+	if started {
+		t.Fatal("Create should not start the sandbox")
+	}
+	if gotConfig.Labels["com.sbhub.managed"] != "true" || gotConfig.Labels["com.sbhub.size"] != "medium" || gotConfig.Labels["com.sbhub.owner"] != "alice" {
+		t.Fatalf("unexpected labels: %v", gotConfig.Labels)
+	}
+	if len(labels) != 1 {
+		t.Fatalf("caller's labels were modified: %v", labels)
+	}
+	if !gotConfig.Tty || gotConfig.Healthcheck == nil {
+		t.Fatalf("expected a TTY and a healthcheck: %+v", gotConfig)
+	}
+	if gotHost.NanoCPUs != 2e9 || gotHost.Memory != 4096<<20 || gotHost.NetworkMode != "sb-hub-net" {
+		t.Fatalf("unexpected host config: %+v", gotHost)
+	}
+	if b := gotHost.PortBindings[nat.Port("80/tcp")]; len(b) != 1 || b[0].HostPort != "8004" {
+		t.Fatalf("unexpected port bindings: %v", gotHost.PortBindings)
+	}
+	if _, ok := gotConfig.ExposedPorts[nat.Port("80/tcp")]; !ok {
+		t.Fatalf("expected 80/tcp to be exposed: %v", gotConfig.ExposedPorts)
+	}
+	if m, _ := rt.Engine.Meta.Load("box"); len(m.Labels) != 0 {
+		t.Fatalf("expected label overrides to be cleared, got %v", m.Labels)
+	}
+
+	if _, err := rt.Create(context.Background(), pkg.SandboxConfig{Name: "box", Size: "huge"}); err == nil {
+		t.Fatal("expected unknown size error")
+	}
+}
+
+func TestDockerRuntime_InspectAndList(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerInspectFn: func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			if containerID != "box" {
+				return container.InspectResponse{}, missingContainer{}
+			}
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:      "abc123",
+					Name:    "/box",
+					Created: "2026-10-18T09:00:00Z",
+					State: &container.State{
+						Status:    "running",
+						Running:   true,
+						StartedAt: "2026-10-18T09:00:01Z",
+						Health:    &container.Health{Status: container.Healthy},
+					},
+				},
+				Config: &container.Config{Image: "alpine:latest", Labels: map[string]string{"com.sbhub.size": "small"}},
+				NetworkSettings: &container.NetworkSettings{NetworkSettingsBase: container.NetworkSettingsBase{
+					Ports: nat.PortMap{"80/tcp": {{HostIP: "0.0.0.0", HostPort: "8004"}}},
+				}},
+			}, nil
+		},
+		ContainerListFn: func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			if !options.All || !options.Filters.ExactMatch("label", "com.sbhub.managed=true") {
+				t.Errorf("expected all managed sandboxes, got %+v", options)
+			}
+			return []container.Summary{{
+				ID:     "abc123",
+				Names:  []string{"/box"},
+				State:  "running",
+				Status: "Up 5 minutes (unhealthy)",
+				Ports:  []container.Port{{PrivatePort: 80, PublicPort: 8004, Type: "tcp"}},
+			}}, nil
+		},
+	}
+	rt := pkg.NewDockerRuntime(mock)
+	ctx := context.Background()
+
+	info, err := rt.Inspect(ctx, "box")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Name != "box" || !info.Running() || info.Health != "healthy" || info.Ports["80/tcp"] != "8004" || info.StartedAt.IsZero() {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if _, err := rt.Inspect(ctx, "ghost"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	infos, err := rt.List(ctx)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("unexpected list %+v (%v)", infos, err)
+	}
+	if infos[0].Name != "box" || infos[0].Health != "unhealthy" || infos[0].Ports["80/tcp"] != "8004" {
+		t.Fatalf("unexpected summary: %+v", infos[0])
+	}
+}
+
+func TestRelabelBinds(t *testing.T) {
+	got := pkg.RelabelBinds([]string{"/srv/box:/data", "/etc/ca:/ca:ro", "/srv/x:/x:ro,Z", "cache:/cache"})
+	want := []string{"/srv/box:/data:z", "/etc/ca:/ca:ro,z", "/srv/x:/x:ro,Z", "cache:/cache"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected binds: %v", got)
+	}
+}
+
+func TestPodmanRuntime_Relabel(t *testing.T) {
+	var binds []string
+	mock := &MockDockerClient{
+		ContainerCreateFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+			binds = hostConfig.Binds
+			return container.CreateResponse{ID: "abc123"}, nil
+		},
+	}
+	rt := pkg.NewPodmanRuntime(mock, false)
+	rt.Relabel = true
+	if rt.Name() != pkg.RuntimePodman {
+		t.Fatalf("unexpected name %s", rt.Name())
+	}
+	if _, err := rt.Create(context.Background(), pkg.SandboxConfig{Name: "box", Size: "small", Binds: []string{"/srv/box:/data"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(binds, []string{"/srv/box:/data:z"}) {
+		t.Fatalf("expected relabelled binds, got %v", binds)
+	}
+}
+
+func TestCreateSandbox_Relabel(t *testing.T) {
+	var binds []string
+	mock := &MockDockerClient{
+		ContainerCreateFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+			binds = hostConfig.Binds
+			return container.CreateResponse{ID: "abc123"}, nil
+		},
+	}
+	// attach, sync --mode bind and apply create through the engine
+	engine := &pkg.Dockerengine{Client: mock, Relabel: true}
+	hostConfig := &container.HostConfig{Binds: []string{"/srv/box:/data", "/home/me/src:/app"}}
+	if _, err := engine.CreateSandbox(context.Background(), "box", time.Hour, "small", &container.Config{}, hostConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(binds, []string{"/srv/box:/data:z", "/home/me/src:/app:z"}) {
+		t.Fatalf("expected relabelled binds, got %v", binds)
+	}
+
+	for _, ep := range []pkg.Endpoint{{}, {Host: "ssh://owen@gpu01", Runtime: pkg.RuntimePodman}} {
+		if ep.Relabels() {
+			t.Errorf("expected no relabelling for %+v", ep)
+		}
+	}
+}
+
+func TestPodmanSocket(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "unix:///tmp/podman.sock")
+	if got := pkg.PodmanSocket(); got != "unix:///tmp/podman.sock" {
+		t.Fatalf("expected CONTAINER_HOST, got %s", got)
+	}
+
+	t.Setenv("CONTAINER_HOST", "")
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	if os.Getuid() != 0 {
+		os.MkdirAll(filepath.Join(dir, "podman"), 0755)
+		os.WriteFile(filepath.Join(dir, "podman", "podman.sock"), nil, 0644)
+		if got := pkg.PodmanSocket(); got != "unix://"+filepath.Join(dir, "podman", "podman.sock") {
+			t.Fatalf("expected the rootless socket, got %s", got)
+		}
+	} else if got := pkg.PodmanSocket(); got != "unix:///run/podman/podman.sock" {
+		t.Fatalf("expected the rootful socket, got %s", got)
+	}
+}
+
+func TestNewRuntime(t *testing.T) {
+	rt, err := pkg.NewRuntime(pkg.Endpoint{Host: "tcp://build01:2375"})
+	if err != nil || rt.Name() != pkg.RuntimeDocker {
+		t.Fatalf("expected docker runtime, got %v (%v)", rt, err)
+	}
+	rt, err = pkg.NewRuntime(pkg.Endpoint{Host: "unix:///run/podman/podman.sock", Runtime: pkg.RuntimePodman})
+	if err != nil || rt.Name() != pkg.RuntimePodman {
+		t.Fatalf("expected podman runtime, got %v (%v)", rt, err)
+	}
+	if err := (pkg.Endpoint{Runtime: "lxc"}).Validate(); err == nil {
+		t.Fatal("expected unknown runtime error")
+	}
+
+	engine := &pkg.Dockerengine{Client: &MockDockerClient{}}
+	rt, err = pkg.RuntimeFor(pkg.Endpoint{Host: "ssh://owen@gpu01", Runtime: pkg.RuntimePodman}, engine)
+	if err != nil || rt.Name() != pkg.RuntimePodman {
+		t.Fatalf("expected podman runtime, got %v (%v)", rt, err)
+	}
+	if p := rt.(*pkg.PodmanRuntime); p.Engine != engine || p.Relabel {
+		t.Fatalf("expected the engine to be shared and remote binds left alone: %+v", p)
+	}
+
+	fake := pkg.Endpoint{Runtime: pkg.RuntimeFake}
+	if err := fake.Validate(); err != nil {
+		t.Fatalf("expected the fake runtime to be accepted: %v", err)
+	}
+	if rt, err := pkg.RuntimeFor(fake, engine); err != nil || rt.Name() != pkg.RuntimeFake {
+		t.Fatalf("expected fake runtime, got %v (%v)", rt, err)
+	}
+	if rt, err := pkg.NewRuntime(fake); err != nil || rt.Name() != pkg.RuntimeFake {
+		t.Fatalf("expected fake runtime without a client, got %v (%v)", rt, err)
+	}
+}
+
+func TestFakeRuntime_Lifecycle(t *testing.T) {
+	rt := pkg.NewFakeRuntime()
+	ctx := context.Background()
+
+	if _, err := rt.Create(ctx, pkg.SandboxConfig{Name: "box", Image: "alpine", Size: "small", TTL: time.Hour}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := rt.Create(ctx, pkg.SandboxConfig{Name: "box", Size: "small"}); err == nil {
+		t.Fatal("expected a name conflict")
+	}
+	if _, err := rt.Exec(ctx, "box", pkg.ExecRequest{Cmd: []string{"true"}}); err == nil {
+		t.Fatal("expected exec to fail before start")
+	}
+	rt.Start(ctx, "box")
+
+	rt.ExecFn = func(name string, req pkg.ExecRequest) (int, error) { return 3, nil }
+	if code, err := rt.Exec(ctx, "box", pkg.ExecRequest{Cmd: []string{"false"}}); err != nil || code != 3 {
+		t.Fatalf("unexpected exec result %d (%v)", code, err)
+	}
+
+	info, err := rt.Inspect(ctx, "box")
+	if err != nil || !info.Running() || info.Labels["com.sbhub.managed"] != "true" {
+		t.Fatalf("unexpected info: %+v (%v)", info, err)
+	}
+	rt.Stop(ctx, "box", time.Second)
+	if infos, _ := rt.List(ctx); len(infos) != 1 || infos[0].State != "exited" {
+		t.Fatalf("unexpected list: %+v", infos)
+	}
+	rt.Remove(ctx, "box")
+	if _, err := rt.Inspect(ctx, "box"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	want := []string{"create box", "start box", "exec box false", "stop box", "remove box"}
+	if !reflect.DeepEqual(rt.Calls, want) {
+		t.Fatalf("unexpected calls: %v", rt.Calls)
+	}
+}
+
+func TestFakeRuntime_Logs(t *testing.T) {
+	rt := pkg.NewFakeRuntime()
+	ctx := context.Background()
+	rt.Create(ctx, pkg.SandboxConfig{Name: "box", Size: "small"})
+	rt.WriteLog("box", "stdout", "listening on :80")
+	rt.WriteLog("box", "stderr", "GET / 500")
+	rt.WriteLog("box", "stdout", "GET / 200")
+
+	var got []string
+	err := rt.Logs(ctx, "box", pkg.LogOptions{Tail: "1", Grep: regexp.MustCompile("GET")}, func(line pkg.LogLine) error {
+		got = append(got, line.Stream+" "+line.Text)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(got, []string{"stdout GET / 200"}) {
+		t.Fatalf("unexpected lines %v (%v)", got, err)
+	}
+}
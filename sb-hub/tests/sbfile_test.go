@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func writeSandboxFile(t *testing.T, content string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "myproj")
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, "sbhub.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write sbhub.yaml: %v", err)
+	}
+	return path
+}
+
+func TestLoadSandboxFile_Defaults(t *testing.T) {
+	path := writeSandboxFile(t, `
+sandboxes:
+  web:
+    image: nginx:latest
+    ports: ["80", "8443:443"]
+    env:
+      APP_ENV: dev
+  api:
+    preset: medium
+    build: ./api
+    ttl: 3h
+`)
+
+	f, err := pkg.LoadSandboxFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Project != "myproj" {
+		t.Fatalf("expected project 'myproj', got '%s'", f.Project)
+	}
+	if f.Sandboxes["web"].Preset != "small" {
+		t.Fatalf("expected default preset 'small', got '%s'", f.Sandboxes["web"].Preset)
+	}
+	if f.SandboxName("api") != "myproj-api" {
+		t.Fatalf("expected name 'myproj-api', got '%s'", f.SandboxName("api"))
+	}
+	if got := f.ResolvePath(f.Sandboxes["api"].Build); got != filepath.Join(f.Dir, "api") {
+		t.Fatalf("expected build path resolved against file dir, got '%s'", got)
+	}
+}
+
+func TestLoadSandboxFile_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"bad preset":    "sandboxes:\n  a:\n    preset: huge\n",
+		"image + build": "sandboxes:\n  a:\n    image: alpine\n    build: .\n",
+		"bad ttl":       "sandboxes:\n  a:\n    ttl: forever\n",
+		"negative ttl":  "sandboxes:\n  a:\n    ttl: -1h\n",
+		"zero ttl":      "sandboxes:\n  a:\n    ttl: 0s\n",
+		"bad port":      "sandboxes:\n  a:\n    ports: [\"1:2:3\"]\n",
+		"bad mount":     "sandboxes:\n  a:\n    mounts: [\"./src\"]\n",
+		"relative dst":  "sandboxes:\n  a:\n    mounts: [\"./src:app\"]\n",
+		"bad mode":      "sandboxes:\n  a:\n    mounts: [\"./src:/app:rx\"]\n",
+		"empty":         "sandboxes: {}\n",
+	}
+	for name, content := range cases {
+		if _, err := pkg.LoadSandboxFile(writeSandboxFile(t, content)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestSandboxFile_MountBind(t *testing.T) {
+	f, err := pkg.LoadSandboxFile(writeSandboxFile(t, "sandboxes:\n  a:\n    mounts: [\"./static:/srv:ro\", \"/opt/data:/data2\"]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bind, err := f.MountBind(f.Sandboxes["a"].Mounts[0])
+	if err != nil || bind != filepath.Join(f.Dir, "static")+":/srv:ro" {
+		t.Fatalf("expected source resolved against file dir, got '%s' %v", bind, err)
+	}
+	bind, err = f.MountBind(f.Sandboxes["a"].Mounts[1])
+	if err != nil || bind != "/opt/data:/data2" {
+		t.Fatalf("expected absolute source kept, got '%s' %v", bind, err)
+	}
+}
+
+func TestSandboxDefinition_HashChanges(t *testing.T) {
+	a := pkg.SandboxDefinition{Preset: "small", Image: "alpine"}
+	b := pkg.SandboxDefinition{Preset: "small", Image: "alpine"}
+	if a.Hash() != b.Hash() {
+		t.Fatal("expected identical definitions to hash the same")
+	}
+	b.Env = map[string]string{"A": "1"}
+	if a.Hash() == b.Hash() {
+		t.Fatal("expected env change to alter the hash")
+	}
+}
+
+func TestPlanSandboxes(t *testing.T) {
+	f := &pkg.SandboxFile{
+		Project: "proj",
+		Sandboxes: map[string]pkg.SandboxDefinition{
+			"new":     {Preset: "small"},
+			"same":    {Preset: "small", Image: "alpine"},
+			"changed": {Preset: "large"},
+		},
+	}
+	active := map[string]container.Summary{
+		"proj-same":    {Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.project": "proj", "com.sbhub.spec-hash": f.Sandboxes["same"].Hash()}},
+		"proj-changed": {Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.project": "proj", "com.sbhub.spec-hash": "stale"}},
+		"proj-old":     {Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.project": "proj"}},
+		"other":        {Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.project": "elsewhere"}},
+	}
+
+	steps := pkg.PlanSandboxes(f, active)
+	got := make(map[string]pkg.PlanAction)
+	for _, s := range steps {
+		got[s.Name] = s.Action
+	}
+
+	expected := map[string]pkg.PlanAction{
+		"proj-new":     pkg.PlanCreate,
+		"proj-same":    pkg.PlanUnchanged,
+		"proj-changed": pkg.PlanRecreate,
+		"proj-old":     pkg.PlanRemove,
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d steps, got %d: %v", len(expected), len(got), got)
+	}
+	for name, action := range expected {
+		if got[name] != action {
+			t.Errorf("%s: expected %s, got %s", name, action, got[name])
+		}
+	}
+}
+
+func TestPlanSandboxes_ForeignContainers(t *testing.T) {
+	f := &pkg.SandboxFile{
+		Project: "proj",
+		Sandboxes: map[string]pkg.SandboxDefinition{
+			"web": {Preset: "small"},
+			"db":  {Preset: "small"},
+			"box": {Preset: "small"},
+		},
+	}
+	active := map[string]container.Summary{
+		"proj-web": {Labels: map[string]string{"com.sbhub.managed": "true", "com.sbhub.project": "shop"}},
+		"proj-db":  {Labels: map[string]string{"maintainer": "postgres"}},
+		"proj-box": {Labels: map[string]string{"com.sbhub.managed": "true"}},
+		// Unmanaged containers are never pruned, whatever their labels say
+		"proj-gone": {Labels: map[string]string{"com.sbhub.project": "proj"}},
+	}
+
+	steps := pkg.PlanSandboxes(f, active)
+	if len(steps) != 3 {
+		t.Fatalf("expected 3 steps, got %+v", steps)
+	}
+	reasons := map[string]string{
+		"proj-box": "created outside any project",
+		"proj-db":  "not managed by sb-hub",
+		"proj-web": "owned by project shop",
+	}
+	for _, s := range steps {
+		if s.Action != pkg.PlanConflict || s.Reason != reasons[s.Name] {
+			t.Errorf("%s: expected conflict (%s), got %s (%s)", s.Name, reasons[s.Name], s.Action, s.Reason)
+		}
+	}
+}
+
+func TestParsePortMapping(t *testing.T) {
+	if host, ctr, err := pkg.ParsePortMapping("8080:80/udp"); err != nil || host != "8080" || ctr != "80/udp" {
+		t.Fatalf("unexpected result %q %q (%v)", host, ctr, err)
+	}
+	for _, bad := range []string{"http", "0", "70000", "abc:80", "8080:", "80/icmp", "-1:80"} {
+		if _, _, err := pkg.ParsePortMapping(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
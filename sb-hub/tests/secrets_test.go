@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+)
+
+func TestSecretStore_RoundTrip(t *testing.T) {
+	t.Setenv("SBHUB_SECRETS_KEY", "")
+	dir := t.TempDir()
+	store, err := pkg.OpenSecretStore(filepath.Join(dir, "store"), filepath.Join(dir, "secrets.key"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := store.Set("db_password", []byte("hunter2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, _ := os.ReadFile(filepath.Join(dir, "store", "db_password.enc"))
+	if strings.Contains(string(raw), "hunter2") {
+		t.Fatal("expected secret to be encrypted at rest")
+	}
+
+	value, err := store.Get("db_password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(value) != "hunter2" {
+		t.Fatalf("expected 'hunter2', got '%s'", value)
+	}
+
+	names, _ := store.List()
+	if !reflect.DeepEqual(names, []string{"db_password"}) {
+		t.Fatalf("expected [db_password], got %v", names)
+	}
+
+	path, err := store.Materialize("db_password", filepath.Join(dir, "mounted"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hunter2" {
+		t.Fatalf("expected materialized secret 'hunter2', got '%s'", data)
+	}
+
+	if err := store.Remove("db_password"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get("db_password"); err == nil {
+		t.Fatal("expected error for removed secret")
+	}
+}
+
+func TestSecretStore_WrongKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SBHUB_SECRETS_KEY", "right")
+	store, _ := pkg.OpenSecretStore(dir, filepath.Join(dir, "unused.key"))
+	store.Set("token", []byte("abc"))
+
+	t.Setenv("SBHUB_SECRETS_KEY", "wrong")
+	store, _ = pkg.OpenSecretStore(dir, filepath.Join(dir, "unused.key"))
+	if _, err := store.Get("token"); err == nil {
+		t.Fatal("expected decryption to fail with the wrong key")
+	}
+}
+
+func TestParseSecretFlag(t *testing.T) {
+	name, path, err := pkg.ParseSecretFlag("api_key=/tmp/key.txt")
+	if err != nil || name != "api_key" || path != "/tmp/key.txt" {
+		t.Fatalf("unexpected result: %s %s %v", name, path, err)
+	}
+	name, path, err = pkg.ParseSecretFlag("api_key")
+	if err != nil || name != "api_key" || path != "" {
+		t.Fatalf("unexpected result: %s %s %v", name, path, err)
+	}
+	for _, bad := range []string{"../etc/passwd=/x", ".", "..", "..=/x", ".hidden"} {
+		if _, _, err := pkg.ParseSecretFlag(bad); err == nil {
+			t.Errorf("expected error for path-like secret name '%s'", bad)
+		}
+	}
+	if bind := pkg.SecretBind("api_key", "/tmp/key.txt"); bind != "/tmp/key.txt:/run/secrets/api_key:ro" {
+		t.Fatalf("unexpected bind '%s'", bind)
+	}
+}
+
+func TestBuildEnv_FlagsOverrideFiles(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envFile, []byte("# comment\nAPP_ENV=prod\nexport NAME=\"quoted value\"\n\nPORT=80\n"), 0644)
+	t.Setenv("SBHUB_PASSTHROUGH", "from-host")
+
+	env, err := pkg.BuildEnv([]string{envFile}, []string{"APP_ENV=dev", "SBHUB_PASSTHROUGH", "SBHUB_UNSET_VAR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"APP_ENV=dev", "NAME=quoted value", "PORT=80", "SBHUB_PASSTHROUGH=from-host"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("expected %v, got %v", expected, env)
+	}
+}
+
+func TestParseEnvFile_Invalid(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envFile, []byte("NOT_A_PAIR\n"), 0644)
+	if _, err := pkg.ParseEnvFile(envFile); err == nil {
+		t.Fatal("expected error for line without '='")
+	}
+}
+
+func TestRemoveMountedSecrets(t *testing.T) {
+	root := t.TempDir()
+	store, err := pkg.OpenSecretStore(filepath.Join(root, ".secrets", "store"), filepath.Join(root, "key"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set("token", []byte("s3cret"))
+	path, err := store.Materialize("token", pkg.MountedSecretsDir(root, "box"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, _ := store.Materialize("token", pkg.MountedSecretsDir(root, "other"))
+
+	if err := pkg.RemoveMountedSecrets(root, "box"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Fatalf("expected the plaintext secrets to be gone, got %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("expected other sandboxes' secrets to stay, got %v", err)
+	}
+	if err := pkg.RemoveMountedSecrets(root, "box"); err != nil {
+		t.Fatalf("expected removing twice to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+)
+
+func TestSessionRecorder_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := pkg.NewSessionRecorder(&buf, pkg.TermSize{Height: 24, Width: 80}, "box", map[string]string{"SHELL": "/bin/bash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	euro := []byte("€")
+	rec.Write([]byte("$ ls\r\n"))
+	rec.Write(euro[:1])
+	rec.Write(euro[1:])
+	rec.Resize(pkg.TermSize{Height: 40, Width: 100})
+	rec.Close()
+
+	header, events, err := pkg.ReadCast(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Env["SHELL"] != "/bin/bash" {
+		t.Fatalf("unexpected header: %+v", header)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+	if events[0].Type != "o" || events[0].Data != "$ ls\r\n" {
+		t.Fatalf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Type != "o" || events[1].Data != "€" {
+		t.Fatalf("expected split character to be joined, got %+v", events[1])
+	}
+	if events[2].Type != "r" || events[2].Data != "100x40" {
+		t.Fatalf("unexpected resize event: %+v", events[2])
+	}
+}
+
+func TestPlayCast_WritesOutputOnly(t *testing.T) {
+	cast := `{"version": 2, "width": 80, "height": 24, "timestamp": 1700000000}
+[0.1, "o", "hello "]
+[0.2, "r", "100x40"]
+[5.0, "o", "world"]
+`
+	var out bytes.Buffer
+	start := time.Now()
+	if err := pkg.PlayCast(bytes.NewBufferString(cast), &out, 10, 50*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "hello world" {
+		t.Fatalf("expected output events only, got %q", out.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected idle limit to shorten playback, took %s", elapsed)
+	}
+}
+
+func TestReadCast_RejectsOtherVersions(t *testing.T) {
+	if _, _, err := pkg.ReadCast(bytes.NewBufferString(`{"version": 1}` + "\n")); err == nil {
+		t.Fatal("expected error for asciicast v1")
+	}
+}
+
+func TestListAndFindSessions(t *testing.T) {
+	dir := t.TempDir()
+	casts := map[string]string{
+		"box-late":  `{"version": 2, "width": 80, "height": 24, "timestamp": 1700000100}` + "\n" + `[3.5, "o", "b"]` + "\n",
+		"box-early": `{"version": 2, "width": 80, "height": 24, "timestamp": 1700000000}` + "\n" + `[1.0, "o", "a"]` + "\n",
+	}
+	for id, cast := range casts {
+		path := pkg.SessionPath(dir, "box", id)
+		os.MkdirAll(filepath.Dir(path), 0700)
+		os.WriteFile(path, []byte(cast), 0600)
+	}
+
+	sessions, err := pkg.ListSessions(dir, "box")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "box-early" || sessions[1].ID != "box-late" {
+		t.Fatalf("expected sessions oldest first, got %+v", sessions)
+	}
+	if sessions[1].Duration != 3500*time.Millisecond {
+		t.Fatalf("expected duration 3.5s, got %s", sessions[1].Duration)
+	}
+
+	path, err := pkg.FindSession(dir, "box-late")
+	if err != nil || path != sessions[1].Path {
+		t.Fatalf("expected to find %s, got %s (%v)", sessions[1].Path, path, err)
+	}
+	if _, err := pkg.FindSession(dir, "missing"); err == nil {
+		t.Fatal("expected error for unknown session")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := pkg.LoadConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil || cfg.Console.Record {
+		t.Fatalf("expected empty config for missing file, got %+v (%v)", cfg, err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("console:\n  record: true\n"), 0600)
+	cfg, err = pkg.LoadConfig(path)
+	if err != nil || !cfg.Console.Record {
+		t.Fatalf("expected console.record to be set, got %+v (%v)", cfg, err)
+	}
+}
+
+func TestNewSessionID_Unique(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 15, 0, 0, time.UTC)
+	a, b := pkg.NewSessionID("box", now), pkg.NewSessionID("box", now)
+	if a == b {
+		t.Fatalf("expected distinct IDs in the same second, got %s twice", a)
+	}
+	if !strings.HasPrefix(a, "box-20261018T101500Z-") {
+		t.Fatalf("unexpected ID: %s", a)
+	}
+}
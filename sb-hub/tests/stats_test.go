@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func sampleStatsResponse() container.StatsResponse {
+	return container.StatsResponse{
+		CPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 300},
+			SystemUsage: 2000,
+			OnlineCPUs:  4,
+		},
+		PreCPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 200},
+			SystemUsage: 1000,
+		},
+		MemoryStats: container.MemoryStats{
+			Usage: 300 << 20,
+			Limit: 512 << 20,
+			Stats: map[string]uint64{"inactive_file": 44 << 20},
+		},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 100, TxBytes: 50},
+			"eth1": {RxBytes: 10, TxBytes: 5},
+		},
+		BlkioStats: container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+			{Op: "read", Value: 4096},
+			{Op: "Write", Value: 1024},
+		}},
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	s := pkg.ComputeStats("box", "small", sampleStatsResponse())
+
+	// 100/1000 of the host across 4 CPUs = 40% of one core, 80% of 0.5 cores
+	if s.CPUPercent != 40 || s.CPULimitPercent != 80 {
+		t.Fatalf("unexpected CPU: %.1f%% (%.1f%% of limit)", s.CPUPercent, s.CPULimitPercent)
+	}
+	if s.MemUsage != 256<<20 || s.MemPercent != 50 {
+		t.Fatalf("expected cache to be excluded, got %d bytes (%.1f%%)", s.MemUsage, s.MemPercent)
+	}
+	if s.NetRx != 110 || s.NetTx != 55 || s.BlockRead != 4096 || s.BlockWrite != 1024 {
+		t.Fatalf("unexpected IO totals: %+v", s)
+	}
+}
+
+func TestSampleStats_OneShot(t *testing.T) {
+	var streamed bool
+	mock := &MockDockerClient{
+		ContainerStatsFn: func(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error) {
+			streamed = stream
+			body := `{"cpu_stats":{"cpu_usage":{"total_usage":300},"system_cpu_usage":2000,"online_cpus":4},` +
+				`"precpu_stats":{"cpu_usage":{"total_usage":200},"system_cpu_usage":1000},"memory_stats":{"usage":1024,"limit":4096}}`
+			return container.StatsResponseReader{Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	s, err := engine.SampleStats(context.Background(), "box", "medium")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streamed {
+		t.Fatal("expected a one-shot stats request")
+	}
+	if s.CPUPercent != 40 || s.CPULimitPercent != 20 || s.MemPercent != 25 {
+		t.Fatalf("unexpected sample: %+v", s)
+	}
+}
+
+func TestWatchStats_DecodesStream(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerStatsFn: func(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error) {
+			body := `{"memory_stats":{"usage":1,"limit":4}}` + "\n" + `{"memory_stats":{"usage":2,"limit":4}}` + "\n"
+			return container.StatsResponseReader{Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	var mem []float64
+	err := engine.WatchStats(context.Background(), "box", "small", func(s pkg.SandboxStats) {
+		mem = append(mem, s.MemPercent)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mem) != 2 || mem[0] != 25 || mem[1] != 50 {
+		t.Fatalf("unexpected samples: %v", mem)
+	}
+}
+
+func TestLimitWatch_WarnsOncePerStreak(t *testing.T) {
+	w := pkg.NewLimitWatch(90, 3)
+	hot := pkg.SandboxStats{Name: "box", Size: "small", MemPercent: 95}
+	cool := pkg.SandboxStats{Name: "box", Size: "small", MemPercent: 10}
+
+	var warnings int
+	for _, s := range []pkg.SandboxStats{hot, hot, hot, hot, cool, hot, hot, hot} {
+		warnings += len(w.Observe(s))
+	}
+	if warnings != 2 {
+		t.Fatalf("expected one warning per streak, got %d", warnings)
+	}
+}
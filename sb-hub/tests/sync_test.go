@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestIgnoreMatcher_GitignoreSemantics(t *testing.T) {
+	m, err := pkg.NewIgnoreMatcher([]string{"# comment", "node_modules/", "*.log", "/build", "!keep.log", "docs/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ignored := []string{"node_modules", "web/node_modules/pkg/index.js", "app.log", "logs/app.log", "build/out", "docs/tmp/x"}
+	for _, p := range ignored {
+		if !m.Ignored(filepath.FromSlash(p)) {
+			t.Errorf("expected '%s' to be ignored", p)
+		}
+	}
+	kept := []string{"src/main.go", "src/build/x", "keep.log", "tmp/a"}
+	for _, p := range kept {
+		if m.Ignored(filepath.FromSlash(p)) {
+			t.Errorf("expected '%s' to be kept", p)
+		}
+	}
+
+	var nilMatcher *pkg.IgnoreMatcher
+	if nilMatcher.Ignored("anything") {
+		t.Fatal("nil matcher should ignore nothing")
+	}
+}
+
+func TestLoadIgnoreMatcher_ReadsBothFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("dist\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".sbignore"), []byte("secrets.txt\n"), 0644)
+
+	m, err := pkg.LoadIgnoreMatcher(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range []string{".git", "dist", "secrets.txt"} {
+		if !m.Ignored(p) {
+			t.Errorf("expected '%s' to be ignored", p)
+		}
+	}
+}
+
+func TestParseSyncSpec(t *testing.T) {
+	dir := t.TempDir()
+	local, remote, err := pkg.ParseSyncSpec(dir + ":/app/src/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if local != dir || remote != "/app/src" {
+		t.Fatalf("unexpected result: %s %s", local, remote)
+	}
+	for _, bad := range []string{dir, dir + ":app", dir + ":", filepath.Join(dir, "missing") + ":/app"} {
+		if _, _, err := pkg.ParseSyncSpec(bad); err == nil {
+			t.Errorf("expected error for '%s'", bad)
+		}
+	}
+}
+
+func tarNames(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	var names []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid tar: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPushTree_PrefixesAndIgnores(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.MkdirAll(filepath.Join(dir, "node_modules", "x"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644)
+	os.WriteFile(filepath.Join(dir, "node_modules", "x", "index.js"), []byte("x"), 0644)
+	os.Symlink("src/main.go", filepath.Join(dir, "link"))
+
+	var names []string
+	mock := &MockDockerClient{
+		CopyToContainerFn: func(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+			if dstPath != "/" {
+				t.Fatalf("expected extraction at '/', got '%s'", dstPath)
+			}
+			names = tarNames(t, content)
+			return nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+	ignore, _ := pkg.NewIgnoreMatcher([]string{"node_modules"})
+
+	opts := pkg.SyncOptions{Sandbox: "box", LocalDir: dir, ContainerPath: "/app", Ignore: ignore}
+	if err := engine.PushTree(context.Background(), opts, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"app/", "app/link", "app/src/", "app/src/main.go"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestPullTree_OnlyNewerFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+	os.WriteFile(filepath.Join(dir, "stale.txt"), []byte("host"), 0644)
+	os.Chtimes(filepath.Join(dir, "stale.txt"), old, old)
+	os.WriteFile(filepath.Join(dir, "fresh.txt"), []byte("host"), 0644)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	now := time.Now().Truncate(time.Second)
+	tw.WriteHeader(&tar.Header{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: now})
+	for _, f := range []struct {
+		name  string
+		mtime time.Time
+	}{{"app/stale.txt", now}, {"app/fresh.txt", old}, {"app/new.txt", now}} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: 9, ModTime: f.mtime})
+		tw.Write([]byte("container"))
+	}
+	tw.Close()
+
+	mock := &MockDockerClient{
+		CopyFromContainerFn: func(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+			return io.NopCloser(&buf), container.PathStat{Name: "app"}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	pulled := make(map[string]time.Time)
+	opts := pkg.SyncOptions{Sandbox: "box", LocalDir: dir, ContainerPath: "/app"}
+	if err := engine.PullTree(context.Background(), opts, pulled); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for file, want := range map[string]string{"stale.txt": "container", "fresh.txt": "host", "new.txt": "container"} {
+		data, _ := os.ReadFile(filepath.Join(dir, file))
+		if string(data) != want {
+			t.Errorf("%s: expected '%s', got '%s'", file, want, data)
+		}
+	}
+	if _, ok := pulled["stale.txt"]; !ok {
+		t.Fatal("expected pulled files to be recorded")
+	}
+	if _, ok := pulled["fresh.txt"]; ok {
+		t.Fatal("expected skipped files not to be recorded")
+	}
+}
+
+func TestExtractTar_RejectsEscapingEntries(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "app/../../evil", Typeflag: tar.TypeReg, Mode: 0644})
+	tw.Close()
+
+	if err := pkg.ExtractTar(&buf, t.TempDir(), nil); err == nil {
+		t.Fatal("expected error for entry escaping destination")
+	}
+}
+
+func TestExtractTar_RejectsSymlinkEscapes(t *testing.T) {
+	outside := t.TempDir()
+	archive := func(link string, then *tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "app/a", Typeflag: tar.TypeSymlink, Linkname: link})
+		if then != nil {
+			tw.WriteHeader(then)
+			tw.Write([]byte("pwned"))
+		}
+		tw.Close()
+		return &buf
+	}
+
+	cases := map[string]*bytes.Buffer{
+		"absolute link":       archive(outside, nil),
+		"escaping link":       archive("../../x", nil),
+		"file through link":   archive(".", &tar.Header{Name: "app/a/x", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}),
+		"folder through link": archive(".", &tar.Header{Name: "app/a", Typeflag: tar.TypeDir, Mode: 0755}),
+	}
+	for name, buf := range cases {
+		if err := pkg.ExtractTar(buf, t.TempDir(), nil); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Fatalf("expected nothing written outside the destination, got %d entries", len(entries))
+	}
+
+	// A link inside the destination is still unpacked
+	dst := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "app/sub", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "app/sub/link", Typeflag: tar.TypeSymlink, Linkname: "../target"})
+	tw.Close()
+	if err := pkg.ExtractTar(&buf, dst, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link, _ := os.Readlink(filepath.Join(dst, "sub", "link")); link != "../target" {
+		t.Fatalf("expected link to be kept, got '%s'", link)
+	}
+}
+
+func TestRunInSandbox_ExitCode(t *testing.T) {
+	mock := &MockDockerClient{
+		ContainerExecInspectFn: func(ctx context.Context, execID string) (container.ExecInspect, error) {
+			return container.ExecInspect{ExecID: execID, ExitCode: 2}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	if err := engine.RunInSandbox(context.Background(), "box", []string{"false"}); err == nil {
+		t.Fatal("expected error for non-zero exit code")
+	}
+}
+
+func TestRemainingTTL(t *testing.T) {
+	future := time.Now().Add(90 * time.Minute).Format(time.RFC3339)
+	past := time.Now().Add(-1 * time.Minute).Format(time.RFC3339)
+
+	if rem := pkg.RemainingTTL(map[string]string{"com.sbhub.expires": future}, time.Hour); rem < 89*time.Minute || rem > 90*time.Minute {
+		t.Fatalf("expected ~90m remaining, got %v", rem)
+	}
+	if rem := pkg.RemainingTTL(map[string]string{"com.sbhub.expires": past}, time.Hour); rem != time.Hour {
+		t.Fatalf("expected fallback for expired label, got %v", rem)
+	}
+	if rem := pkg.RemainingTTL(nil, time.Hour); rem != time.Hour {
+		t.Fatalf("expected fallback for missing label, got %v", rem)
+	}
+}
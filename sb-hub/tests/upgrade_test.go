@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NjariaOwen/sb-hub/pkg"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestRebuildOptions(t *testing.T) {
+	source, opts, err := pkg.RebuildOptions(map[string]string{
+		"com.sbhub.source":     "/src/app",
+		"com.sbhub.dockerfile": "docker/Dockerfile.dev",
+		"com.sbhub.target":     "dev",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != "/src/app" || opts.Dockerfile != "/src/app/docker/Dockerfile.dev" || opts.Target != "dev" {
+		t.Fatalf("unexpected options: %s %+v", source, opts)
+	}
+	if _, _, err := pkg.RebuildOptions(map[string]string{"maintainer": "x"}); err == nil {
+		t.Fatal("expected error for an image without a source label")
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	pulled := image.InspectResponse{ID: "sha256:aaa", RepoDigests: []string{"alpine@sha256:bbb"}}
+	if got := pkg.ImageDigest(pulled); got != "sha256:bbb" {
+		t.Fatalf("expected the repo digest, got %s", got)
+	}
+	if got := pkg.ImageDigest(image.InspectResponse{ID: "sha256:aaa"}); got != "sha256:aaa" {
+		t.Fatalf("expected the ID of a local image, got %s", got)
+	}
+}
+
+func upgradeInspect() container.InspectResponse {
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			Image: "sha256:old",
+			HostConfig: &container.HostConfig{
+				Binds:        []string{"/data/box:/workspace"},
+				PortBindings: nat.PortMap{"8080/tcp": {{HostPort: "9001"}}},
+			},
+		},
+		Config: &container.Config{
+			Image:      "alpine:latest",
+			Cmd:        []string{"/bin/sh"},
+			WorkingDir: "/",
+			Env:        []string{"PATH=/usr/bin", "APP_ENV=dev"},
+			Labels: map[string]string{
+				"com.sbhub.managed": "true",
+				"com.sbhub.size":    "small",
+				"com.sbhub.expires": time.Now().Add(30 * time.Minute).Format(time.RFC3339),
+				"maintainer":        "upstream",
+			},
+		},
+	}
+}
+
+func oldImage(ctx context.Context, id string) (image.InspectResponse, error) {
+	return image.InspectResponse{ID: id, Config: &dockerspec.DockerOCIImageConfig{
+		ImageConfig: ocispec.ImageConfig{
+			Cmd:        []string{"/bin/sh"},
+			WorkingDir: "/",
+			Env:        []string{"PATH=/usr/bin"},
+			Labels:     map[string]string{"maintainer": "upstream"},
+		},
+	}}, nil
+}
+
+func TestRecreateSandbox_KeepsSettingsAndDropsImageDefaults(t *testing.T) {
+	var created *container.Config
+	var createdHost *container.HostConfig
+	mock := &MockDockerClient{
+		ImageInspectFn: oldImage,
+		ContainerCreateFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+			created, createdHost = config, hostConfig
+			return container.CreateResponse{ID: "new-container-id"}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	if _, err := engine.RecreateSandbox(context.Background(), "box", upgradeInspect(), "alpine:latest"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Image != "alpine:latest" || len(created.Env) != 1 || created.Env[0] != "APP_ENV=dev" {
+		t.Fatalf("unexpected config: %+v", created)
+	}
+	if created.Cmd != nil || created.WorkingDir != "" || created.Labels["maintainer"] != "" {
+		t.Fatalf("expected image defaults to be dropped: %+v", created)
+	}
+	if createdHost.Binds[0] != "/data/box:/workspace" || createdHost.PortBindings["8080/tcp"][0].HostPort != "9001" {
+		t.Fatalf("expected binds and ports to be kept: %+v", createdHost)
+	}
+	expires, _ := time.Parse(time.RFC3339, created.Labels["com.sbhub.expires"])
+	if left := time.Until(expires); left > 31*time.Minute || left < 28*time.Minute {
+		t.Fatalf("expected the remaining TTL to be kept, got %s", left)
+	}
+}
+
+func TestRecreateSandbox_RestoresOldImageOnFailure(t *testing.T) {
+	var images []string
+	mock := &MockDockerClient{
+		ImageInspectFn: oldImage,
+		ContainerCreateFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+			images = append(images, config.Image)
+			if config.Image == "alpine:latest" {
+				return container.CreateResponse{}, errors.New("port is already allocated")
+			}
+			return container.CreateResponse{ID: "restored-id"}, nil
+		},
+	}
+	engine := &pkg.Dockerengine{Client: mock}
+
+	_, err := engine.RecreateSandbox(context.Background(), "box", upgradeInspect(), "alpine:latest")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(images) != 2 || images[1] != "sha256:old" {
+		t.Fatalf("expected the old image to be started again, got %v", images)
+	}
+}